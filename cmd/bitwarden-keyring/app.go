@@ -7,11 +7,16 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 
+	"github.com/joe/bitwarden-keyring/internal/access"
+	"github.com/joe/bitwarden-keyring/internal/admin"
+	"github.com/joe/bitwarden-keyring/internal/autolock"
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
 	secretdbus "github.com/joe/bitwarden-keyring/internal/dbus"
+	"github.com/joe/bitwarden-keyring/internal/kwallet"
 	"github.com/joe/bitwarden-keyring/internal/logging"
 	"github.com/joe/bitwarden-keyring/internal/ssh"
 )
@@ -22,7 +27,12 @@ type App struct {
 	bwClient  *bitwarden.Client
 	conn      *dbus.Conn
 	service   *secretdbus.Service
+	kwallet   *kwallet.Service
 	sshServer *ssh.Server
+	autoLock  *autolock.Policy
+	policy    *access.Policy     // nil without --confirm-access
+	access    *access.Controller // nil without --confirm-access
+	admin     *admin.Service
 }
 
 // NewApp creates a new App with the given configuration
@@ -53,6 +63,9 @@ func (a *App) Start(ctx context.Context) error {
 		return err
 	}
 
+	// Created before the components so they can report activity
+	a.autoLock = autolock.NewPolicy(a.config.IdleLock, a.lockVault)
+
 	// Start Secret Service if enabled
 	if a.config.EnabledComponents["secrets"] {
 		if err := a.startSecretService(); err != nil {
@@ -60,6 +73,13 @@ func (a *App) Start(ctx context.Context) error {
 		}
 	}
 
+	// Start KWallet frontend if enabled
+	if a.config.EnabledComponents["kwallet"] {
+		if err := a.startKWallet(); err != nil {
+			return err
+		}
+	}
+
 	// Start SSH agent if enabled
 	if a.config.EnabledComponents["ssh"] {
 		if err := a.startSSHAgent(ctx); err != nil {
@@ -67,14 +87,110 @@ func (a *App) Start(ctx context.Context) error {
 		}
 	}
 
+	// Let panels, scripts and the CLI control the daemon. Not fatal: the
+	// SSH agent alone works without a session bus.
+	if err := a.startAdmin(); err != nil {
+		logging.L.Warn("daemon control over D-Bus unavailable", "error", err)
+	}
+
+	// Announce lock and unlock, including bw lock run outside the daemon
+	a.bwClient.SetLockChangeHandler(a.lockChanged)
+	go a.bwClient.WatchLockState(ctx, bitwarden.DefaultLockPollInterval)
+
+	// Pick up changes made in the browser extension or on other devices
+	if a.config.SyncInterval > 0 {
+		go a.bwClient.RunSync(ctx, a.config.SyncInterval)
+		logging.L.Info("vault syncs in the background", "every", a.config.SyncInterval)
+	}
+
+	if err := a.autoLock.WatchLogind(a.config.LockOn["sleep"], a.config.LockOn["screen-lock"]); err != nil {
+		// Not fatal: the idle timeout and manual locking still work
+		logging.L.Warn("cannot lock on sleep or screen lock", "error", err)
+	}
+	if a.config.IdleLock > 0 {
+		logging.L.Info("vault locks when idle", "after", a.config.IdleLock)
+	}
+
 	return nil
 }
 
+// lockVault locks the vault for the lock policy; lockChanged tells the
+// components
+func (a *App) lockVault(reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if locked, err := a.bwClient.IsLocked(ctx); err == nil && locked {
+		return
+	}
+	if err := a.bwClient.Lock(ctx); err != nil {
+		logging.L.Warn("failed to lock vault", "reason", reason, "error", err)
+		return
+	}
+	logging.L.Info("vault locked", "reason", reason)
+}
+
+// lockChanged is called whenever the vault locks or unlocks, whether through
+// the daemon or bw directly. It tells Secret Service clients. On unlock it
+// restarts the idle timeout; on lock it unexports vault items, drops the SSH
+// agent's key cache and closes open KWallet wallets.
+func (a *App) lockChanged(locked bool) {
+	logging.L.Debug("vault lock state changed", "locked", locked)
+	if a.service != nil {
+		a.service.NotifyLockChanged(locked)
+	}
+	if a.admin != nil {
+		a.admin.NotifyLockChanged(locked)
+	}
+	if !locked {
+		// An unlock without further use must still idle-lock
+		a.autoLock.Activity()
+		return
+	}
+	if a.service != nil {
+		a.service.ForgetVaultItems()
+	}
+	if a.sshServer != nil {
+		a.sshServer.Keyring().ClearKeys()
+	}
+	if a.kwallet != nil {
+		a.kwallet.CloseAll()
+	}
+}
+
+// noteDBusActivity counts Secret Service, gnome-keyring, secret portal and
+// KWallet method calls as vault use. Property reads and introspection don't
+// count, so a panel polling Locked does not keep the vault open.
+func (a *App) noteDBusActivity(msg *dbus.Message) {
+	if msg.Type != dbus.TypeMethodCall {
+		return
+	}
+	iface, _ := msg.Headers[dbus.FieldInterface].Value().(string)
+	switch {
+	case strings.HasPrefix(iface, "org.freedesktop.Secret."),
+		iface == secretdbus.GnomeKeyringInterface,
+		iface == secretdbus.KeyringItemInterface,
+		iface == secretdbus.PortalSecretInterface,
+		iface == kwallet.Interface:
+		a.autoLock.Activity()
+	}
+}
+
 // startBitwardenBackend starts bw serve and waits for it to be ready
 func (a *App) startBitwardenBackend(ctx context.Context) error {
 	// Create Bitwarden client with session config
 	a.bwClient = bitwarden.NewClientWithConfig(a.config.BWPort, a.config.SessionConfig())
 
+	// Serve item reads from memory, refreshed periodically from bw serve
+	a.bwClient.EnableItemCache(a.config.ItemCacheRefresh)
+	a.bwClient.SetRepromptGrace(a.config.RepromptGrace)
+
+	// In strict mode only Prompt objects and the SSH agent ask for the
+	// master password; other Secret Service calls fail with IsLocked
+	if a.config.StrictUnlock {
+		a.bwClient.SetAutoUnlock(false)
+	}
+
 	// Enable HTTP body logging if both --debug and --debug-http are set
 	if a.config.Debug && a.config.DebugHTTP {
 		a.bwClient.SetDebug(true)
@@ -98,31 +214,134 @@ func (a *App) startBitwardenBackend(ctx context.Context) error {
 	return nil
 }
 
+// sessionBus returns the session bus connection shared by the D-Bus
+// components, connecting on first use
+func (a *App) sessionBus() (*dbus.Conn, error) {
+	if a.conn != nil {
+		return a.conn, nil
+	}
+	conn, err := dbus.ConnectSessionBus(dbus.WithIncomingInterceptor(a.noteDBusActivity))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	logging.L.Info("connected to session D-Bus")
+	a.conn = conn
+	return conn, nil
+}
+
 // startSecretService connects to D-Bus and exports the Secret Service
 func (a *App) startSecretService() error {
-	var err error
-	a.conn, err = dbus.ConnectSessionBus()
+	conn, err := a.sessionBus()
 	if err != nil {
-		return fmt.Errorf("failed to connect to session bus: %w", err)
+		return err
 	}
 
-	logging.L.Info("connected to session D-Bus")
-
 	// Create and export the service
-	a.service, err = secretdbus.NewService(a.conn, a.bwClient)
+	a.service, err = secretdbus.NewService(conn, a.bwClient)
 	if err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
 	}
+	a.bwClient.SetItemChangeHandler(a.service.ApplyItemChanges)
+	a.bwClient.SetSyncHandler(a.service.RefreshFolders)
+
+	if c := a.accessController(conn); c != nil {
+		a.service.SetAccessController(c)
+	}
 
 	if err := a.service.Export(); err != nil {
 		return fmt.Errorf("failed to export service: %w", err)
 	}
 
+	if a.config.Portal {
+		if err := a.service.ExportPortal(); err != nil {
+			return err
+		}
+		logging.L.Info("secret portal backend exported", "path", secretdbus.PortalPath)
+	}
+
 	logging.L.Info("secret service exported", "busname", secretdbus.BusName)
 	logging.L.Info("ready to serve secrets from bitwarden vault")
 	return nil
 }
 
+// accessController returns the controller confirming secret reads, shared by
+// the Secret Service and KWallet, or nil without --confirm-access
+func (a *App) accessController(conn *dbus.Conn) *access.Controller {
+	if !a.config.ConfirmAccess || a.access != nil {
+		return a.access
+	}
+	a.policy = access.NewPolicy(access.DefaultPolicyFilePath())
+	if err := a.policy.Load(); err != nil {
+		logging.L.Warn("failed to load access policy, every read will be confirmed", "error", err)
+	}
+	a.access = access.NewController(conn, a.policy, a.bwClient.SessionManager())
+	logging.L.Info("access confirmation enabled")
+	return a.access
+}
+
+// startAdmin exports the admin interface on the session bus
+func (a *App) startAdmin() error {
+	conn, err := a.sessionBus()
+	if err != nil {
+		return err
+	}
+
+	svc := admin.NewService(conn, a.bwClient, a.config.Version, a.config.EnabledComponentsList())
+	if a.service != nil {
+		svc.SetSessionCounter(a.service.SessionCount)
+	}
+	if a.sshServer != nil {
+		svc.SetSSHKeyLister(a.sshServer.Keyring().List)
+	}
+	svc.SetReloadHandler(a.reloadConfig)
+	if err := svc.Export(); err != nil {
+		return err
+	}
+
+	a.admin = svc
+	logging.L.Info("admin interface exported", "busname", admin.BusName, "path", admin.Path)
+	return nil
+}
+
+// reloadConfig re-reads the files loaded at startup: the access policy and
+// the Secret Service aliases. Flags only take effect on restart.
+func (a *App) reloadConfig() error {
+	var errs []string
+	if a.policy != nil {
+		if err := a.policy.Load(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if a.service != nil {
+		if err := a.service.ReloadAliases(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("reload failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// startKWallet exports the KWallet frontend on the session bus
+func (a *App) startKWallet() error {
+	conn, err := a.sessionBus()
+	if err != nil {
+		return err
+	}
+
+	a.kwallet = kwallet.NewService(conn, a.bwClient)
+	if c := a.accessController(conn); c != nil {
+		a.kwallet.SetAccessController(c)
+	}
+	if err := a.kwallet.Export(); err != nil {
+		return fmt.Errorf("failed to export KWallet service: %w", err)
+	}
+
+	logging.L.Info("KWallet service exported", "folder_prefix", kwallet.FolderPrefix)
+	return nil
+}
+
 // startSSHAgent starts the SSH agent server
 func (a *App) startSSHAgent(ctx context.Context) error {
 	socketPath := a.config.SSHSocketPath
@@ -132,6 +351,8 @@ func (a *App) startSSHAgent(ctx context.Context) error {
 
 	a.sshServer = ssh.NewServer(socketPath, a.bwClient)
 	a.sshServer.SetDebug(a.config.Debug)
+	a.sshServer.SetConfirmer(a.bwClient.SessionManager())
+	a.sshServer.SetActivityHook(a.autoLock.Activity)
 
 	if err := a.sshServer.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start SSH agent: %w", err)
@@ -187,6 +408,9 @@ func isSystemdUserRunning() bool {
 func (a *App) Stop() error {
 	var errs []string
 
+	// No automatic locks while shutting down
+	a.autoLock.Stop()
+
 	// Stop SSH agent
 	if a.sshServer != nil {
 		if err := a.sshServer.Stop(); err != nil {
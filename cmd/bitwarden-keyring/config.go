@@ -21,6 +21,17 @@ const (
 var validComponents = map[string]bool{
 	"secrets": true,
 	"ssh":     true,
+	"kwallet": true,
+}
+
+// defaultComponents are enabled when --components is not given. The KWallet
+// frontend is opt-in since it takes over the bus names of kwalletd.
+var defaultComponents = []string{"secrets", "ssh"}
+
+// validLockEvents defines the logind events --lock-on accepts
+var validLockEvents = map[string]bool{
+	"sleep":       true,
+	"screen-lock": true,
 }
 
 // Config holds all application configuration
@@ -37,6 +48,14 @@ type Config struct {
 	SessionStore           string
 	SessionFile            string
 	MaxPasswordRetries     int
+	ItemCacheRefresh       time.Duration
+	SyncInterval           time.Duration
+	RepromptGrace          time.Duration
+	IdleLock               time.Duration
+	LockOn                 map[string]bool
+	ConfirmAccess          bool
+	StrictUnlock           bool
+	Portal                 bool
 	EnabledComponents      map[string]bool
 	SSHSocketPath          string
 	NoSSHEnvExport         bool
@@ -68,13 +87,13 @@ func validComponentsList() string {
 }
 
 // parseComponents parses the component flag and returns a map of enabled components.
-// If componentStr is empty, all components are enabled.
+// If componentStr is empty, the default components are enabled.
 func parseComponents(componentStr string) (map[string]bool, error) {
 	enabled := make(map[string]bool)
 
-	// If empty, enable all components
+	// If empty, enable the default components
 	if componentStr == "" {
-		for c := range validComponents {
+		for _, c := range defaultComponents {
 			enabled[c] = true
 		}
 		return enabled, nil
@@ -99,6 +118,23 @@ func parseComponents(componentStr string) (map[string]bool, error) {
 	return enabled, nil
 }
 
+// parseLockEvents parses the --lock-on flag into the set of events that
+// lock the vault. An empty string selects none.
+func parseLockEvents(eventStr string) (map[string]bool, error) {
+	events := make(map[string]bool)
+	for _, e := range strings.Split(eventStr, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !validLockEvents[e] {
+			return nil, fmt.Errorf("unknown lock event: %s (valid: screen-lock, sleep)", e)
+		}
+		events[e] = true
+	}
+	return events, nil
+}
+
 // selectPort returns the port to use, handling deprecated flag and auto-selection
 func selectPort(bwPortFlag, deprecatedPortFlag int) (int, error) {
 	selectedPort := bwPortFlag
@@ -137,6 +173,27 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("--session-store must be 'memory' or 'file', got: %s", cfg.SessionStore)
 	}
 
+	if cfg.ItemCacheRefresh < 0 {
+		return fmt.Errorf("--item-cache-refresh must not be negative, got: %s", cfg.ItemCacheRefresh)
+	}
+
+	if cfg.SyncInterval < 0 {
+		return fmt.Errorf("--sync-interval must not be negative, got: %s", cfg.SyncInterval)
+	}
+
+	// Changes made elsewhere are found by diffing the item cache
+	if cfg.SyncInterval > 0 && cfg.ItemCacheRefresh == 0 {
+		logging.L.Warn("--item-cache-refresh=0 disables item change signals; --sync-interval only keeps the vault synced")
+	}
+
+	if cfg.RepromptGrace < 0 {
+		return fmt.Errorf("--reprompt-grace must not be negative, got: %s", cfg.RepromptGrace)
+	}
+
+	if cfg.IdleLock < 0 {
+		return fmt.Errorf("--idle-lock must not be negative, got: %s", cfg.IdleLock)
+	}
+
 	return nil
 }
 
@@ -155,7 +212,7 @@ func ConfigFromArgs(args []string) (Config, error) {
 		fNoctaliaFlag           = fs.Bool("noctalia", false, "Enable Noctalia UI integration for password prompts")
 		fNoctaliaSocket         = fs.String("noctalia-socket", "", "Custom Noctalia socket path (default: $XDG_RUNTIME_DIR/noctalia-keyring.sock)")
 		fNoctaliaTimeout        = fs.Duration("noctalia-timeout", 120*time.Second, "Noctalia prompt timeout")
-		fComponents             = fs.String("components", "", "Components to enable (comma-separated): secrets,ssh. Default: all")
+		fComponents             = fs.String("components", "", "Components to enable (comma-separated): secrets,ssh,kwallet. Default: secrets,ssh")
 		fSshSocket              = fs.String("ssh-socket", "", "SSH agent socket path (default: $XDG_RUNTIME_DIR/bitwarden-keyring/ssh.sock)")
 		fNoSSHEnvExport         = fs.Bool("no-ssh-env-export", false, "Disable automatic SSH_AUTH_SOCK export to D-Bus/systemd environment")
 		fAllowInsecurePrompts   = fs.Bool("allow-insecure-prompts", false, "Allow insecure password prompt methods like dmenu")
@@ -163,8 +220,22 @@ func ConfigFromArgs(args []string) (Config, error) {
 		fSessionStore           = fs.String("session-store", "memory", "Session storage mode: 'memory' or 'file' (default: memory)")
 		fSessionFile            = fs.String("session-file", "", "Custom session file path (default: $XDG_CONFIG_HOME/bitwarden-keyring/session)")
 		fMaxPasswordRetries     = fs.Int("max-password-retries", 3, "Maximum password retry attempts (default: 3)")
+		fConfirmAccess          = fs.Bool("confirm-access", false, "Ask before an application reads a secret; answers are saved to $XDG_CONFIG_HOME/bitwarden-keyring/access.json")
+		fItemCacheRefresh       = fs.Duration("item-cache-refresh", 5*time.Minute, "Refresh cached vault items after this long (0 = disable the item cache)")
+		fSyncInterval           = fs.Duration("sync-interval", 5*time.Minute, "Sync the vault in the background this often and after unlocking, signalling items changed elsewhere (0 = never)")
+		fIdleLock               = fs.Duration("idle-lock", 0, "Lock the vault after this long without Secret Service or SSH agent use (0 = never)")
+		fLockOn                 = fs.String("lock-on", "", "Also lock the vault on these logind events (comma-separated): sleep,screen-lock")
+		fStrictUnlock           = fs.Bool("strict-unlock", false, "Answer Secret Service calls on a locked vault with IsLocked instead of prompting; clients unlock through Unlock's prompt")
+		fPortal                 = fs.Bool("portal", false, "Serve Flatpak app secrets to xdg-desktop-portal (needs bitwarden-keyring.portal installed)")
+		fRepromptGrace          = fs.Duration("reprompt-grace", bitwarden.DefaultRepromptGrace, "Don't ask again for the master password of re-prompt protected items for this long after a re-prompt (0 = ask every time)")
 	)
 
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage of bitwarden-keyring:")
+		fs.PrintDefaults()
+		fmt.Fprint(fs.Output(), "\n"+commandUsage)
+	}
+
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
 			return Config{}, err
@@ -172,6 +243,12 @@ func ConfigFromArgs(args []string) (Config, error) {
 		return Config{}, fmt.Errorf("failed to parse flags: %w", err)
 	}
 
+	// Subcommands are dispatched before flags are parsed, so anything left
+	// is a mistyped command
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
 	// Select port (handles deprecated flag and auto-selection)
 	selectedPort, err := selectPort(*fBwPort, *fPort)
 	if err != nil {
@@ -184,6 +261,11 @@ func ConfigFromArgs(args []string) (Config, error) {
 		return Config{}, fmt.Errorf("invalid --components flag: %w", err)
 	}
 
+	lockOn, err := parseLockEvents(*fLockOn)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid --lock-on flag: %w", err)
+	}
+
 	// Check for environment variable override for Noctalia
 	noctaliaEnabled := *fNoctaliaFlag
 	if os.Getenv("BITWARDEN_KEYRING_NOCTALIA") == "1" {
@@ -203,6 +285,14 @@ func ConfigFromArgs(args []string) (Config, error) {
 		SessionStore:           *fSessionStore,
 		SessionFile:            *fSessionFile,
 		MaxPasswordRetries:     *fMaxPasswordRetries,
+		ItemCacheRefresh:       *fItemCacheRefresh,
+		SyncInterval:           *fSyncInterval,
+		RepromptGrace:          *fRepromptGrace,
+		IdleLock:               *fIdleLock,
+		LockOn:                 lockOn,
+		ConfirmAccess:          *fConfirmAccess,
+		StrictUnlock:           *fStrictUnlock,
+		Portal:                 *fPortal,
 		EnabledComponents:      enabledComponents,
 		SSHSocketPath:          *fSshSocket,
 		NoSSHEnvExport:         *fNoSSHEnvExport,
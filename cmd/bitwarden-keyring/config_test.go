@@ -188,8 +188,32 @@ func TestConfigFromArgs(t *testing.T) {
 				if cfg.MaxPasswordRetries != 3 {
 					t.Errorf("MaxPasswordRetries = %d, want 3", cfg.MaxPasswordRetries)
 				}
+				if cfg.ConfirmAccess {
+					t.Error("ConfirmAccess = true, want false by default")
+				}
+				if cfg.StrictUnlock {
+					t.Error("StrictUnlock = true, want false by default")
+				}
+				if cfg.Portal {
+					t.Error("Portal = true, want false by default")
+				}
+				if cfg.ItemCacheRefresh != 5*time.Minute {
+					t.Errorf("ItemCacheRefresh = %v, want %v", cfg.ItemCacheRefresh, 5*time.Minute)
+				}
+				if cfg.SyncInterval != 5*time.Minute {
+					t.Errorf("SyncInterval = %v, want %v", cfg.SyncInterval, 5*time.Minute)
+				}
+				if cfg.IdleLock != 0 || len(cfg.LockOn) != 0 {
+					t.Errorf("auto-lock enabled by default: IdleLock = %v, LockOn = %v", cfg.IdleLock, cfg.LockOn)
+				}
+				if cfg.RepromptGrace != time.Minute {
+					t.Errorf("RepromptGrace = %v, want %v", cfg.RepromptGrace, time.Minute)
+				}
 				if !cfg.EnabledComponents["secrets"] || !cfg.EnabledComponents["ssh"] {
-					t.Error("expected all components enabled by default")
+					t.Error("expected secrets and ssh enabled by default")
+				}
+				if cfg.EnabledComponents["kwallet"] {
+					t.Error("kwallet enabled by default, want opt-in")
 				}
 			},
 		},
@@ -248,6 +272,110 @@ func TestConfigFromArgs(t *testing.T) {
 			wantErr:  true,
 			wantHelp: true,
 		},
+		{
+			name:    "enable access confirmation",
+			args:    []string{"--confirm-access"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if !cfg.ConfirmAccess {
+					t.Error("ConfirmAccess = false, want true")
+				}
+			},
+		},
+		{
+			name:    "strict unlock",
+			args:    []string{"--strict-unlock"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if !cfg.StrictUnlock {
+					t.Error("StrictUnlock = false, want true")
+				}
+			},
+		},
+		{
+			name:    "enable secret portal",
+			args:    []string{"--portal"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if !cfg.Portal {
+					t.Error("Portal = false, want true")
+				}
+			},
+		},
+		{
+			name:    "disable item cache",
+			args:    []string{"--item-cache-refresh=0"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if cfg.ItemCacheRefresh != 0 {
+					t.Errorf("ItemCacheRefresh = %v, want 0", cfg.ItemCacheRefresh)
+				}
+			},
+		},
+		{
+			name:           "negative item cache refresh",
+			args:           []string{"--item-cache-refresh=-1s"},
+			wantErr:        true,
+			wantErrContain: "item-cache-refresh must not be negative",
+		},
+		{
+			name:           "negative sync interval",
+			args:           []string{"--sync-interval=-1s"},
+			wantErr:        true,
+			wantErrContain: "sync-interval must not be negative",
+		},
+		{
+			// Accepted with a warning: syncing still keeps uncached reads fresh
+			name:    "sync without item cache",
+			args:    []string{"--item-cache-refresh=0"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if cfg.ItemCacheRefresh != 0 || cfg.SyncInterval != 5*time.Minute {
+					t.Errorf("ItemCacheRefresh = %v, SyncInterval = %v", cfg.ItemCacheRefresh, cfg.SyncInterval)
+				}
+			},
+		},
+		{
+			name:    "reprompt every time",
+			args:    []string{"--reprompt-grace=0"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if cfg.RepromptGrace != 0 {
+					t.Errorf("RepromptGrace = %v, want 0", cfg.RepromptGrace)
+				}
+			},
+		},
+		{
+			name:           "negative reprompt grace",
+			args:           []string{"--reprompt-grace=-1s"},
+			wantErr:        true,
+			wantErrContain: "reprompt-grace must not be negative",
+		},
+		{
+			name:    "auto-lock",
+			args:    []string{"--idle-lock=15m", "--lock-on=sleep, screen-lock"},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg Config) {
+				if cfg.IdleLock != 15*time.Minute {
+					t.Errorf("IdleLock = %v, want 15m", cfg.IdleLock)
+				}
+				if !cfg.LockOn["sleep"] || !cfg.LockOn["screen-lock"] {
+					t.Errorf("LockOn = %v, want sleep and screen-lock", cfg.LockOn)
+				}
+			},
+		},
+		{
+			name:           "unknown lock event",
+			args:           []string{"--lock-on=logout"},
+			wantErr:        true,
+			wantErrContain: "invalid --lock-on flag",
+		},
+		{
+			name:           "negative idle lock",
+			args:           []string{"--idle-lock=-1m"},
+			wantErr:        true,
+			wantErrContain: "idle-lock must not be negative",
+		},
 		{
 			name:           "invalid session store",
 			args:           []string{"--session-store=invalid"},
@@ -350,6 +478,12 @@ func TestConfigFromArgs(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:           "mistyped command",
+			args:           []string{"--debug", "stauts"},
+			wantErr:        true,
+			wantErrContain: `unknown command "stauts"`,
+		},
 		{
 			name:    "deprecated port flag",
 			args:    []string{"--port=9090"},
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/admin"
+	secretdbus "github.com/joe/bitwarden-keyring/internal/dbus"
+)
+
+const (
+	// callTimeout bounds calls that answer right away
+	callTimeout = 30 * time.Second
+
+	// unlockTimeout bounds calls that may wait for the master password
+	unlockTimeout = 5 * time.Minute
+)
+
+// commandUsage describes the subcommands that control a running daemon
+const commandUsage = `Commands for the running daemon:
+  status                       Show vault, bw serve and session status
+  lock                         Lock the vault
+  unlock                       Unlock the vault, asking for the master password
+  sync                         Sync the vault with the Bitwarden server
+  search <attr=value>...       List items matching all attributes
+  get <attr=value>...          Print the secret of the first matching item
+  store --label <label> <attr=value>...
+                               Store the secret read from stdin
+  ssh list                     List the SSH agent's keys
+
+Every command accepts --json for machine-readable output.
+`
+
+// errVaultLocked is returned for calls that failed on a locked vault
+var errVaultLocked = errors.New("the vault is locked")
+
+// commands are the subcommands, keyed by name
+var commands = map[string]func(*controller, []string) error{
+	"status": (*controller).status,
+	"lock":   (*controller).lock,
+	"unlock": (*controller).unlock,
+	"sync":   (*controller).sync,
+	"search": (*controller).search,
+	"get":    (*controller).get,
+	"store":  (*controller).store,
+	"ssh":    (*controller).ssh,
+}
+
+// isCommand reports whether arg names a subcommand
+func isCommand(arg string) bool {
+	_, ok := commands[arg]
+	return ok
+}
+
+// controller runs subcommands against the daemon over the session bus
+type controller struct {
+	in    io.Reader
+	out   io.Writer
+	json  bool
+	label string // store only
+
+	conn *dbus.Conn // connected on first use
+}
+
+// runCommand parses the flags of a subcommand and runs it
+func runCommand(name string, args []string, in io.Reader, out io.Writer) error {
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	c := &controller{in: in, out: out}
+	fs := flag.NewFlagSet("bitwarden-keyring "+name, flag.ContinueOnError)
+	fs.BoolVar(&c.json, "json", false, "Print JSON instead of text")
+	if name == "store" {
+		fs.StringVar(&c.label, "label", "", "Label of the stored item (required)")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of bitwarden-keyring %s:\n", name)
+		fs.PrintDefaults()
+		fmt.Fprint(fs.Output(), "\n"+commandUsage)
+	}
+
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	defer c.close()
+	return cmd(c, args)
+}
+
+// parseInterspersed parses flags given before, between or after arguments
+// and returns the arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseAttributes parses attr=value arguments into a lookup attribute map
+func parseAttributes(args []string) (map[string]string, error) {
+	attrs := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid attribute %q, want attr=value", arg)
+		}
+		attrs[key] = value
+	}
+	return attrs, nil
+}
+
+// bus returns the session bus connection, connecting on first use
+func (c *controller) bus() (*dbus.Conn, error) {
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// close closes the bus connection, if any
+func (c *controller) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// call calls a method on a daemon object and stores its results in ret
+func (c *controller) call(timeout time.Duration, dest string, path dbus.ObjectPath, method string, args []interface{}, ret ...interface{}) error {
+	conn, err := c.bus()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	call := conn.Object(dest, path).CallWithContext(ctx, method, 0, args...)
+	if call.Err != nil {
+		return callError(call.Err)
+	}
+	return call.Store(ret...)
+}
+
+// adminCall calls a method of the admin interface
+func (c *controller) adminCall(timeout time.Duration, method string, ret ...interface{}) error {
+	return c.call(timeout, admin.BusName, admin.Path, admin.Interface+"."+method, nil, ret...)
+}
+
+// callError turns D-Bus errors into messages for the terminal
+func callError(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	switch dbusErr.Name {
+	case "org.freedesktop.DBus.Error.ServiceUnknown", "org.freedesktop.DBus.Error.NameHasNoOwner":
+		return errors.New("bitwarden-keyring is not running")
+	case secretdbus.ErrIsLocked:
+		return errVaultLocked
+	case admin.ErrCancelled:
+		return errors.New("cancelled")
+	}
+	if len(dbusErr.Body) > 0 {
+		if msg, ok := dbusErr.Body[0].(string); ok {
+			return errors.New(msg)
+		}
+	}
+	return err
+}
+
+// unlockAndRetry runs fn, and runs it again after unlocking the vault if it
+// failed because the vault was locked, as it is with --strict-unlock
+func (c *controller) unlockAndRetry(fn func() error) error {
+	err := fn()
+	if !errors.Is(err, errVaultLocked) {
+		return err
+	}
+	if err := c.adminCall(unlockTimeout, "Unlock"); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// noArgs fails if a command that takes no arguments got some
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected argument %q", args[0])
+	}
+	return nil
+}
+
+// printJSON writes v as indented JSON
+func (c *controller) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// daemonStatus is the daemon status as printed by the status command
+type daemonStatus struct {
+	Version      string     `json:"version"`
+	Components   []string   `json:"components"`
+	Locked       bool       `json:"locked"`
+	ServePID     uint32     `json:"serve_pid"`
+	ServeHealthy bool       `json:"serve_healthy"`
+	ServeError   string     `json:"serve_error,omitempty"`
+	Sessions     uint32     `json:"sessions"`
+	LastSync     *time.Time `json:"last_sync"` // nil if never synced
+}
+
+// statusFromProperties reads a daemonStatus from the admin properties
+func statusFromProperties(props map[string]dbus.Variant) daemonStatus {
+	var st daemonStatus
+	st.Version, _ = props["Version"].Value().(string)
+	st.Components, _ = props["Components"].Value().([]string)
+	st.Locked, _ = props["Locked"].Value().(bool)
+	st.ServePID, _ = props["ServePID"].Value().(uint32)
+	st.ServeHealthy, _ = props["ServeHealthy"].Value().(bool)
+	st.ServeError, _ = props["ServeError"].Value().(string)
+	st.Sessions, _ = props["Sessions"].Value().(uint32)
+	if lastSync, _ := props["LastSync"].Value().(int64); lastSync > 0 {
+		t := time.Unix(lastSync, 0)
+		st.LastSync = &t
+	}
+	return st
+}
+
+// printStatus writes the status as text or JSON
+func (c *controller) printStatus(st daemonStatus) error {
+	if c.json {
+		return c.printJSON(st)
+	}
+
+	vault := "unlocked"
+	if st.Locked {
+		vault = "locked"
+	}
+	serve := fmt.Sprintf("running (PID %d)", st.ServePID)
+	if !st.ServeHealthy {
+		serve = "not healthy: " + st.ServeError
+	}
+	lastSync := "never"
+	if st.LastSync != nil {
+		lastSync = st.LastSync.Local().Format("2006-01-02 15:04:05")
+	}
+
+	fmt.Fprintf(c.out, "Vault:       %s\n", vault)
+	fmt.Fprintf(c.out, "Last sync:   %s\n", lastSync)
+	fmt.Fprintf(c.out, "bw serve:    %s\n", serve)
+	fmt.Fprintf(c.out, "Sessions:    %d\n", st.Sessions)
+	fmt.Fprintf(c.out, "Components:  %s\n", strings.Join(st.Components, ", "))
+	fmt.Fprintf(c.out, "Version:     %s\n", st.Version)
+	return nil
+}
+
+// currentStatus fetches the daemon status
+func (c *controller) currentStatus() (daemonStatus, error) {
+	var props map[string]dbus.Variant
+	if err := c.adminCall(callTimeout, "Status", &props); err != nil {
+		return daemonStatus{}, err
+	}
+	return statusFromProperties(props), nil
+}
+
+// status prints the daemon status
+func (c *controller) status(args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	st, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	return c.printStatus(st)
+}
+
+// adminAction runs an admin method taking no arguments, then confirms it in
+// text or prints the resulting status as JSON
+func (c *controller) adminAction(args []string, method string, timeout time.Duration, done string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	if err := c.adminCall(timeout, method); err != nil {
+		return err
+	}
+	if !c.json {
+		fmt.Fprintln(c.out, done)
+		return nil
+	}
+	st, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	return c.printJSON(st)
+}
+
+// lock locks the vault
+func (c *controller) lock(args []string) error {
+	return c.adminAction(args, "Lock", callTimeout, "Vault locked")
+}
+
+// unlock unlocks the vault
+func (c *controller) unlock(args []string) error {
+	return c.adminAction(args, "Unlock", unlockTimeout, "Vault unlocked")
+}
+
+// sync syncs the vault; it may ask for the master password first
+func (c *controller) sync(args []string) error {
+	return c.adminAction(args, "Sync", unlockTimeout, "Vault synced")
+}
+
+// itemInfo describes a Secret Service item for search output
+type itemInfo struct {
+	Path       dbus.ObjectPath   `json:"path"`
+	Label      string            `json:"label"`
+	Attributes map[string]string `json:"attributes"`
+	Locked     bool              `json:"locked"`
+}
+
+// searchItems runs a Secret Service search
+func (c *controller) searchItems(attrs map[string]string) (unlocked, locked []dbus.ObjectPath, err error) {
+	err = c.call(callTimeout, secretdbus.BusName, secretdbus.ServicePath, secretdbus.ServiceInterface+".SearchItems",
+		[]interface{}{attrs}, &unlocked, &locked)
+	return unlocked, locked, err
+}
+
+// itemInfo reads the label and attributes of an item
+func (c *controller) itemInfo(path dbus.ObjectPath) (itemInfo, error) {
+	var props map[string]dbus.Variant
+	if err := c.call(callTimeout, secretdbus.BusName, path, secretdbus.PropertiesInterface+".GetAll",
+		[]interface{}{secretdbus.ItemInterface}, &props); err != nil {
+		return itemInfo{}, err
+	}
+	info := itemInfo{Path: path}
+	info.Label, _ = props["Label"].Value().(string)
+	info.Attributes, _ = props["Attributes"].Value().(map[string]string)
+	info.Locked, _ = props["Locked"].Value().(bool)
+	return info, nil
+}
+
+// search lists the items matching attr=value arguments
+func (c *controller) search(args []string) error {
+	attrs, err := parseAttributes(args)
+	if err != nil {
+		return err
+	}
+	unlocked, locked, err := c.searchItems(attrs)
+	if err != nil {
+		return err
+	}
+
+	items := make([]itemInfo, 0, len(unlocked)+len(locked))
+	for _, path := range append(unlocked, locked...) {
+		info, err := c.itemInfo(path)
+		if err != nil {
+			return err
+		}
+		items = append(items, info)
+	}
+
+	if c.json {
+		return c.printJSON(items)
+	}
+	for i, item := range items {
+		if i > 0 {
+			fmt.Fprintln(c.out)
+		}
+		printItem(c.out, item)
+	}
+	return nil
+}
+
+// printItem writes an item's label, path and sorted attributes as text
+func printItem(w io.Writer, item itemInfo) {
+	fmt.Fprintf(w, "%s\n", item.Label)
+	fmt.Fprintf(w, "  path = %s\n", item.Path)
+	if item.Locked {
+		fmt.Fprintln(w, "  locked")
+	}
+	keys := make([]string, 0, len(item.Attributes))
+	for key := range item.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  attribute.%s = %s\n", key, item.Attributes[key])
+	}
+}
+
+// openSession opens a plain Secret Service session
+func (c *controller) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := c.call(callTimeout, secretdbus.BusName, secretdbus.ServicePath, secretdbus.ServiceInterface+".OpenSession",
+		[]interface{}{"plain", dbus.MakeVariant("")}, &output, &session)
+	return session, err
+}
+
+// closeSession closes a Secret Service session, ignoring errors
+func (c *controller) closeSession(session dbus.ObjectPath) {
+	_ = c.call(callTimeout, secretdbus.BusName, session, secretdbus.SessionInterface+".Close", nil)
+}
+
+// get prints the secret of the first item matching attr=value arguments,
+// unlocking the vault first if needed
+func (c *controller) get(args []string) error {
+	attrs, err := parseAttributes(args)
+	if err != nil {
+		return err
+	}
+	if len(attrs) == 0 {
+		return errors.New("at least one attr=value is required")
+	}
+
+	unlocked, locked, err := c.searchItems(attrs)
+	if err != nil {
+		return err
+	}
+	if len(unlocked) == 0 && len(locked) > 0 {
+		if err := c.adminCall(unlockTimeout, "Unlock"); err != nil {
+			return err
+		}
+		if unlocked, _, err = c.searchItems(attrs); err != nil {
+			return err
+		}
+	}
+	if len(unlocked) == 0 {
+		return errors.New("no matching item")
+	}
+	path := unlocked[0]
+
+	session, err := c.openSession()
+	if err != nil {
+		return err
+	}
+	defer c.closeSession(session)
+
+	var secret secretdbus.Secret
+	if err := c.unlockAndRetry(func() error {
+		return c.call(unlockTimeout, secretdbus.BusName, path, secretdbus.ItemInterface+".GetSecret",
+			[]interface{}{session}, &secret)
+	}); err != nil {
+		return err
+	}
+
+	if !c.json {
+		fmt.Fprintln(c.out, string(secret.Value))
+		return nil
+	}
+	info, err := c.itemInfo(path)
+	if err != nil {
+		return err
+	}
+	return c.printJSON(struct {
+		itemInfo
+		Secret      string `json:"secret"`
+		ContentType string `json:"content_type"`
+	}{info, string(secret.Value), secret.ContentType})
+}
+
+// store stores the secret read from stdin as a new item in the default
+// collection, replacing an item with the same attributes
+func (c *controller) store(args []string) error {
+	if c.label == "" {
+		return errors.New("--label is required")
+	}
+	attrs, err := parseAttributes(args)
+	if err != nil {
+		return err
+	}
+	if len(attrs) == 0 {
+		return errors.New("at least one attr=value is required")
+	}
+
+	data, err := io.ReadAll(c.in)
+	if err != nil {
+		return fmt.Errorf("failed to read secret: %w", err)
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	if value == "" {
+		return errors.New("empty secret on stdin")
+	}
+
+	session, err := c.openSession()
+	if err != nil {
+		return err
+	}
+	defer c.closeSession(session)
+
+	props := map[string]dbus.Variant{
+		secretdbus.PropItemLabel:      dbus.MakeVariant(c.label),
+		secretdbus.PropItemAttributes: dbus.MakeVariant(attrs),
+	}
+	secret := secretdbus.Secret{Session: session, Parameters: []byte{}, Value: []byte(value), ContentType: "text/plain"}
+	collection := dbus.ObjectPath(secretdbus.AliasPath + "default")
+
+	var item, prompt dbus.ObjectPath
+	if err := c.unlockAndRetry(func() error {
+		return c.call(unlockTimeout, secretdbus.BusName, collection, secretdbus.CollectionInterface+".CreateItem",
+			[]interface{}{props, secret, true}, &item, &prompt)
+	}); err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string]dbus.ObjectPath{"path": item})
+	}
+	fmt.Fprintf(c.out, "Stored %s\n", item)
+	return nil
+}
+
+// ssh runs SSH agent subcommands; only list exists
+func (c *controller) ssh(args []string) error {
+	if len(args) != 1 || args[0] != "list" {
+		return errors.New("usage: bitwarden-keyring ssh list")
+	}
+
+	var keys []admin.SSHKey
+	if err := c.unlockAndRetry(func() error {
+		return c.adminCall(callTimeout, "ListSSHKeys", &keys)
+	}); err != nil {
+		return err
+	}
+
+	if c.json {
+		if keys == nil {
+			keys = []admin.SSHKey{}
+		}
+		return c.printJSON(keys)
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(c.out, "The agent has no identities.")
+		return nil
+	}
+	for _, key := range keys {
+		fmt.Fprintf(c.out, "%s %s (%s)\n", key.Fingerprint, key.Comment, key.Type)
+	}
+	return nil
+}
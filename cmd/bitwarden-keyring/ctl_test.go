@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/admin"
+	secretdbus "github.com/joe/bitwarden-keyring/internal/dbus"
+)
+
+func TestParseAttributes(t *testing.T) {
+	attrs, err := parseAttributes([]string{"service=example.com", "username=joe", "query=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("parseAttributes() error = %v", err)
+	}
+	want := map[string]string{"service": "example.com", "username": "joe", "query": "a=b", "empty": ""}
+	if len(attrs) != len(want) {
+		t.Fatalf("parseAttributes() = %v, want %v", attrs, want)
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("attrs[%q] = %q, want %q", k, attrs[k], v)
+		}
+	}
+
+	for _, bad := range []string{"service", "=value"} {
+		if _, err := parseAttributes([]string{bad}); err == nil {
+			t.Errorf("parseAttributes(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "")
+	label := fs.String("label", "", "")
+
+	args, err := parseInterspersed(fs, []string{"a=1", "--json", "b=2", "--label", "L"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*jsonOut || *label != "L" || strings.Join(args, " ") != "a=1 b=2" {
+		t.Errorf("json = %v, label = %q, args = %v", *jsonOut, *label, args)
+	}
+}
+
+func TestRunCommand_ArgumentErrors(t *testing.T) {
+	// These fail before connecting to the session bus
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"status", []string{"extra"}, "unexpected argument"},
+		{"store", []string{"service=x"}, "--label is required"},
+		{"store", []string{"--label", "L"}, "attr=value is required"},
+		{"get", nil, "attr=value is required"},
+		{"search", []string{"novalue"}, "invalid attribute"},
+		{"ssh", []string{"add"}, "usage: bitwarden-keyring ssh list"},
+		{"lock", []string{"--label", "L"}, "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+strings.Join(tt.args, " "), func(t *testing.T) {
+			var out bytes.Buffer
+			err := runCommand(tt.name, tt.args, strings.NewReader(""), &out)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runCommand() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if err := runCommand("nope", nil, nil, &bytes.Buffer{}); err == nil {
+		t.Error("runCommand(nope) should fail")
+	}
+}
+
+func TestStatusOutput(t *testing.T) {
+	props := map[string]dbus.Variant{
+		"Version":      dbus.MakeVariant("0.6.0"),
+		"Components":   dbus.MakeVariant([]string{"secrets", "ssh"}),
+		"Locked":       dbus.MakeVariant(false),
+		"ServePID":     dbus.MakeVariant(uint32(4242)),
+		"ServeHealthy": dbus.MakeVariant(true),
+		"ServeError":   dbus.MakeVariant(""),
+		"Sessions":     dbus.MakeVariant(uint32(3)),
+		"LastSync":     dbus.MakeVariant(int64(0)),
+	}
+	st := statusFromProperties(props)
+
+	var out bytes.Buffer
+	c := &controller{out: &out}
+	if err := c.printStatus(st); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Vault:       unlocked", "Last sync:   never", "running (PID 4242)", "Sessions:    3", "secrets, ssh"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text status missing %q:\n%s", want, out.String())
+		}
+	}
+
+	props["LastSync"] = dbus.MakeVariant(int64(1704164645))
+	out.Reset()
+	c.json = true
+	if err := c.printStatus(statusFromProperties(props)); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Locked   bool       `json:"locked"`
+		ServePID uint32     `json:"serve_pid"`
+		LastSync *time.Time `json:"last_sync"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if got.ServePID != 4242 || got.LastSync == nil || got.LastSync.Unix() != 1704164645 {
+		t.Errorf("JSON status = %+v", got)
+	}
+}
+
+func TestPrintItem(t *testing.T) {
+	var out bytes.Buffer
+	printItem(&out, itemInfo{
+		Path:       "/org/freedesktop/secrets/collections/default/abc",
+		Label:      "GitHub",
+		Attributes: map[string]string{"username": "joe", "service": "github.com"},
+	})
+	want := "GitHub\n" +
+		"  path = /org/freedesktop/secrets/collections/default/abc\n" +
+		"  attribute.service = github.com\n" +
+		"  attribute.username = joe\n"
+	if out.String() != want {
+		t.Errorf("printItem() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}, "bitwarden-keyring is not running"},
+		{dbus.Error{Name: secretdbus.ErrIsLocked, Body: []interface{}{"Vault is locked"}}, "the vault is locked"},
+		{dbus.Error{Name: admin.ErrCancelled}, "cancelled"},
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.Failed", Body: []interface{}{"bw serve died"}}, "bw serve died"},
+	}
+	for _, tt := range tests {
+		if got := callError(tt.err); got.Error() != tt.want {
+			t.Errorf("callError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -52,6 +52,15 @@ func run(args []string) error {
 }
 
 func main() {
+	// Subcommands talk to the running daemon instead of starting one
+	if len(os.Args) > 1 && isCommand(os.Args[1]) {
+		if err := runCommand(os.Args[1], os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "bitwarden-keyring %s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(os.Args[1:]); err != nil {
 		logging.L.Error("fatal error", "error", err)
 		os.Exit(1)
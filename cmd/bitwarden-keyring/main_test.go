@@ -11,10 +11,11 @@ func TestParseComponents(t *testing.T) {
 		input       string
 		wantSecrets bool
 		wantSSH     bool
+		wantKWallet bool
 		wantErr     bool
 	}{
 		{
-			name:        "empty enables all",
+			name:        "empty enables defaults",
 			input:       "",
 			wantSecrets: true,
 			wantSSH:     true,
@@ -76,6 +77,18 @@ func TestParseComponents(t *testing.T) {
 			wantSSH:     true,
 			wantErr:     false,
 		},
+		{
+			name:        "kwallet only",
+			input:       "kwallet",
+			wantKWallet: true,
+		},
+		{
+			name:        "all components",
+			input:       "secrets,ssh,kwallet",
+			wantSecrets: true,
+			wantSSH:     true,
+			wantKWallet: true,
+		},
 		{
 			name:    "unknown component",
 			input:   "invalid",
@@ -120,6 +133,9 @@ func TestParseComponents(t *testing.T) {
 			if got["ssh"] != tt.wantSSH {
 				t.Errorf("parseComponents(%q) ssh = %v, want %v", tt.input, got["ssh"], tt.wantSSH)
 			}
+			if got["kwallet"] != tt.wantKWallet {
+				t.Errorf("parseComponents(%q) kwallet = %v, want %v", tt.input, got["kwallet"], tt.wantKWallet)
+			}
 		})
 	}
 }
@@ -135,7 +151,7 @@ func TestValidComponentsList(t *testing.T) {
 	list := validComponentsList()
 
 	// Should be sorted alphabetically
-	want := "secrets, ssh"
+	want := "kwallet, secrets, ssh"
 	if list != want {
 		t.Errorf("validComponentsList() = %q, want %q", list, want)
 	}
@@ -5,6 +5,7 @@ go 1.25.4
 require (
 	github.com/godbus/dbus/v5 v5.2.2
 	golang.org/x/crypto v0.47.0
+	golang.org/x/net v0.49.0
 )
 
 require golang.org/x/sys v0.40.0 // indirect
@@ -0,0 +1,198 @@
+// Package access asks the user to confirm when an application reads a secret
+// and remembers the answer per executable and item.
+package access
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// ErrDenied indicates the user (or a remembered decision) refused access
+var ErrDenied = errors.New("access denied")
+
+// Prompter asks the user whether an application may read a secret.
+// *bitwarden.SessionManager implements it with the configured prompt backends.
+type Prompter interface {
+	PromptForAccess(message string) (bitwarden.AccessChoice, error)
+}
+
+// Caller identifies the process behind a D-Bus connection
+type Caller struct {
+	PID        uint32
+	Executable string // empty if it could not be determined
+}
+
+// Target is the item whose secret is requested
+type Target struct {
+	ID    string
+	Label string
+}
+
+// Controller decides whether a D-Bus caller may read an item's secret,
+// consulting the policy and prompting the user for anything not yet decided
+type Controller struct {
+	policy   *Policy
+	prompter Prompter
+	resolve  func(sender string) (Caller, error)
+	promptMu sync.Mutex // one confirmation dialog at a time
+}
+
+// NewController creates a controller resolving callers through conn
+func NewController(conn *dbus.Conn, policy *Policy, prompter Prompter) *Controller {
+	return &Controller{
+		policy:   policy,
+		prompter: prompter,
+		resolve:  busCallerResolver(conn),
+	}
+}
+
+// busCallerResolver returns a resolver mapping a unique bus name to its
+// process ID and executable via the bus daemon and /proc
+func busCallerResolver(conn *dbus.Conn) func(string) (Caller, error) {
+	return func(sender string) (Caller, error) {
+		var caller Caller
+		if conn == nil {
+			return caller, fmt.Errorf("no bus connection")
+		}
+		err := conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixProcessID", 0, sender).Store(&caller.PID)
+		if err != nil {
+			return caller, fmt.Errorf("failed to get caller PID: %w", err)
+		}
+		exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", caller.PID))
+		if err != nil {
+			return caller, fmt.Errorf("failed to resolve caller executable: %w", err)
+		}
+		caller.Executable = exe
+		return caller, nil
+	}
+}
+
+// Check returns nil if sender may read target's secret and ErrDenied
+// otherwise. An empty sender is an in-process call and is always allowed;
+// a nil controller allows everything. Callers whose executable cannot be
+// determined are always prompted and their answers are never remembered.
+func (c *Controller) Check(sender string, target Target) error {
+	return c.CheckAll(sender, []Target{target})
+}
+
+// CheckAll is Check for several items read in one request. Remembered
+// decisions apply per item, and the items without one are confirmed in a
+// single prompt whose answer covers all of them. It returns ErrDenied if any
+// item is denied.
+func (c *Controller) CheckAll(sender string, targets []Target) error {
+	if c == nil || sender == "" || len(targets) == 0 {
+		return nil
+	}
+	log := logging.L.With("component", "access")
+
+	caller, err := c.resolve(sender)
+	if err != nil {
+		log.Warn("could not identify caller", "sender", sender, "error", err)
+		caller.Executable = ""
+	}
+
+	pending, err := c.undecided(caller, targets)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	c.promptMu.Lock()
+	defer c.promptMu.Unlock()
+
+	// Another request may have been answered while we waited
+	pending, err = c.undecided(caller, pending)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	choice, err := c.prompter.PromptForAccess(promptMessage(caller, pending))
+	if err != nil {
+		log.Info("access confirmation not answered, denying", "executable", caller.Executable, "error", err)
+		return ErrDenied
+	}
+	ids := make([]string, len(pending))
+	for i, t := range pending {
+		ids[i] = t.ID
+	}
+	log.Info("access confirmation answered", "executable", caller.Executable, "items", ids, "choice", choice.String())
+
+	var d Decision
+	switch choice {
+	case bitwarden.AccessAllowOnce:
+		return nil
+	case bitwarden.AccessAllowAlways:
+		d = Allow
+	default:
+		d = Deny
+	}
+
+	if caller.Executable != "" {
+		for _, t := range pending {
+			if err := c.policy.Remember(caller.Executable, t.ID, d); err != nil {
+				log.Warn("failed to save access policy", "error", err)
+			}
+		}
+	}
+	return decisionError(d)
+}
+
+// undecided returns the targets without a remembered decision, or ErrDenied
+// if one of them is remembered as denied
+func (c *Controller) undecided(caller Caller, targets []Target) ([]Target, error) {
+	var pending []Target
+	for _, t := range targets {
+		d, ok := c.remembered(caller, t)
+		if !ok {
+			pending = append(pending, t)
+			continue
+		}
+		if d != Allow {
+			return nil, ErrDenied
+		}
+	}
+	return pending, nil
+}
+
+// remembered looks up a stored decision for an identified caller
+func (c *Controller) remembered(caller Caller, target Target) (Decision, bool) {
+	if caller.Executable == "" {
+		return "", false
+	}
+	return c.policy.Lookup(caller.Executable, target.ID)
+}
+
+// decisionError maps a decision to Check's result
+func decisionError(d Decision) error {
+	if d == Allow {
+		return nil
+	}
+	return ErrDenied
+}
+
+// promptMessage describes the request to the user
+func promptMessage(caller Caller, targets []Target) string {
+	app := "An unknown application"
+	if caller.Executable != "" {
+		app = caller.Executable
+	}
+	if caller.PID != 0 {
+		app = fmt.Sprintf("%s (pid %d)", app, caller.PID)
+	}
+	if len(targets) == 1 {
+		return fmt.Sprintf("%s wants to read the secret of %q.", app, targets[0].Label)
+	}
+	labels := make([]string, len(targets))
+	for i, t := range targets {
+		labels[i] = strconv.Quote(t.Label)
+	}
+	return fmt.Sprintf("%s wants to read the secrets of %d items: %s.", app, len(targets), strings.Join(labels, ", "))
+}
@@ -0,0 +1,157 @@
+package access
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+type fakePrompter struct {
+	choice   bitwarden.AccessChoice
+	err      error
+	calls    int
+	messages []string
+}
+
+func (f *fakePrompter) PromptForAccess(message string) (bitwarden.AccessChoice, error) {
+	f.calls++
+	f.messages = append(f.messages, message)
+	return f.choice, f.err
+}
+
+func newTestController(prompter Prompter, callers map[string]Caller) *Controller {
+	return &Controller{
+		policy:   NewPolicy(""),
+		prompter: prompter,
+		resolve: func(sender string) (Caller, error) {
+			c, ok := callers[sender]
+			if !ok {
+				return Caller{}, errors.New("unknown sender")
+			}
+			return c, nil
+		},
+	}
+}
+
+var (
+	gitCaller = map[string]Caller{":1.5": {PID: 42, Executable: "/usr/bin/git"}}
+	target    = Target{ID: "item-1", Label: "GitHub"}
+)
+
+func TestController_Choices(t *testing.T) {
+	tests := []struct {
+		name        string
+		choice      bitwarden.AccessChoice
+		promptErr   error
+		wantErr     error
+		wantPrompts int // after two checks
+	}{
+		{"allow once asks every time", bitwarden.AccessAllowOnce, nil, nil, 2},
+		{"allow always is remembered", bitwarden.AccessAllowAlways, nil, nil, 1},
+		{"deny is remembered", bitwarden.AccessDeny, nil, ErrDenied, 1},
+		{"dismissed prompt denies without remembering", bitwarden.AccessDeny, bitwarden.ErrUserCancelled, ErrDenied, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakePrompter{choice: tt.choice, err: tt.promptErr}
+			c := newTestController(p, gitCaller)
+
+			for i := 0; i < 2; i++ {
+				if err := c.Check(":1.5", target); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Check() #%d error = %v, want %v", i+1, err, tt.wantErr)
+				}
+			}
+			if p.calls != tt.wantPrompts {
+				t.Errorf("prompted %d times, want %d", p.calls, tt.wantPrompts)
+			}
+		})
+	}
+}
+
+func TestController_DecisionsArePerItemAndExecutable(t *testing.T) {
+	p := &fakePrompter{choice: bitwarden.AccessAllowAlways}
+	callers := map[string]Caller{
+		":1.5": {PID: 42, Executable: "/usr/bin/git"},
+		":1.6": {PID: 43, Executable: "/usr/bin/curl"},
+	}
+	c := newTestController(p, callers)
+
+	c.Check(":1.5", target)
+	c.Check(":1.5", Target{ID: "item-2", Label: "Other"})
+	c.Check(":1.6", target)
+	if p.calls != 3 {
+		t.Errorf("prompted %d times, want 3", p.calls)
+	}
+}
+
+func TestController_UnknownCallerNeverRemembered(t *testing.T) {
+	p := &fakePrompter{choice: bitwarden.AccessAllowAlways}
+	c := newTestController(p, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := c.Check(":1.9", target); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+	if p.calls != 2 {
+		t.Errorf("prompted %d times, want 2", p.calls)
+	}
+	if p.messages[0] != `An unknown application wants to read the secret of "GitHub".` {
+		t.Errorf("prompt message = %q", p.messages[0])
+	}
+}
+
+func TestController_InProcessAndNil(t *testing.T) {
+	p := &fakePrompter{choice: bitwarden.AccessDeny}
+	c := newTestController(p, gitCaller)
+	if err := c.Check("", target); err != nil {
+		t.Errorf("Check() with empty sender error = %v, want nil", err)
+	}
+	if p.calls != 0 {
+		t.Error("in-process calls should not prompt")
+	}
+
+	var nilController *Controller
+	if err := nilController.Check(":1.5", target); err != nil {
+		t.Errorf("nil Controller.Check() error = %v, want nil", err)
+	}
+}
+
+func TestController_PromptMessage(t *testing.T) {
+	p := &fakePrompter{choice: bitwarden.AccessAllowOnce}
+	c := newTestController(p, gitCaller)
+	c.Check(":1.5", target)
+
+	want := `/usr/bin/git (pid 42) wants to read the secret of "GitHub".`
+	if p.messages[0] != want {
+		t.Errorf("prompt message = %q, want %q", p.messages[0], want)
+	}
+}
+
+func TestController_CheckAll(t *testing.T) {
+	p := &fakePrompter{choice: bitwarden.AccessAllowAlways}
+	c := newTestController(p, gitCaller)
+	other := Target{ID: "item-2", Label: "GitLab"}
+
+	if err := c.CheckAll(":1.5", []Target{target, other}); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+	want := `/usr/bin/git (pid 42) wants to read the secrets of 2 items: "GitHub", "GitLab".`
+	if p.calls != 1 || p.messages[0] != want {
+		t.Errorf("prompts = %q, want one prompt %q", p.messages, want)
+	}
+
+	// Both answers were remembered
+	if err := c.Check(":1.5", other); err != nil || p.calls != 1 {
+		t.Errorf("Check() after CheckAll error = %v, prompts = %d, want nil and 1", err, p.calls)
+	}
+
+	// One remembered denial refuses the whole batch without asking
+	c.policy.Remember("/usr/bin/git", "item-3", Deny)
+	err := c.CheckAll(":1.5", []Target{{ID: "item-4", Label: "New"}, {ID: "item-3", Label: "Denied"}})
+	if !errors.Is(err, ErrDenied) || p.calls != 1 {
+		t.Errorf("CheckAll() with a denied item error = %v, prompts = %d, want ErrDenied and 1", err, p.calls)
+	}
+}
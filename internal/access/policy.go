@@ -0,0 +1,133 @@
+package access
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/joe/bitwarden-keyring/internal/fileutil"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// Decision is a remembered answer for an executable and item
+type Decision string
+
+const (
+	Allow Decision = "allow"
+	Deny  Decision = "deny"
+)
+
+// AnyItem can be used in the policy file in place of an item ID to apply a
+// decision to every item for an executable
+const AnyItem = "*"
+
+// Policy holds the remembered access decisions, keyed by executable path and
+// then by Bitwarden item ID, and persists them to a JSON file:
+//
+//	{"/usr/bin/secret-tool": {"<item id>": "allow", "*": "deny"}}
+type Policy struct {
+	path  string
+	rules map[string]map[string]Decision
+	mu    sync.RWMutex
+}
+
+// NewPolicy creates an empty policy backed by the given file path.
+// An empty path keeps decisions in memory only.
+func NewPolicy(path string) *Policy {
+	return &Policy{
+		path:  path,
+		rules: make(map[string]map[string]Decision),
+	}
+}
+
+// DefaultPolicyFilePath returns the default policy file location:
+// $XDG_CONFIG_HOME/bitwarden-keyring/access.json
+func DefaultPolicyFilePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.Getenv("HOME")
+	}
+	return filepath.Join(configDir, "bitwarden-keyring", "access.json")
+}
+
+// Load replaces the in-memory rules with the policy file's contents.
+// A missing file yields an empty policy.
+func (p *Policy) Load() error {
+	rules := make(map[string]map[string]Decision)
+
+	if p.path != "" {
+		data, err := os.ReadFile(p.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read access policy: %w", err)
+		}
+		if err == nil {
+			var raw map[string]map[string]Decision
+			if err := json.Unmarshal(data, &raw); err != nil {
+				return fmt.Errorf("failed to parse access policy: %w", err)
+			}
+			for exe, items := range raw {
+				for id, d := range items {
+					if exe == "" || id == "" || (d != Allow && d != Deny) {
+						logging.L.With("component", "access").Warn("ignoring invalid access policy entry", "executable", exe, "item", id)
+						continue
+					}
+					if rules[exe] == nil {
+						rules[exe] = make(map[string]Decision)
+					}
+					rules[exe][id] = d
+				}
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.rules = rules
+	p.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the remembered decision for an executable and item. An
+// entry for the exact item takes precedence over an AnyItem entry.
+func (p *Policy) Lookup(exe, itemID string) (Decision, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	items := p.rules[exe]
+	if d, ok := items[itemID]; ok {
+		return d, true
+	}
+	d, ok := items[AnyItem]
+	return d, ok
+}
+
+// Remember records a decision and saves the policy file
+func (p *Policy) Remember(exe, itemID string, d Decision) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.rules[exe] == nil {
+		p.rules[exe] = make(map[string]Decision)
+	}
+	p.rules[exe][itemID] = d
+	return p.save()
+}
+
+// save atomically writes the rules to the policy file. Callers must hold mu.
+func (p *Policy) save() error {
+	if p.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(p.rules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := fileutil.WriteFileAtomic(p.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save access policy: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package access
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicy_RememberAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "access.json")
+	p := NewPolicy(path)
+
+	if err := p.Remember("/usr/bin/git", "item-1", Allow); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+	if err := p.Remember("/usr/bin/evil", AnyItem, Deny); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("policy dir mode = %o, want 0700", info.Mode().Perm())
+	}
+
+	reloaded := NewPolicy(path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		exe, item string
+		want      Decision
+		wantOK    bool
+	}{
+		{"/usr/bin/git", "item-1", Allow, true},
+		{"/usr/bin/git", "item-2", "", false},
+		{"/usr/bin/evil", "item-1", Deny, true},
+		{"/usr/bin/other", "item-1", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := reloaded.Lookup(tt.exe, tt.item)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Lookup(%q, %q) = %q, %v; want %q, %v", tt.exe, tt.item, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPolicy_ExactItemOverridesAnyItem(t *testing.T) {
+	p := NewPolicy("")
+	p.Remember("/usr/bin/app", AnyItem, Deny)
+	p.Remember("/usr/bin/app", "item-1", Allow)
+
+	if d, _ := p.Lookup("/usr/bin/app", "item-1"); d != Allow {
+		t.Errorf("Lookup(item-1) = %q, want allow", d)
+	}
+	if d, _ := p.Lookup("/usr/bin/app", "item-2"); d != Deny {
+		t.Errorf("Lookup(item-2) = %q, want deny", d)
+	}
+}
+
+func TestPolicy_LoadSkipsInvalidEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	data := `{"/usr/bin/app": {"a": "allow", "b": "maybe", "": "deny"}, "": {"c": "allow"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPolicy(path)
+	if err := p.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if d, ok := p.Lookup("/usr/bin/app", "a"); !ok || d != Allow {
+		t.Errorf("valid entry not loaded: %q, %v", d, ok)
+	}
+	if _, ok := p.Lookup("/usr/bin/app", "b"); ok {
+		t.Error("entry with invalid decision should be ignored")
+	}
+	if _, ok := p.Lookup("", "c"); ok {
+		t.Error("entry with empty executable should be ignored")
+	}
+}
+
+func TestPolicy_LoadMissingAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := NewPolicy(filepath.Join(dir, "missing.json")).Load(); err != nil {
+		t.Errorf("Load() of missing file error = %v, want nil", err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("{"), 0600)
+	if err := NewPolicy(corrupt).Load(); err == nil {
+		t.Error("Load() of corrupt file should fail")
+	}
+}
@@ -0,0 +1,280 @@
+// Package admin serves io.github.joe.BitwardenKeyring1, the D-Bus interface
+// for controlling a running daemon: its status, locking and unlocking the
+// vault, syncing, reloading configuration files and listing SSH agent keys.
+// It is used by panel widgets, scripts and the bitwarden-keyring CLI.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+const (
+	// BusName is requested on the session bus so the daemon can be found
+	// whichever components are enabled
+	BusName = "io.github.joe.BitwardenKeyring"
+
+	// Path is the object path of the admin object
+	Path = dbus.ObjectPath("/io/github/joe/BitwardenKeyring")
+
+	// Interface is the admin interface
+	Interface = "io.github.joe.BitwardenKeyring1"
+
+	// PropertiesInterface is the standard D-Bus properties interface
+	PropertiesInterface = "org.freedesktop.DBus.Properties"
+
+	// Error names beyond the Secret Service and D-Bus ones
+	ErrCancelled = "io.github.joe.BitwardenKeyring.Error.Cancelled"
+)
+
+// SSHKey describes a key served by the SSH agent
+// D-Bus signature: (sss)
+type SSHKey struct {
+	Fingerprint string `json:"fingerprint"` // SHA256 fingerprint, as ssh-add -l prints it
+	Type        string `json:"type"`        // key algorithm, e.g. ssh-ed25519
+	Comment     string `json:"comment"`     // Bitwarden item name
+}
+
+// Service implements the admin interface
+type Service struct {
+	conn       *dbus.Conn
+	bwClient   *bitwarden.Client
+	version    string
+	components []string
+
+	sessions func() int                   // nil without the Secret Service
+	sshKeys  func() ([]*agent.Key, error) // nil without the SSH agent
+	reload   func() error                 // nil when there is nothing to reload
+}
+
+// NewService creates the admin service for a daemon running the given
+// components
+func NewService(conn *dbus.Conn, bwClient *bitwarden.Client, version string, components []string) *Service {
+	return &Service{
+		conn:       conn,
+		bwClient:   bwClient,
+		version:    version,
+		components: components,
+	}
+}
+
+// SetSessionCounter sets the function reporting open Secret Service
+// sessions. It must be called before Export.
+func (s *Service) SetSessionCounter(fn func() int) {
+	s.sessions = fn
+}
+
+// SetSSHKeyLister sets the function listing the SSH agent's keys. It must be
+// called before Export.
+func (s *Service) SetSSHKeyLister(fn func() ([]*agent.Key, error)) {
+	s.sshKeys = fn
+}
+
+// SetReloadHandler sets the function ReloadConfig calls. It must be
+// called before Export.
+func (s *Service) SetReloadHandler(fn func() error) {
+	s.reload = fn
+}
+
+// Export exports the admin object and requests BusName. It fails if another
+// daemon owns the name.
+func (s *Service) Export() error {
+	if err := s.conn.Export(s, Path, Interface); err != nil {
+		return fmt.Errorf("failed to export %s: %w", Interface, err)
+	}
+	if err := s.conn.Export(s, Path, PropertiesInterface); err != nil {
+		return fmt.Errorf("failed to export properties: %w", err)
+	}
+	if err := s.conn.Export(introspectable(IntrospectXML), Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("failed to export introspection: %w", err)
+	}
+
+	reply, err := s.conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("failed to request bus name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("bus name %s already taken", BusName)
+	}
+	return nil
+}
+
+// NotifyLockChanged emits PropertiesChanged for Locked
+func (s *Service) NotifyLockChanged(locked bool) {
+	if s.conn == nil {
+		return
+	}
+	changed := map[string]dbus.Variant{"Locked": dbus.MakeVariant(locked)}
+	if err := s.conn.Emit(Path, PropertiesInterface+".PropertiesChanged", Interface, changed, []string{}); err != nil {
+		logging.L.With("component", "admin").Warn("failed to emit signal", "signal", "PropertiesChanged", "error", err)
+	}
+}
+
+// Status returns every property of the admin interface (D-Bus method)
+func (s *Service) Status() (map[string]dbus.Variant, *dbus.Error) {
+	return s.GetAll(Interface)
+}
+
+// Lock locks the vault (D-Bus method)
+func (s *Service) Lock() *dbus.Error {
+	if err := s.bwClient.Lock(context.Background()); err != nil {
+		return toDBusError(err)
+	}
+	logging.L.Info("vault locked", "reason", "admin")
+	return nil
+}
+
+// Unlock asks for the master password if the vault is locked and returns
+// once it is unlocked (D-Bus method). Callers should allow for the time the
+// user takes to answer.
+func (s *Service) Unlock() *dbus.Error {
+	if err := s.bwClient.EnsureUnlocked(bitwarden.WithUnlockPrompt(context.Background())); err != nil {
+		return toDBusError(err)
+	}
+	return nil
+}
+
+// Sync syncs the vault with the Bitwarden server (D-Bus method)
+func (s *Service) Sync() *dbus.Error {
+	if err := s.bwClient.SyncNow(context.Background()); err != nil {
+		return toDBusError(err)
+	}
+	return nil
+}
+
+// ReloadConfig re-reads the configuration files the daemon loaded at
+// startup (D-Bus method)
+func (s *Service) ReloadConfig() *dbus.Error {
+	if s.reload == nil {
+		return nil
+	}
+	if err := s.reload(); err != nil {
+		return toDBusError(err)
+	}
+	logging.L.Info("configuration reloaded")
+	return nil
+}
+
+// ListSSHKeys returns the keys the SSH agent serves (D-Bus method). It never
+// asks for the master password: while the vault is locked it fails with
+// IsLocked, and callers unlock through Unlock first.
+func (s *Service) ListSSHKeys() ([]SSHKey, *dbus.Error) {
+	if s.sshKeys == nil {
+		return nil, &dbus.Error{
+			Name: "org.freedesktop.DBus.Error.NotSupported",
+			Body: []interface{}{"the SSH agent is not enabled"},
+		}
+	}
+	if s.bwClient.IsLockedSafe(context.Background()) {
+		return nil, toDBusError(bitwarden.ErrVaultLocked)
+	}
+	keys, err := s.sshKeys()
+	if err != nil {
+		return nil, toDBusError(err)
+	}
+
+	result := make([]SSHKey, 0, len(keys))
+	for _, key := range keys {
+		pub, err := cryptossh.ParsePublicKey(key.Blob)
+		if err != nil {
+			continue
+		}
+		result = append(result, SSHKey{
+			Fingerprint: cryptossh.FingerprintSHA256(pub),
+			Type:        key.Format,
+			Comment:     key.Comment,
+		})
+	}
+	return result, nil
+}
+
+// Get implements org.freedesktop.DBus.Properties.Get
+func (s *Service) Get(iface, property string) (dbus.Variant, *dbus.Error) {
+	props, dbusErr := s.GetAll(iface)
+	if dbusErr != nil {
+		return dbus.Variant{}, dbusErr
+	}
+	v, ok := props[property]
+	if !ok {
+		return dbus.Variant{}, &dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs", Body: []interface{}{"unknown property: " + property}}
+	}
+	return v, nil
+}
+
+// Set implements org.freedesktop.DBus.Properties.Set; every property is read-only
+func (s *Service) Set(iface, property string, value dbus.Variant) *dbus.Error {
+	return &dbus.Error{Name: "org.freedesktop.DBus.Error.PropertyReadOnly", Body: []interface{}{"read-only property: " + property}}
+}
+
+// GetAll implements org.freedesktop.DBus.Properties.GetAll
+func (s *Service) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != Interface {
+		return nil, &dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs", Body: []interface{}{"unknown interface: " + iface}}
+	}
+
+	ctx := context.Background()
+
+	// Like errors, the reason stays in the log rather than going to callers
+	healthy, serveError := true, ""
+	if err := s.bwClient.ServeHealthy(); err != nil {
+		logging.L.With("component", "admin").Debug("bw serve unhealthy", "error", err)
+		healthy, serveError = false, serveFailure
+	}
+
+	var lastSync int64
+	if status, err := s.bwClient.Status(ctx); err == nil {
+		if u := status.LastSyncTime().Unix(); u > 0 {
+			lastSync = u
+		}
+	}
+
+	sessions := 0
+	if s.sessions != nil {
+		sessions = s.sessions()
+	}
+
+	return map[string]dbus.Variant{
+		"Version":      dbus.MakeVariant(s.version),
+		"Components":   dbus.MakeVariant(s.components),
+		"Locked":       dbus.MakeVariant(s.bwClient.IsLockedSafe(ctx)),
+		"ServePID":     dbus.MakeVariant(uint32(s.bwClient.ServePID())),
+		"ServeHealthy": dbus.MakeVariant(healthy),
+		"ServeError":   dbus.MakeVariant(serveError),
+		"Sessions":     dbus.MakeVariant(uint32(sessions)),
+		"LastSync":     dbus.MakeVariant(lastSync),
+	}, nil
+}
+
+// serveFailure is the ServeError reported while bw serve is unhealthy
+const serveFailure = "bw serve is not running, see the daemon log"
+
+// toDBusError converts an error to a D-Bus error. Like the Secret Service it
+// returns fixed messages, so that details such as HTTP bodies from bw serve
+// are logged rather than handed to any caller on the bus.
+func toDBusError(err error) *dbus.Error {
+	switch {
+	case errors.Is(err, bitwarden.ErrVaultLocked):
+		return &dbus.Error{Name: "org.freedesktop.Secret.Error.IsLocked", Body: []interface{}{"vault is locked"}}
+	case errors.Is(err, bitwarden.ErrUserCancelled):
+		return &dbus.Error{Name: ErrCancelled, Body: []interface{}{"unlock was cancelled"}}
+	default:
+		logging.L.With("component", "admin").Warn("request failed", "error", err)
+		return &dbus.Error{Name: "org.freedesktop.DBus.Error.Failed", Body: []interface{}{"request failed, see the daemon log"}}
+	}
+}
+
+// introspectable implements org.freedesktop.DBus.Introspectable
+type introspectable string
+
+// Introspect returns the introspection XML (D-Bus method)
+func (i introspectable) Introspect() (string, *dbus.Error) {
+	return string(i), nil
+}
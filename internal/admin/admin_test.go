@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+// newTestService returns a Service backed by a fake bw serve reporting an
+// unlocked vault last synced at lastSync
+func newTestService(t *testing.T, lastSync string) *Service {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"template":{"status":"unlocked","lastSync":"` + lastSync + `"}}}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return NewService(nil, bitwarden.NewClient(port), "1.2.3", []string{"secrets", "ssh"})
+}
+
+func TestService_GetAll(t *testing.T) {
+	s := newTestService(t, "2024-01-02T03:04:05.000Z")
+	s.SetSessionCounter(func() int { return 2 })
+
+	props, dbusErr := s.GetAll(Interface)
+	if dbusErr != nil {
+		t.Fatalf("GetAll() error = %v", dbusErr)
+	}
+
+	checks := map[string]any{
+		"Version":      "1.2.3",
+		"Locked":       false,
+		"ServePID":     uint32(0),
+		"ServeHealthy": false, // bw serve was never started by this client
+		"Sessions":     uint32(2),
+		"LastSync":     int64(1704164645),
+	}
+	for name, want := range checks {
+		if got := props[name].Value(); got != want {
+			t.Errorf("%s = %v (%T), want %v (%T)", name, got, got, want, want)
+		}
+	}
+	if got := props["Components"].Value().([]string); len(got) != 2 || got[0] != "secrets" {
+		t.Errorf("Components = %v, want [secrets ssh]", got)
+	}
+	if props["ServeError"].Value() != serveFailure {
+		t.Errorf("ServeError = %q, want %q without the underlying error", props["ServeError"].Value(), serveFailure)
+	}
+
+	if v, dbusErr := s.Get(Interface, "Version"); dbusErr != nil || v.Value() != "1.2.3" {
+		t.Errorf("Get(Version) = %v, %v", v, dbusErr)
+	}
+	if _, dbusErr := s.Get(Interface, "Missing"); dbusErr == nil {
+		t.Error("Get(Missing) should fail")
+	}
+	if _, dbusErr := s.GetAll("org.example.Other"); dbusErr == nil {
+		t.Error("GetAll on another interface should fail")
+	}
+}
+
+func TestService_GetAll_NeverSynced(t *testing.T) {
+	s := newTestService(t, "")
+	props, dbusErr := s.GetAll(Interface)
+	if dbusErr != nil {
+		t.Fatal(dbusErr)
+	}
+	if got := props["LastSync"].Value(); got != int64(0) {
+		t.Errorf("LastSync = %v, want 0", got)
+	}
+	if got := props["Sessions"].Value(); got != uint32(0) {
+		t.Errorf("Sessions = %v, want 0 without the Secret Service", got)
+	}
+}
+
+func TestService_ListSSHKeys(t *testing.T) {
+	s := newTestService(t, "")
+
+	if _, dbusErr := s.ListSSHKeys(); dbusErr == nil || dbusErr.Name != "org.freedesktop.DBus.Error.NotSupported" {
+		t.Errorf("ListSSHKeys() without the agent = %v, want NotSupported", dbusErr)
+	}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := cryptossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetSSHKeyLister(func() ([]*agent.Key, error) {
+		return []*agent.Key{{Format: sshPub.Type(), Blob: sshPub.Marshal(), Comment: "GitHub"}}, nil
+	})
+
+	keys, dbusErr := s.ListSSHKeys()
+	if dbusErr != nil {
+		t.Fatalf("ListSSHKeys() error = %v", dbusErr)
+	}
+	want := SSHKey{Fingerprint: cryptossh.FingerprintSHA256(sshPub), Type: "ssh-ed25519", Comment: "GitHub"}
+	if len(keys) != 1 || keys[0] != want {
+		t.Errorf("ListSSHKeys() = %+v, want [%+v]", keys, want)
+	}
+}
+
+func TestService_ListSSHKeys_Locked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"template":{"status":"locked"}}}`))
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	s := NewService(nil, bitwarden.NewClient(port), "1.2.3", []string{"ssh"})
+
+	listed := false
+	s.SetSSHKeyLister(func() ([]*agent.Key, error) {
+		listed = true
+		return nil, nil
+	})
+
+	if _, dbusErr := s.ListSSHKeys(); dbusErr == nil || dbusErr.Name != "org.freedesktop.Secret.Error.IsLocked" {
+		t.Errorf("ListSSHKeys() while locked = %v, want IsLocked", dbusErr)
+	}
+	if listed {
+		t.Error("ListSSHKeys() asked the agent, which prompts to unlock")
+	}
+}
+
+func TestService_ReloadConfig(t *testing.T) {
+	s := newTestService(t, "")
+	if dbusErr := s.ReloadConfig(); dbusErr != nil {
+		t.Errorf("ReloadConfig() without a handler = %v", dbusErr)
+	}
+
+	s.SetReloadHandler(func() error { return errors.New("bad file") })
+	if dbusErr := s.ReloadConfig(); dbusErr == nil || dbusErr.Name != "org.freedesktop.DBus.Error.Failed" {
+		t.Errorf("ReloadConfig() = %v, want Failed for the handler's error", dbusErr)
+	}
+}
+
+func TestToDBusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{bitwarden.ErrVaultLocked, "org.freedesktop.Secret.Error.IsLocked"},
+		{bitwarden.ErrUserCancelled, ErrCancelled},
+		{errors.New("boom"), "org.freedesktop.DBus.Error.Failed"},
+	}
+	for _, tt := range tests {
+		if got := toDBusError(tt.err); got.Name != tt.want {
+			t.Errorf("toDBusError(%v) = %s, want %s", tt.err, got.Name, tt.want)
+		}
+	}
+
+	// Backend details are logged, not returned
+	got := toDBusError(errors.New(`HTTP 500: {"message":"secret detail"}`))
+	if body := fmt.Sprint(got.Body...); strings.Contains(body, "secret detail") {
+		t.Errorf("toDBusError() leaked %q", body)
+	}
+}
@@ -0,0 +1,53 @@
+package admin
+
+// IntrospectXML describes the admin object
+const IntrospectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+"http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="io.github.joe.BitwardenKeyring1">
+    <method name="Status">
+      <arg name="status" type="a{sv}" direction="out"/>
+    </method>
+    <method name="Lock"/>
+    <method name="Unlock"/>
+    <method name="Sync"/>
+    <method name="ReloadConfig"/>
+    <method name="ListSSHKeys">
+      <arg name="keys" type="a(sss)" direction="out"/>
+    </method>
+    <property name="Version" type="s" access="read"/>
+    <property name="Components" type="as" access="read"/>
+    <property name="Locked" type="b" access="read"/>
+    <property name="ServePID" type="u" access="read"/>
+    <property name="ServeHealthy" type="b" access="read"/>
+    <property name="ServeError" type="s" access="read"/>
+    <property name="Sessions" type="u" access="read"/>
+    <property name="LastSync" type="x" access="read"/>
+  </interface>
+  <interface name="org.freedesktop.DBus.Properties">
+    <method name="Get">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="Set">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="in"/>
+    </method>
+    <method name="GetAll">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="properties" type="a{sv}" direction="out"/>
+    </method>
+    <signal name="PropertiesChanged">
+      <arg name="interface" type="s"/>
+      <arg name="changed_properties" type="a{sv}"/>
+      <arg name="invalidated_properties" type="as"/>
+    </signal>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect">
+      <arg name="data" type="s" direction="out"/>
+    </method>
+  </interface>
+</node>`
@@ -0,0 +1,196 @@
+// Package autolock locks the vault after a period without Secret Service or
+// SSH agent activity, and when logind reports that the system is about to
+// sleep or that one of the user's sessions was locked.
+package autolock
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// Reasons passed to the lock function
+const (
+	ReasonIdle       = "idle"
+	ReasonSleep      = "sleep"
+	ReasonScreenLock = "screen-lock"
+)
+
+// logind names on the system bus
+const (
+	login1Dest    = "org.freedesktop.login1"
+	login1Manager = "org.freedesktop.login1.Manager"
+	login1Session = "org.freedesktop.login1.Session"
+)
+
+// Policy decides when to lock the vault and calls lock to do it
+type Policy struct {
+	idle  time.Duration
+	lock  func(reason string)
+	mu    sync.Mutex
+	timer *time.Timer
+
+	// sessionUser returns the UID owning a logind session; replaceable in tests
+	sessionUser func(path dbus.ObjectPath) (uint32, error)
+	systemConn  *dbus.Conn
+}
+
+// NewPolicy creates a policy that calls lock after idle without activity.
+// The idle timeout starts right away, so a vault that was already unlocked
+// locks even if it is never used. An idle duration of zero disables it.
+func NewPolicy(idle time.Duration, lock func(reason string)) *Policy {
+	p := &Policy{
+		idle: idle,
+		lock: lock,
+	}
+	p.Activity()
+	return p
+}
+
+// Activity records use of the vault and restarts the idle timeout
+func (p *Policy) Activity() {
+	if p == nil || p.idle <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timer == nil {
+		p.timer = time.AfterFunc(p.idle, p.idleExpired)
+		return
+	}
+	p.timer.Reset(p.idle)
+}
+
+// idleExpired locks once the idle timeout passed without activity
+func (p *Policy) idleExpired() {
+	logging.L.With("component", "autolock").Info("locking vault after idle timeout", "idle", p.idle)
+	p.lock(ReasonIdle)
+}
+
+// WatchLogind subscribes to logind on the system bus: PrepareForSleep
+// (before suspend or hibernate) and Lock on any session of the current user
+// lock the vault. It returns an error if the system bus is unavailable.
+func (p *Policy) WatchLogind(sleep, screenLock bool) error {
+	if !sleep && !screenLock {
+		return nil
+	}
+
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+
+	if sleep {
+		if err := conn.AddMatchSignal(
+			dbus.WithMatchSender(login1Dest),
+			dbus.WithMatchInterface(login1Manager),
+			dbus.WithMatchMember("PrepareForSleep"),
+		); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to watch for sleep: %w", err)
+		}
+	}
+	if screenLock {
+		if err := conn.AddMatchSignal(
+			dbus.WithMatchSender(login1Dest),
+			dbus.WithMatchInterface(login1Session),
+			dbus.WithMatchMember("Lock"),
+		); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to watch for session lock: %w", err)
+		}
+	}
+
+	p.mu.Lock()
+	p.systemConn = conn
+	if p.sessionUser == nil {
+		p.sessionUser = busSessionUser(conn)
+	}
+	p.mu.Unlock()
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+	go func() {
+		for sig := range signals {
+			p.handleSignal(sig)
+		}
+	}()
+	return nil
+}
+
+// handleSignal locks the vault for a logind signal that calls for it
+func (p *Policy) handleSignal(sig *dbus.Signal) {
+	log := logging.L.With("component", "autolock")
+
+	switch sig.Name {
+	case login1Manager + ".PrepareForSleep":
+		// Sent with true before sleeping and false after waking up
+		if starting, ok := singleBool(sig.Body); ok && starting {
+			log.Info("locking vault before sleep")
+			p.lock(ReasonSleep)
+		}
+
+	case login1Session + ".Lock":
+		uid, err := p.sessionUser(sig.Path)
+		if err != nil {
+			log.Warn("could not determine owner of locked session", "session", sig.Path, "error", err)
+			return
+		}
+		if uid != uint32(os.Getuid()) {
+			return
+		}
+		log.Info("locking vault with the session", "session", sig.Path)
+		p.lock(ReasonScreenLock)
+	}
+}
+
+// singleBool extracts the only argument of a signal carrying one boolean
+func singleBool(body []interface{}) (bool, bool) {
+	if len(body) != 1 {
+		return false, false
+	}
+	b, ok := body[0].(bool)
+	return b, ok
+}
+
+// busSessionUser reads a logind session's User property
+func busSessionUser(conn *dbus.Conn) func(dbus.ObjectPath) (uint32, error) {
+	return func(path dbus.ObjectPath) (uint32, error) {
+		v, err := conn.Object(login1Dest, path).GetProperty(login1Session + ".User")
+		if err != nil {
+			return 0, err
+		}
+		// User is (uo): the UID and the user's object path
+		var user struct {
+			UID  uint32
+			Path dbus.ObjectPath
+		}
+		if err := dbus.Store([]interface{}{v.Value()}, &user); err != nil {
+			return 0, fmt.Errorf("unexpected User property: %w", err)
+		}
+		return user.UID, nil
+	}
+}
+
+// Stop cancels the idle timeout and closes the system bus connection
+func (p *Policy) Stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	if p.systemConn != nil {
+		p.systemConn.Close()
+		p.systemConn = nil
+	}
+}
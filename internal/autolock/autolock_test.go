@@ -0,0 +1,129 @@
+package autolock
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// lockRecorder collects the reasons the vault was locked for
+type lockRecorder struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *lockRecorder) lock(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, reason)
+}
+
+func (r *lockRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.reasons...)
+}
+
+func TestPolicy_IdleTimeout(t *testing.T) {
+	rec := &lockRecorder{}
+	p := NewPolicy(50*time.Millisecond, rec.lock)
+	defer p.Stop()
+
+	// Activity keeps pushing the deadline back
+	for i := 0; i < 5; i++ {
+		p.Activity()
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := rec.get(); len(got) != 0 {
+		t.Fatalf("locked during activity: %v", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if got := rec.get(); len(got) != 1 || got[0] != ReasonIdle {
+		t.Errorf("locks after idle = %v, want [%s]", got, ReasonIdle)
+	}
+}
+
+func TestPolicy_IdleWithoutActivity(t *testing.T) {
+	rec := &lockRecorder{}
+	p := NewPolicy(30*time.Millisecond, rec.lock)
+	defer p.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if got := rec.get(); len(got) != 1 || got[0] != ReasonIdle {
+		t.Errorf("locks without any activity = %v, want [%s]", got, ReasonIdle)
+	}
+}
+
+func TestPolicy_IdleDisabled(t *testing.T) {
+	rec := &lockRecorder{}
+	p := NewPolicy(0, rec.lock)
+	defer p.Stop()
+
+	p.Activity()
+	if p.timer != nil {
+		t.Error("idle timer armed with idle timeout disabled")
+	}
+}
+
+func TestPolicy_HandleSignal(t *testing.T) {
+	me := uint32(os.Getuid())
+	users := map[dbus.ObjectPath]uint32{
+		"/org/freedesktop/login1/session/mine":  me,
+		"/org/freedesktop/login1/session/other": me + 1,
+	}
+
+	tests := []struct {
+		name string
+		sig  *dbus.Signal
+		want []string
+	}{
+		{
+			name: "about to sleep",
+			sig:  &dbus.Signal{Name: login1Manager + ".PrepareForSleep", Body: []interface{}{true}},
+			want: []string{ReasonSleep},
+		},
+		{
+			name: "woke up",
+			sig:  &dbus.Signal{Name: login1Manager + ".PrepareForSleep", Body: []interface{}{false}},
+		},
+		{
+			name: "own session locked",
+			sig:  &dbus.Signal{Name: login1Session + ".Lock", Path: "/org/freedesktop/login1/session/mine"},
+			want: []string{ReasonScreenLock},
+		},
+		{
+			name: "other user's session locked",
+			sig:  &dbus.Signal{Name: login1Session + ".Lock", Path: "/org/freedesktop/login1/session/other"},
+		},
+		{
+			name: "unknown session locked",
+			sig:  &dbus.Signal{Name: login1Session + ".Lock", Path: "/org/freedesktop/login1/session/gone"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &lockRecorder{}
+			p := NewPolicy(0, rec.lock)
+			p.sessionUser = func(path dbus.ObjectPath) (uint32, error) {
+				uid, ok := users[path]
+				if !ok {
+					return 0, errors.New("no such session")
+				}
+				return uid, nil
+			}
+
+			p.handleSignal(tt.sig)
+
+			got := rec.get()
+			if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+				t.Errorf("locks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package bitwarden
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// itemCache keeps the decoded vault items in memory so that read-heavy
+// callers (D-Bus property getters, searches, the SSH agent) are served
+// without listing the whole vault over HTTP on every call.
+//
+// Items are indexed by the base domain of their login URIs, which is what
+// SearchItems narrows on. Regular expression URIs can match any domain and
+// are indexed under anyDomain. The cache is refreshed from bw serve when it is
+// older than the refresh interval or has been marked stale (after Sync),
+// and it is dropped entirely when the vault is locked.
+type itemCache struct {
+	interval time.Duration
+	now      func() time.Time // test hook
+
+	// writeMu serializes refreshes with mutations so a refresh that fetched
+	// the item list before a create/update/delete cannot overwrite it.
+	writeMu sync.Mutex
+
+	mu        sync.RWMutex
+	items     map[string]*Item
+	order     []string                       // item IDs in vault order
+	byDomain  map[string]map[string]struct{} // base domain -> item IDs
+	loaded    bool
+	refreshed time.Time
+}
+
+// anyDomain is the index key of items returned by every search
+const anyDomain = "*"
+
+// ItemChanges lists what a refresh of the item cache found changed in bw
+// serve. Items written through the Client are applied to the cache directly
+// and so never show up here.
+type ItemChanges struct {
+	Added   []Item // items new to the cache
+	Updated []Item // items whose RevisionDate changed, as they are now
+	Removed []Item // deleted items, as they were last seen
+
+	// Reloaded is set when the cache was empty before the refresh, as on
+	// first use and after the vault was unlocked. Added then lists every
+	// item, whether or not it is new.
+	Reloaded bool
+}
+
+// Empty reports whether the refresh changed nothing
+func (c ItemChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+// newItemCache creates an empty cache refreshed every interval
+func newItemCache(interval time.Duration) *itemCache {
+	return &itemCache{
+		interval: interval,
+		now:      time.Now,
+		items:    make(map[string]*Item),
+		byDomain: make(map[string]map[string]struct{}),
+	}
+}
+
+// fresh reports whether the cache can serve reads without a refresh
+func (ic *itemCache) fresh() bool {
+	ic.mu.RLock()
+	defer ic.mu.RUnlock()
+	return ic.loaded && ic.now().Sub(ic.refreshed) < ic.interval
+}
+
+// refresh reloads the cache through fetch unless it is already fresh.
+// It reports whether a reload happened and what changed.
+func (ic *itemCache) refresh(fetch func() ([]Item, error)) (ItemChanges, bool, error) {
+	ic.writeMu.Lock()
+	defer ic.writeMu.Unlock()
+
+	if ic.fresh() {
+		return ItemChanges{}, false, nil
+	}
+	items, err := fetch()
+	if err != nil {
+		return ItemChanges{}, false, err
+	}
+	return ic.replace(items), true, nil
+}
+
+// replace applies a full item listing, keeping entries whose RevisionDate is
+// unchanged so only new and modified items are re-indexed. The returned
+// changes share no memory with the cache.
+// Callers must hold writeMu.
+func (ic *itemCache) replace(items []Item) ItemChanges {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	diff := ItemChanges{Reloaded: !ic.loaded}
+	seen := make(map[string]struct{}, len(items))
+	order := make([]string, 0, len(items))
+
+	for i := range items {
+		item := &items[i]
+		if item.ID == "" {
+			continue
+		}
+		if _, dup := seen[item.ID]; dup {
+			continue
+		}
+		seen[item.ID] = struct{}{}
+		order = append(order, item.ID)
+
+		old, ok := ic.items[item.ID]
+		if ok && old.RevisionDate.Equal(item.RevisionDate) {
+			continue
+		}
+		stored, err := item.Clone()
+		if err != nil {
+			continue // keeps the old entry, if any, until the next refresh
+		}
+		if ok {
+			diff.Updated = append(diff.Updated, *item)
+			ic.unindex(old)
+		} else {
+			diff.Added = append(diff.Added, *item)
+		}
+		ic.items[item.ID] = stored
+		ic.index(stored)
+	}
+
+	for id, old := range ic.items {
+		if _, ok := seen[id]; !ok {
+			ic.unindex(old)
+			delete(ic.items, id)
+			diff.Removed = append(diff.Removed, *old)
+		}
+	}
+
+	ic.order = order
+	ic.loaded = true
+	ic.refreshed = ic.now()
+	return diff
+}
+
+// put inserts or replaces a single item written through this service.
+// Before the first refresh there is nothing to update and it is a no-op.
+func (ic *itemCache) put(item *Item) {
+	if item == nil || item.ID == "" {
+		return
+	}
+	stored, err := item.Clone()
+	if err != nil {
+		ic.markStale()
+		return
+	}
+
+	ic.writeMu.Lock()
+	defer ic.writeMu.Unlock()
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if !ic.loaded {
+		return
+	}
+	if old, ok := ic.items[stored.ID]; ok {
+		ic.unindex(old)
+	} else {
+		ic.order = append(ic.order, stored.ID)
+	}
+	ic.items[stored.ID] = stored
+	ic.index(stored)
+}
+
+// remove drops a single item deleted through this service
+func (ic *itemCache) remove(id string) {
+	ic.writeMu.Lock()
+	defer ic.writeMu.Unlock()
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	old, ok := ic.items[id]
+	if !ok {
+		return
+	}
+	ic.unindex(old)
+	delete(ic.items, id)
+	for i, oid := range ic.order {
+		if oid == id {
+			ic.order = append(ic.order[:i], ic.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// markStale forces the next read to refresh while still keeping the
+// current items for the RevisionDate diff
+func (ic *itemCache) markStale() {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.refreshed = time.Time{}
+}
+
+// clear drops every cached item, waiting for any refresh in flight so it
+// cannot repopulate the cache afterwards
+func (ic *itemCache) clear() {
+	ic.writeMu.Lock()
+	defer ic.writeMu.Unlock()
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.items = make(map[string]*Item)
+	ic.byDomain = make(map[string]map[string]struct{})
+	ic.order = nil
+	ic.loaded = false
+	ic.refreshed = time.Time{}
+}
+
+// list returns copies of all cached items in vault order
+func (ic *itemCache) list() ([]Item, error) {
+	ic.mu.RLock()
+	defer ic.mu.RUnlock()
+	return ic.collect(func(string) bool { return true })
+}
+
+// search returns copies of the cached items sharing a base domain with
+// searchURL. Like bw's own URL search the result is a superset; callers
+// narrow it with their attribute matching. URLs without a host match all
+// items.
+func (ic *itemCache) search(searchURL string) ([]Item, error) {
+	domain := baseDomain(uriHost(searchURL))
+
+	ic.mu.RLock()
+	defer ic.mu.RUnlock()
+	if domain == "" {
+		return ic.collect(func(string) bool { return true })
+	}
+	ids, wild := ic.byDomain[domain], ic.byDomain[anyDomain]
+	return ic.collect(func(id string) bool {
+		_, ok := ids[id]
+		if !ok {
+			_, ok = wild[id]
+		}
+		return ok
+	})
+}
+
+// get returns a copy of a cached item
+func (ic *itemCache) get(id string) (*Item, bool) {
+	ic.mu.RLock()
+	defer ic.mu.RUnlock()
+	item, ok := ic.items[id]
+	if !ok {
+		return nil, false
+	}
+	c, err := item.Clone()
+	if err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// collect clones the items whose IDs pass keep. Callers must hold mu.
+func (ic *itemCache) collect(keep func(id string) bool) ([]Item, error) {
+	items := make([]Item, 0, len(ic.order))
+	for _, id := range ic.order {
+		if !keep(id) {
+			continue
+		}
+		c, err := ic.items[id].Clone()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *c)
+	}
+	return items, nil
+}
+
+// index adds item to the domain index. Callers must hold mu.
+func (ic *itemCache) index(item *Item) {
+	for _, domain := range itemDomains(item) {
+		ids := ic.byDomain[domain]
+		if ids == nil {
+			ids = make(map[string]struct{})
+			ic.byDomain[domain] = ids
+		}
+		ids[item.ID] = struct{}{}
+	}
+}
+
+// unindex removes item from the domain index. Callers must hold mu.
+func (ic *itemCache) unindex(item *Item) {
+	for _, domain := range itemDomains(item) {
+		if ids := ic.byDomain[domain]; ids != nil {
+			delete(ids, item.ID)
+			if len(ids) == 0 {
+				delete(ic.byDomain, domain)
+			}
+		}
+	}
+}
+
+// itemDomains returns the base domains of an item's login URIs, or
+// anyDomain for regular expressions
+func itemDomains(item *Item) []string {
+	if item.Login == nil {
+		return nil
+	}
+	var domains []string
+	for _, u := range item.Login.URIs {
+		if u.Match != nil && *u.Match == URIMatchRegularExpression {
+			domains = append(domains, anyDomain)
+			continue
+		}
+		if d := baseDomain(uriHost(u.URI)); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// uriHost extracts the lowercased host from a URI, accepting bare hostnames
+func uriHost(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// baseDomain reduces a host to the domain it is registered under, such as
+// example.co.uk for www.example.co.uk, so that subdomains share an index entry
+// as they do in mapping's base domain matching. IP addresses, single-label
+// hosts and public suffixes are returned as-is.
+func baseDomain(host string) string {
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+	host = strings.TrimSuffix(host, ".")
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
@@ -0,0 +1,348 @@
+package bitwarden
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault is a minimal bw serve backend for cache tests
+type fakeVault struct {
+	mu        sync.Mutex
+	items     []Item
+	listCalls int
+	nextID    int
+	lastPut   map[string]any // body of the last item update
+}
+
+func (v *fakeVault) handler(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/status":
+		w.Write([]byte(`{"success":true,"data":{"template":{"status":"unlocked"}}}`))
+	case r.URL.Path == "/list/object/items":
+		v.listCalls++
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "data": map[string]any{"data": v.items}})
+	case r.URL.Path == "/object/item" && r.Method == http.MethodPost:
+		var req CreateItemRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		v.nextID++
+		item := Item{ID: "new-" + string(rune('0'+v.nextID)), Type: req.Type, Name: req.Name, Login: req.Login, RevisionDate: time.Now()}
+		v.items = append(v.items, item)
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "data": item})
+	case strings.HasPrefix(r.URL.Path, "/object/item/"):
+		id := strings.TrimPrefix(r.URL.Path, "/object/item/")
+		for i := range v.items {
+			if v.items[i].ID != id {
+				continue
+			}
+			switch r.Method {
+			case http.MethodDelete:
+				v.items = append(v.items[:i], v.items[i+1:]...)
+				w.Write([]byte(`{"success":true}`))
+				return
+			case http.MethodGet:
+				json.NewEncoder(w).Encode(map[string]any{"success": true, "data": v.items[i]})
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			var req CreateItemRequest
+			json.Unmarshal(body, &req)
+			json.Unmarshal(body, &v.lastPut)
+			v.items[i].Name = req.Name
+			v.items[i].Notes = req.Notes
+			v.items[i].RevisionDate = time.Now()
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "data": v.items[i]})
+			return
+		}
+		http.NotFound(w, r)
+	default:
+		w.Write([]byte(`{"success":true}`))
+	}
+}
+
+func (v *fakeVault) lists() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.listCalls
+}
+
+func newCachedClient(t *testing.T, v *fakeVault) *Client {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(v.handler))
+	t.Cleanup(ts.Close)
+	c := clientWithPrompter(ts, &mockPrompter{}, false)
+	c.EnableItemCache(time.Hour)
+	return c
+}
+
+func loginItem(id, name, uri string) Item {
+	return Item{
+		ID:           id,
+		Type:         ItemTypeLogin,
+		Name:         name,
+		Login:        &Login{URIs: []URI{{URI: uri}}},
+		RevisionDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestItemCache_ServesReadsFromMemory(t *testing.T) {
+	ctx := context.Background()
+	v := &fakeVault{items: []Item{loginItem("a", "A", "https://example.com")}}
+	c := newCachedClient(t, v)
+
+	for i := 0; i < 3; i++ {
+		items, err := c.ListItems(ctx)
+		if err != nil {
+			t.Fatalf("ListItems() error = %v", err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("ListItems() = %d items, want 1", len(items))
+		}
+	}
+	if _, err := c.GetItem(ctx, "a"); err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+	if got := v.lists(); got != 1 {
+		t.Errorf("vault listed %d times, want 1", got)
+	}
+
+	// Returned items are copies
+	items, _ := c.ListItems(ctx)
+	items[0].Login.URIs[0].URI = "https://changed.example"
+	items, _ = c.ListItems(ctx)
+	if items[0].Login.URIs[0].URI != "https://example.com" {
+		t.Errorf("cached item was mutated through a returned copy")
+	}
+}
+
+func TestItemCache_WritesThroughService(t *testing.T) {
+	ctx := context.Background()
+	v := &fakeVault{items: []Item{loginItem("a", "A", "https://example.com")}}
+	c := newCachedClient(t, v)
+
+	if _, err := c.ListItems(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := c.CreateItem(ctx, CreateItemRequest{Type: ItemTypeLogin, Name: "B", Login: &Login{}})
+	if err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+	if _, err := c.UpdateItem(ctx, "a", CreateItemRequest{Type: ItemTypeLogin, Name: "A2"}); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	items, err := c.ListItems(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, item := range items {
+		names[item.Name] = true
+	}
+	if len(items) != 2 || !names["A2"] || !names["B"] {
+		t.Errorf("ListItems() = %+v, want A2 and B", items)
+	}
+
+	if err := c.DeleteItem(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if items, _ := c.ListItems(ctx); len(items) != 1 {
+		t.Errorf("ListItems() after delete = %d items, want 1", len(items))
+	}
+	if got := v.lists(); got != 1 {
+		t.Errorf("vault listed %d times, want 1", got)
+	}
+}
+
+func TestUpdateItem_MergesChangesMadeElsewhere(t *testing.T) {
+	ctx := context.Background()
+	v := &fakeVault{items: []Item{loginItem("a", "A", "https://example.com")}}
+	c := newCachedClient(t, v)
+
+	read, err := c.GetItem(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone adds notes in the browser extension
+	notes := "from the browser"
+	v.mu.Lock()
+	v.items[0].Notes = &notes
+	v.items[0].RevisionDate = v.items[0].RevisionDate.Add(time.Minute)
+	v.mu.Unlock()
+
+	read.Name = "Renamed"
+	updated, err := c.UpdateItem(ctx, "a", read.ToUpdateRequest())
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if updated.Name != "Renamed" || updated.Notes == nil || *updated.Notes != notes {
+		t.Errorf("UpdateItem() = name %q notes %v, want both changes", updated.Name, updated.Notes)
+	}
+}
+
+func TestUpdateItem_Conflict(t *testing.T) {
+	ctx := context.Background()
+	v := &fakeVault{items: []Item{loginItem("a", "A", "https://example.com")}}
+	c := newCachedClient(t, v)
+
+	read, err := c.GetItem(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v.mu.Lock()
+	v.items[0].Name = "Browser"
+	v.items[0].RevisionDate = v.items[0].RevisionDate.Add(time.Minute)
+	v.mu.Unlock()
+
+	read.Name = "Keyring"
+	_, err = c.UpdateItem(ctx, "a", read.ToUpdateRequest())
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("UpdateItem() error = %v, want a ConflictError", err)
+	}
+	if conflict.Current.Name != "Browser" {
+		t.Errorf("Current.Name = %q, want Browser", conflict.Current.Name)
+	}
+	if v.lastPut != nil {
+		t.Errorf("conflicting update was written: %v", v.lastPut)
+	}
+
+	// The cache now has the current item, so a retry goes through
+	retry, err := c.GetItem(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry.Name = "Keyring"
+	if _, err := c.UpdateItem(ctx, "a", retry.ToUpdateRequest()); err != nil {
+		t.Fatalf("retried UpdateItem() error = %v", err)
+	}
+}
+
+func TestItemCache_RefreshTriggers(t *testing.T) {
+	ctx := context.Background()
+	v := &fakeVault{items: []Item{loginItem("a", "A", "https://example.com")}}
+	c := newCachedClient(t, v)
+	now := time.Now()
+	c.cache.now = func() time.Time { return now }
+
+	c.ListItems(ctx)
+
+	// Interval elapsed
+	now = now.Add(2 * time.Hour)
+	c.ListItems(ctx)
+	if got := v.lists(); got != 2 {
+		t.Errorf("after interval: vault listed %d times, want 2", got)
+	}
+
+	// Sync
+	if err := c.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.ListItems(ctx)
+	if got := v.lists(); got != 3 {
+		t.Errorf("after Sync: vault listed %d times, want 3", got)
+	}
+
+	// Lock drops everything
+	if err := c.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.cache.get("a"); ok {
+		t.Error("cache still holds items after Lock")
+	}
+	c.ListItems(ctx)
+	if got := v.lists(); got != 4 {
+		t.Errorf("after Lock: vault listed %d times, want 4", got)
+	}
+}
+
+func TestItemCache_ReplaceDiffsByRevisionDate(t *testing.T) {
+	ic := newItemCache(time.Hour)
+	a := loginItem("a", "A", "https://example.com")
+	b := loginItem("b", "B", "https://example.org")
+	if first := ic.replace([]Item{a, b}); !first.Reloaded || len(first.Added) != 2 {
+		t.Errorf("first replace() = %+v, want a reload adding both items", first)
+	}
+
+	a.Name = "A2"
+	a.RevisionDate = a.RevisionDate.Add(time.Minute)
+	c := loginItem("c", "C", "https://example.net")
+	diff := ic.replace([]Item{a, c})
+
+	if diff.Reloaded || len(diff.Added) != 1 || diff.Added[0].ID != "c" ||
+		len(diff.Updated) != 1 || diff.Updated[0].Name != "A2" ||
+		len(diff.Removed) != 1 || diff.Removed[0].ID != "b" {
+		t.Errorf("replace() diff = %+v, want c added, a updated, b removed", diff)
+	}
+	if got, _ := ic.get("a"); got == nil || got.Name != "A2" {
+		t.Errorf("updated item = %+v, want name A2", got)
+	}
+	if found, _ := ic.search("https://example.org"); len(found) != 0 {
+		t.Errorf("removed item still indexed: %+v", found)
+	}
+}
+
+// regexItem returns a login whose only URI is a regular expression
+func regexItem(id, pattern string) Item {
+	item := loginItem(id, id, pattern)
+	match := URIMatchRegularExpression
+	item.Login.URIs[0].Match = &match
+	return item
+}
+
+func TestItemCache_SearchByBaseDomain(t *testing.T) {
+	ic := newItemCache(time.Hour)
+	ic.replace([]Item{
+		loginItem("a", "A", "https://example.com/login"),
+		loginItem("b", "B", "mail.example.com"),
+		loginItem("c", "C", "https://other.org"),
+		loginItem("d", "D", "https://10.0.0.1:8443"),
+		{ID: "e", Type: ItemTypeSecureNote, Name: "note"},
+		regexItem("f", `^https://intranet\.`),
+		loginItem("g", "G", "https://www.shop.co.uk"),
+		loginItem("h", "H", "https://bank.co.uk"),
+	})
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://www.example.com/x", []string{"a", "b", "f"}},
+		{"example.com", []string{"a", "b", "f"}},
+		{"https://other.org", []string{"c", "f"}},
+		{"https://10.0.0.1", []string{"d", "f"}},
+		{"https://nowhere.test", []string{"f"}},
+		{"https://shop.co.uk", []string{"f", "g"}},
+		{"", []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			found, err := ic.search(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, item := range found {
+				ids = append(ids, item.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("search(%q) = %v, want %v", tt.url, ids, tt.want)
+			}
+		})
+	}
+}
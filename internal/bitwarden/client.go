@@ -17,6 +17,8 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/joe/bitwarden-keyring/internal/logging"
 )
 
 // jsonBody marshals v to JSON and returns a reader for HTTP request bodies.
@@ -78,7 +80,8 @@ type passwordPrompter interface {
 	// Returns the password and an optional ResultNotifier.
 	// The ResultNotifier should be called after the unlock attempt to inform the prompter of the result.
 	// If the notifier is nil, the prompter doesn't support result notifications.
-	PromptForPassword(errMsg string) (password string, notifier ResultNotifier, err error)
+	// The dialog is shown on top of parent where the backend supports it.
+	PromptForPassword(errMsg string, parent ParentWindow) (password string, notifier ResultNotifier, err error)
 }
 
 // logHTTPBodySnippet returns a truncated and redacted snippet of an HTTP body for debug logging.
@@ -124,6 +127,19 @@ type Client struct {
 	autoUnlock atomic.Bool
 	debug      atomic.Bool
 	prompter   passwordPrompter // used for password prompting; defaults to session
+	cache      *itemCache       // nil unless EnableItemCache was called
+
+	onItemChanges func(ItemChanges) // see SetItemChangeHandler
+	onSync        func()            // see SetSyncHandler
+	changesMu     sync.Mutex        // serializes onItemChanges and onSync calls
+	unlocked      chan struct{}     // signaled by Unlock for RunSync
+
+	lock         lockState  // last seen lock state, see IsLockedSafe
+	onLockChange func(bool) // see SetLockChangeHandler
+	lockChangeMu sync.Mutex // serializes onLockChange calls
+
+	repromptGrace time.Duration // see SetRepromptGrace
+	verifiedAt    atomic.Int64  // unix nanos of the last confirmed re-prompt; 0 if none
 }
 
 // NewClient creates a new Bitwarden API client
@@ -138,7 +154,9 @@ func NewClientWithConfig(port int, sessionCfg SessionConfig) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		session: NewSessionManagerWithConfig(sessionCfg),
+		session:       NewSessionManagerWithConfig(sessionCfg),
+		repromptGrace: DefaultRepromptGrace,
+		unlocked:      make(chan struct{}, 1),
 	}
 	c.autoUnlock.Store(true)
 	c.prompter = c.session
@@ -146,12 +164,72 @@ func NewClientWithConfig(port int, sessionCfg SessionConfig) *Client {
 }
 
 // SetAutoUnlock enables or disables automatic unlocking with password prompt.
-// When disabled, operations that require an unlocked vault will return ErrVaultLocked.
+// When disabled, operations that require an unlocked vault will return
+// ErrVaultLocked unless their context came from WithUnlockPrompt.
 // This is useful for testing or programmatic control.
 func (c *Client) SetAutoUnlock(enabled bool) {
 	c.autoUnlock.Store(enabled)
 }
 
+// unlockPromptKey marks contexts created by WithUnlockPrompt
+type unlockPromptKey struct{}
+
+// WithUnlockPrompt returns a context whose requests ask for the master
+// password when the vault is locked, even if auto-unlock is disabled
+func WithUnlockPrompt(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unlockPromptKey{}, true)
+}
+
+// unlockPromptAllowed reports whether ctx came from WithUnlockPrompt
+func unlockPromptAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(unlockPromptKey{}).(bool)
+	return allowed
+}
+
+// EnableItemCache serves item reads from an in-memory copy of the vault that
+// is refreshed from bw serve once it is older than interval, after Sync, and
+// from scratch after the vault is locked. Items created, updated or deleted
+// through this client are applied to the cache directly. A non-positive
+// interval disables the cache. Call before the client is shared.
+func (c *Client) EnableItemCache(interval time.Duration) {
+	if interval <= 0 {
+		c.cache = nil
+		return
+	}
+	c.cache = newItemCache(interval)
+}
+
+// InvalidateItemCache forces the next item read to refresh from bw serve
+func (c *Client) InvalidateItemCache() {
+	if c.cache != nil {
+		c.cache.markStale()
+	}
+}
+
+// refreshItemCache reloads the item cache if it is stale.
+// The vault must be unlocked.
+func (c *Client) refreshItemCache(ctx context.Context) error {
+	diff, reloaded, err := c.cache.refresh(func() ([]Item, error) {
+		return c.listItemsInternal(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	if reloaded {
+		logging.L.Debug("item cache refreshed",
+			"added", len(diff.Added), "updated", len(diff.Updated), "removed", len(diff.Removed))
+		c.notifyItemChanges(diff)
+	}
+	return nil
+}
+
+// dropItemCache discards all cached items, e.g. because the vault is locked
+func (c *Client) dropItemCache() {
+	if c.cache != nil {
+		c.cache.clear()
+	}
+}
+
 // SetDebug enables or disables HTTP body logging for errors.
 // When enabled, APIError.DebugDetails() will contain response bodies (up to 4096 bytes
 // plus truncation indicator when exceeded), which are redacted but should only be
@@ -245,6 +323,13 @@ func (c *Client) ServeHealthy() error {
 	return nil
 }
 
+// ServePID returns the process ID of bw serve, or 0 when it isn't running
+func (c *Client) ServePID() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.servePID
+}
+
 // waitForReady waits for the bw serve API to be responsive
 func (c *Client) waitForReady(ctx context.Context) error {
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -334,20 +419,27 @@ func (c *Client) Status(ctx context.Context) (*StatusResponse, error) {
 	return &status, nil
 }
 
-// IsLocked returns whether the vault is currently locked
+// IsLocked asks bw serve whether the vault is currently locked
 func (c *Client) IsLocked(ctx context.Context) (bool, error) {
 	status, err := c.Status(ctx)
 	if err != nil {
 		return true, err
 	}
-	return status.Data.Template.Status == "locked", nil
+	locked := status.Data.Template.Status == "locked"
+	c.setLocked(locked)
+	return locked, nil
 }
 
-// IsLockedSafe returns whether the vault is currently locked, defaulting to true on error.
+// IsLockedSafe returns whether the vault is locked, defaulting to true on error.
+// It answers from the last seen lock state, kept current by Lock, Unlock,
+// IsLocked and WatchLockState, and only asks bw serve when there is none yet.
 // This is useful for D-Bus property getters that should return a safe default rather than
 // surfacing errors. Do not use this where errors should be propagated (e.g., in operations
 // that need to know the precise lock state before proceeding).
 func (c *Client) IsLockedSafe(ctx context.Context) bool {
+	if locked, known := c.lock.get(); known {
+		return locked
+	}
 	locked, err := c.IsLocked(ctx)
 	if err != nil {
 		return true // Safe default: assume locked on error
@@ -383,12 +475,22 @@ func (c *Client) Unlock(ctx context.Context, password string) (string, error) {
 
 	// Store the session key
 	c.session.SetSession(result.Data.Raw)
+	c.setLocked(false)
+
+	// Let RunSync pick up what changed while the vault was locked
+	select {
+	case c.unlocked <- struct{}{}:
+	default:
+	}
 
 	return result.Data.Raw, nil
 }
 
 // Lock locks the vault
 func (c *Client) Lock(ctx context.Context) error {
+	c.dropItemCache()
+	c.verifiedAt.Store(0)
+
 	resp, err := c.doRequest(ctx, "POST", "/lock", nil)
 	if err != nil {
 		return err
@@ -396,19 +498,22 @@ func (c *Client) Lock(ctx context.Context) error {
 	defer resp.Body.Close()
 
 	c.session.ClearSession()
+	c.setLocked(true)
 	return nil
 }
 
 // ensureUnlocked checks if the vault is locked and prompts for unlock if needed.
-// Only prompts if autoUnlock is true. Returns ErrVaultLocked if autoUnlock is false.
+// Only prompts if autoUnlock is true or ctx came from WithUnlockPrompt;
+// returns ErrVaultLocked otherwise.
 // Uses double-check locking to prevent concurrent password prompts.
 func (c *Client) ensureUnlocked(ctx context.Context) error {
-	if !c.autoUnlock.Load() {
+	if !c.autoUnlock.Load() && !unlockPromptAllowed(ctx) {
 		locked, err := c.IsLocked(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to check vault status: %w", err)
 		}
 		if locked {
+			c.dropItemCache()
 			return ErrVaultLocked
 		}
 		return nil
@@ -423,6 +528,9 @@ func (c *Client) ensureUnlocked(ctx context.Context) error {
 		return nil
 	}
 
+	// Whatever was cached belongs to a session that is gone
+	c.dropItemCache()
+
 	// Check context before waiting on lock
 	if err := ctx.Err(); err != nil {
 		return err
@@ -451,13 +559,21 @@ func (c *Client) ensureUnlocked(ctx context.Context) error {
 		return fmt.Errorf("backend not healthy: %w", err)
 	}
 
+	return c.promptAndUnlock(ctx, "")
+}
+
+// promptAndUnlock asks for the master password and unlocks the vault with it,
+// retrying wrong passwords. reason, if set, is shown with the first prompt.
+// Callers must hold unlockMu.
+func (c *Client) promptAndUnlock(ctx context.Context, reason string) error {
 	// Retry loop for password prompts
 	maxRetries := c.session.MaxPasswordRetries()
 	if maxRetries <= 0 {
 		maxRetries = 3
 	}
 
-	var errMsg string
+	errMsg := reason
+	parent := parentWindowFrom(ctx)
 	var notifier ResultNotifier
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -473,14 +589,14 @@ func (c *Client) ensureUnlocked(ctx context.Context) error {
 		// For subsequent attempts with a notifier, wait for retry via the session
 		if attempt == 1 || notifier == nil {
 			// Prompt for password with optional error message for retries
-			password, notifier, err = c.prompter.PromptForPassword(errMsg)
+			password, notifier, err = c.prompter.PromptForPassword(errMsg, parent)
 			if err != nil {
 				return err // Preserves ErrUserCancelled
 			}
 		} else {
 			// Notifier was already set and supports retry - password comes from WaitForRetry
 			// The session manager handles this internally
-			password, notifier, err = c.prompter.PromptForPassword(errMsg)
+			password, notifier, err = c.prompter.PromptForPassword(errMsg, parent)
 			if err != nil {
 				return err
 			}
@@ -539,6 +655,8 @@ func (c *Client) Sync(ctx context.Context) error {
 		return err
 	}
 	defer resp.Body.Close()
+
+	c.InvalidateItemCache()
 	return nil
 }
 
@@ -564,6 +682,13 @@ func (c *Client) ListItems(ctx context.Context) ([]Item, error) {
 	var items []Item
 	err := c.withAutoUnlock(ctx, func() error {
 		var err error
+		if c.cache != nil {
+			if err = c.refreshItemCache(ctx); err != nil {
+				return err
+			}
+			items, err = c.cache.list()
+			return err
+		}
 		items, err = c.listItemsInternal(ctx)
 		return err
 	})
@@ -595,6 +720,13 @@ func (c *Client) SearchItems(ctx context.Context, searchURL string) ([]Item, err
 	var items []Item
 	err := c.withAutoUnlock(ctx, func() error {
 		var err error
+		if c.cache != nil {
+			if err = c.refreshItemCache(ctx); err != nil {
+				return err
+			}
+			items, err = c.cache.search(searchURL)
+			return err
+		}
 		items, err = c.searchItemsInternal(ctx, searchURL)
 		return err
 	})
@@ -623,6 +755,15 @@ func (c *Client) GetItem(ctx context.Context, id string) (*Item, error) {
 	var item *Item
 	err := c.withAutoUnlock(ctx, func() error {
 		var err error
+		if c.cache != nil {
+			if err = c.refreshItemCache(ctx); err != nil {
+				return err
+			}
+			if cached, ok := c.cache.get(id); ok {
+				item = cached
+				return nil
+			}
+		}
 		item, err = c.getItemInternal(ctx, id)
 		return err
 	})
@@ -661,6 +802,9 @@ func (c *Client) CreateItem(ctx context.Context, item CreateItemRequest) (*Item,
 	err := c.withAutoUnlock(ctx, func() error {
 		var err error
 		result, err = c.createItemInternal(ctx, item)
+		if err == nil && c.cache != nil {
+			c.cache.put(result)
+		}
 		return err
 	})
 	return result, err
@@ -689,11 +833,29 @@ func (c *Client) updateItemInternal(ctx context.Context, id string, item CreateI
 
 // UpdateItem updates an existing item.
 // Automatically prompts for unlock if the vault is locked.
+//
+// Requests made by Item.ToUpdateRequest are checked against the item as it
+// is now: if it was changed since it was read, the update is merged with
+// those changes, or fails with a *ConflictError when both touch the same
+// field.
 func (c *Client) UpdateItem(ctx context.Context, id string, item CreateItemRequest) (*Item, error) {
 	var result *Item
 	err := c.withAutoUnlock(ctx, func() error {
-		var err error
-		result, err = c.updateItemInternal(ctx, id, item)
+		current, err := c.getItemInternal(ctx, id)
+		if err != nil {
+			return err
+		}
+		req, err := item.rebase(current)
+		if err != nil {
+			if c.cache != nil {
+				c.cache.put(current)
+			}
+			return err
+		}
+		result, err = c.updateItemInternal(ctx, id, req)
+		if err == nil && c.cache != nil {
+			c.cache.put(result)
+		}
 		return err
 	})
 	return result, err
@@ -713,7 +875,13 @@ func (c *Client) deleteItemInternal(ctx context.Context, id string) error {
 // Automatically prompts for unlock if the vault is locked.
 func (c *Client) DeleteItem(ctx context.Context, id string) error {
 	return c.withAutoUnlock(ctx, func() error {
-		return c.deleteItemInternal(ctx, id)
+		if err := c.deleteItemInternal(ctx, id); err != nil {
+			return err
+		}
+		if c.cache != nil {
+			c.cache.remove(id)
+		}
+		return nil
 	})
 }
 
@@ -745,6 +913,100 @@ func (c *Client) ListFolders(ctx context.Context) ([]Folder, error) {
 	return folders, err
 }
 
+// createFolderInternal performs the actual CreateFolder API call
+func (c *Client) createFolderInternal(ctx context.Context, name string) (*Folder, error) {
+	body, err := jsonBody(FolderRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/object/folder", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result APIResponse[Folder]
+	if err := decodeJSON(resp.Body, &result, "create folder response"); err != nil {
+		return nil, err
+	}
+
+	if result.Data.ID == "" {
+		return nil, fmt.Errorf("created folder has no ID")
+	}
+
+	return &result.Data, nil
+}
+
+// CreateFolder creates a new folder in the vault.
+// Automatically prompts for unlock if the vault is locked.
+func (c *Client) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	var folder *Folder
+	err := c.withAutoUnlock(ctx, func() error {
+		var err error
+		folder, err = c.createFolderInternal(ctx, name)
+		return err
+	})
+	return folder, err
+}
+
+// updateFolderInternal performs the actual UpdateFolder API call
+func (c *Client) updateFolderInternal(ctx context.Context, id, name string) (*Folder, error) {
+	body, err := jsonBody(FolderRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, "PUT", "/object/folder/"+id, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result APIResponse[Folder]
+	if err := decodeJSON(resp.Body, &result, "update folder response"); err != nil {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
+
+// UpdateFolder renames an existing folder.
+// Automatically prompts for unlock if the vault is locked.
+func (c *Client) UpdateFolder(ctx context.Context, id, name string) (*Folder, error) {
+	var folder *Folder
+	err := c.withAutoUnlock(ctx, func() error {
+		var err error
+		folder, err = c.updateFolderInternal(ctx, id, name)
+		return err
+	})
+	return folder, err
+}
+
+// deleteFolderInternal performs the actual DeleteFolder API call
+func (c *Client) deleteFolderInternal(ctx context.Context, id string) error {
+	resp, err := c.doRequest(ctx, "DELETE", "/object/folder/"+id, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
+
+// DeleteFolder deletes a folder by ID. Bitwarden moves the folder's items
+// to "No Folder" rather than deleting them.
+// Automatically prompts for unlock if the vault is locked.
+func (c *Client) DeleteFolder(ctx context.Context, id string) error {
+	return c.withAutoUnlock(ctx, func() error {
+		if err := c.deleteFolderInternal(ctx, id); err != nil {
+			return err
+		}
+		// Cached items still reference the deleted folder
+		c.InvalidateItemCache()
+		return nil
+	})
+}
+
 // doRequest performs an HTTP request to the Bitwarden API
 func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
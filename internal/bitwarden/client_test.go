@@ -25,12 +25,14 @@ type mockPrompter struct {
 	callCount  atomic.Int32
 	onlyOnce   bool // If true, returns error on subsequent calls
 	onceCalled atomic.Bool
-	lastErrMsg string // Records the last error message passed to prompt
+	lastErrMsg string       // Records the last error message passed to prompt
+	lastParent ParentWindow // Records the last parent window passed to prompt
 }
 
-func (m *mockPrompter) PromptForPassword(errMsg string) (string, ResultNotifier, error) {
+func (m *mockPrompter) PromptForPassword(errMsg string, parent ParentWindow) (string, ResultNotifier, error) {
 	m.callCount.Add(1)
 	m.lastErrMsg = errMsg
+	m.lastParent = parent
 	if m.onlyOnce {
 		if m.onceCalled.Load() {
 			return "", nil, errors.New("prompt called more than once")
@@ -49,7 +51,7 @@ type blockingPrompter struct {
 	once      atomic.Bool
 }
 
-func (p *blockingPrompter) PromptForPassword(errMsg string) (string, ResultNotifier, error) {
+func (p *blockingPrompter) PromptForPassword(errMsg string, parent ParentWindow) (string, ResultNotifier, error) {
 	p.callCount.Add(1)
 	if p.once.Load() {
 		return "", nil, errors.New("prompt called more than once")
@@ -69,7 +71,7 @@ type retryPrompter struct {
 	mu        sync.Mutex
 }
 
-func (p *retryPrompter) PromptForPassword(errMsg string) (string, ResultNotifier, error) {
+func (p *retryPrompter) PromptForPassword(errMsg string, parent ParentWindow) (string, ResultNotifier, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -559,6 +561,35 @@ func TestSetAutoUnlock_TogglesBehavior(t *testing.T) {
 	}
 }
 
+func TestEnsureUnlocked_WithUnlockPromptOverridesDisabledAutoUnlock(t *testing.T) {
+	var unlockCalls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/status":
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"locked"}}}`))
+		case "/unlock":
+			atomic.AddInt32(&unlockCalls, 1)
+			w.Write([]byte(`{"success":true,"data":{"raw":"test-token"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	prompter := &mockPrompter{password: "pw"}
+	c := clientWithPrompter(ts, prompter, false)
+	makeServeHealthy(c)
+
+	if err := c.ensureUnlocked(WithUnlockPrompt(context.Background())); err != nil {
+		t.Fatalf("ensureUnlocked() error = %v, want nil", err)
+	}
+	if prompter.callCount.Load() != 1 {
+		t.Fatalf("prompter calls = %d, want 1", prompter.callCount.Load())
+	}
+	if atomic.LoadInt32(&unlockCalls) != 1 {
+		t.Fatalf("/unlock calls = %d, want 1", unlockCalls)
+	}
+}
+
 func TestWithAutoUnlock_DoesNotCallFnOnEnsureError(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -1423,3 +1454,61 @@ func TestDoRequest_TruncatesLargeErrorBody(t *testing.T) {
 		t.Errorf("DebugDetails() length = %d, expected truncated to ~4096 bytes", len(details))
 	}
 }
+
+func TestFolderCRUD_UsesFolderEndpoints(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/status":
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"unlocked"}}}`))
+		case r.Method == "DELETE":
+			w.Write([]byte(`{"success":true}`))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"name":"Work"`) {
+				t.Errorf("%s %s body = %s, want folder name", r.Method, r.URL.Path, body)
+			}
+			w.Write([]byte(`{"success":true,"data":{"id":"folder-1","name":"Work"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := clientWithPrompter(ts, &mockPrompter{}, false)
+	ctx := context.Background()
+
+	folder, err := c.CreateFolder(ctx, "Work")
+	if err != nil {
+		t.Fatalf("CreateFolder() error = %v", err)
+	}
+	if folder.ID != "folder-1" || folder.Name != "Work" {
+		t.Errorf("CreateFolder() = %+v, want folder-1/Work", folder)
+	}
+
+	if _, err := c.UpdateFolder(ctx, "folder-1", "Work"); err != nil {
+		t.Fatalf("UpdateFolder() error = %v", err)
+	}
+
+	if err := c.DeleteFolder(ctx, "folder-1"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"POST /object/folder", "PUT /object/folder/folder-1", "DELETE /object/folder/folder-1"}
+	var got []string
+	for _, call := range calls {
+		if call != "GET /status" {
+			got = append(got, call)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+package bitwarden
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/joe/bitwarden-keyring/internal/logging"
+	"github.com/joe/bitwarden-keyring/internal/noctalia"
+)
+
+// AccessChoice is the user's answer to an access confirmation prompt
+type AccessChoice int
+
+const (
+	AccessDeny AccessChoice = iota
+	AccessAllowOnce
+	AccessAllowAlways
+)
+
+// Button labels shared by the confirmation backends
+const (
+	accessLabelOnce   = "Allow once"
+	accessLabelAlways = "Always allow"
+	accessLabelDeny   = "Deny"
+)
+
+// String returns the button label of the choice
+func (c AccessChoice) String() string {
+	switch c {
+	case AccessAllowOnce:
+		return accessLabelOnce
+	case AccessAllowAlways:
+		return accessLabelAlways
+	default:
+		return accessLabelDeny
+	}
+}
+
+// PromptForAccess asks the user whether an application may read a secret,
+// trying the same backends as PromptForPassword. Noctalia only offers a
+// confirm/cancel dialog, so confirming there allows once. Returns
+// ErrUserCancelled if the prompt is dismissed or times out without a choice.
+func (sm *SessionManager) PromptForAccess(message string) (AccessChoice, error) {
+	if sm.noctaliaClient != nil && sm.noctaliaClient.IsAvailable() {
+		ctx, cancel := context.WithTimeout(context.Background(), noctalia.DefaultTimeout)
+		err := sm.noctaliaClient.RequestConfirmation(ctx, "Bitwarden Keyring", "Allow access to a secret?", message)
+		cancel()
+		if err == nil {
+			return AccessAllowOnce, nil
+		}
+		if errors.Is(err, noctalia.ErrCancelled) || errors.Is(err, noctalia.ErrTimeout) {
+			return AccessDeny, ErrUserCancelled
+		}
+		logging.L.Info("noctalia confirmation failed, trying fallback methods", "error", err)
+	}
+
+	if commandExists("zenity") {
+		return confirmZenity(message)
+	}
+	if commandExists("kdialog") {
+		return confirmKDialog(message)
+	}
+	if commandExists("rofi") {
+		return confirmRofi(message)
+	}
+
+	return AccessDeny, fmt.Errorf("no confirmation prompt method available (install zenity, kdialog or rofi)")
+}
+
+// confirmZenity shows a zenity question with an extra "Always allow" button.
+// zenity exits 1 for both the cancel and the extra button, printing the
+// extra button's label in the latter case.
+func confirmZenity(message string) (AccessChoice, error) {
+	output, err := exec.Command("zenity",
+		"--question",
+		"--title=Bitwarden Keyring",
+		"--text="+message,
+		"--ok-label="+accessLabelOnce,
+		"--cancel-label="+accessLabelDeny,
+		"--extra-button="+accessLabelAlways,
+		"--timeout=120",
+	).Output()
+	if err == nil {
+		return AccessAllowOnce, nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return AccessDeny, err
+	}
+	switch exitErr.ExitCode() {
+	case 1:
+		if strings.TrimSpace(string(output)) == accessLabelAlways {
+			return AccessAllowAlways, nil
+		}
+		return AccessDeny, nil
+	case 5: // timeout
+		return AccessDeny, ErrUserCancelled
+	default:
+		return AccessDeny, err
+	}
+}
+
+// confirmKDialog shows a kdialog yes/no/cancel box: yes allows once,
+// no always allows and cancel denies
+func confirmKDialog(message string) (AccessChoice, error) {
+	err := exec.Command("kdialog",
+		"--title", "Bitwarden Keyring",
+		"--yesnocancel", message,
+		"--yes-label", accessLabelOnce,
+		"--no-label", accessLabelAlways,
+		"--cancel-label", accessLabelDeny,
+	).Run()
+	if err == nil {
+		return AccessAllowOnce, nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return AccessDeny, err
+	}
+	switch exitErr.ExitCode() {
+	case 1:
+		return AccessAllowAlways, nil
+	case 2:
+		return AccessDeny, nil
+	default:
+		return AccessDeny, err
+	}
+}
+
+// confirmRofi offers the three choices as a rofi menu
+func confirmRofi(message string) (AccessChoice, error) {
+	cmd := exec.Command("rofi", "-dmenu", "-p", "Bitwarden Keyring", "-mesg", message, "-no-custom")
+	cmd.Stdin = strings.NewReader(strings.Join([]string{accessLabelOnce, accessLabelAlways, accessLabelDeny}, "\n"))
+	choice, err := runPromptCommand(cmd)
+	if err != nil {
+		return AccessDeny, err
+	}
+
+	switch choice {
+	case accessLabelOnce:
+		return AccessAllowOnce, nil
+	case accessLabelAlways:
+		return AccessAllowAlways, nil
+	default:
+		return AccessDeny, nil
+	}
+}
+
+// PromptForConfirmation asks the user a yes/no question using the same
+// backends as PromptForAccess. It returns false if the user declines and
+// ErrUserCancelled if the prompt is dismissed or times out.
+func (sm *SessionManager) PromptForConfirmation(message string) (bool, error) {
+	if sm.noctaliaClient != nil && sm.noctaliaClient.IsAvailable() {
+		ctx, cancel := context.WithTimeout(context.Background(), noctalia.DefaultTimeout)
+		err := sm.noctaliaClient.RequestConfirmation(ctx, "Bitwarden Keyring", message, "")
+		cancel()
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, noctalia.ErrCancelled):
+			return false, nil
+		case errors.Is(err, noctalia.ErrTimeout):
+			return false, ErrUserCancelled
+		}
+		logging.L.Info("noctalia confirmation failed, trying fallback methods", "error", err)
+	}
+
+	if commandExists("zenity") {
+		err := exec.Command("zenity",
+			"--question",
+			"--title=Bitwarden Keyring",
+			"--text="+message,
+			"--timeout=120",
+		).Run()
+		return confirmExitCode(err, 5)
+	}
+	if commandExists("kdialog") {
+		err := exec.Command("kdialog", "--title", "Bitwarden Keyring", "--yesno", message).Run()
+		return confirmExitCode(err, -1)
+	}
+	if commandExists("rofi") {
+		cmd := exec.Command("rofi", "-dmenu", "-p", "Bitwarden Keyring", "-mesg", message, "-no-custom")
+		cmd.Stdin = strings.NewReader("Yes\nNo")
+		choice, err := runPromptCommand(cmd)
+		if err != nil {
+			return false, err
+		}
+		return choice == "Yes", nil
+	}
+
+	return false, fmt.Errorf("no confirmation prompt method available (install zenity, kdialog or rofi)")
+}
+
+// confirmExitCode maps a yes/no dialog's exit status to an answer:
+// 0 is yes, 1 is no and timeoutCode (if the tool has one) is a dismissal
+func confirmExitCode(err error, timeoutCode int) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false, err
+	}
+	switch exitErr.ExitCode() {
+	case 1:
+		return false, nil
+	case timeoutCode:
+		return false, ErrUserCancelled
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,94 @@
+package bitwarden
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakePromptTool installs a shell script named name as the only tool on PATH
+func fakePromptTool(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestPromptForAccess_Backends(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		script  string
+		want    AccessChoice
+		wantErr error
+	}{
+		{"zenity ok", "zenity", "exit 0", AccessAllowOnce, nil},
+		{"zenity extra button", "zenity", "echo 'Always allow'; exit 1", AccessAllowAlways, nil},
+		{"zenity cancel", "zenity", "exit 1", AccessDeny, nil},
+		{"zenity timeout", "zenity", "exit 5", AccessDeny, ErrUserCancelled},
+		{"kdialog yes", "kdialog", "exit 0", AccessAllowOnce, nil},
+		{"kdialog no", "kdialog", "exit 1", AccessAllowAlways, nil},
+		{"kdialog cancel", "kdialog", "exit 2", AccessDeny, nil},
+		{"rofi always", "rofi", "read a; read b; echo \"$b\"", AccessAllowAlways, nil},
+		{"rofi escape", "rofi", "exit 1", AccessDeny, ErrUserCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakePromptTool(t, tt.tool, tt.script)
+			sm := &SessionManager{}
+
+			got, err := sm.PromptForAccess("git wants to read \"GitHub\"")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PromptForAccess() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PromptForAccess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptForAccess_NoBackend(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	sm := &SessionManager{}
+	if _, err := sm.PromptForAccess("x"); err == nil {
+		t.Error("PromptForAccess() with no backends should fail")
+	}
+}
+
+func TestPromptForConfirmation_Backends(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		script  string
+		want    bool
+		wantErr error
+	}{
+		{"zenity yes", "zenity", "exit 0", true, nil},
+		{"zenity no", "zenity", "exit 1", false, nil},
+		{"zenity timeout", "zenity", "exit 5", false, ErrUserCancelled},
+		{"kdialog yes", "kdialog", "exit 0", true, nil},
+		{"kdialog no", "kdialog", "exit 1", false, nil},
+		{"rofi yes", "rofi", "read a; echo \"$a\"", true, nil},
+		{"rofi escape", "rofi", "exit 1", false, ErrUserCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakePromptTool(t, tt.tool, tt.script)
+			sm := &SessionManager{}
+
+			got, err := sm.PromptForConfirmation("Use key?")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PromptForConfirmation() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PromptForConfirmation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
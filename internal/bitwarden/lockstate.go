@@ -0,0 +1,88 @@
+package bitwarden
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultLockPollInterval is how often WatchLockState asks bw serve whether
+// the vault is locked
+const DefaultLockPollInterval = 15 * time.Second
+
+// lockState caches whether the vault is locked, as last seen by the client
+type lockState struct {
+	mu     sync.Mutex
+	known  bool
+	locked bool
+}
+
+// set records the lock state and reports whether it changed. An unknown
+// state counts as unlocked: locking before the first status check is a
+// change, while finding the vault unlocked then is not.
+func (s *lockState) set(locked bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.locked != locked
+	s.known = true
+	s.locked = locked
+	return changed
+}
+
+// get returns the recorded lock state and whether there is one
+func (s *lockState) get() (locked, known bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked, s.known
+}
+
+// SetLockChangeHandler registers fn to be called when the vault is seen to
+// lock or unlock: through Lock and Unlock, including unlocks after a password
+// prompt, and through status checks such as WatchLockState's that find it
+// was locked or unlocked with bw directly. Calls are made on their own
+// goroutine, one at a time, with the lock state current at the time of the
+// call.
+func (c *Client) SetLockChangeHandler(fn func(locked bool)) {
+	c.lockChangeMu.Lock()
+	defer c.lockChangeMu.Unlock()
+	c.onLockChange = fn
+}
+
+// setLocked records a lock state seen by the client. When the vault turns
+// out to be locked, the item cache and master password entry belong to a
+// session that is gone and are dropped.
+func (c *Client) setLocked(locked bool) {
+	if !c.lock.set(locked) {
+		return
+	}
+	if locked {
+		c.dropItemCache()
+		c.verifiedAt.Store(0)
+	}
+	go func() {
+		c.lockChangeMu.Lock()
+		defer c.lockChangeMu.Unlock()
+		if c.onLockChange == nil {
+			return
+		}
+		locked, _ := c.lock.get()
+		c.onLockChange(locked)
+	}()
+}
+
+// WatchLockState asks bw serve for the vault status every interval, so that
+// locking or unlocking with bw directly reaches the lock change handler. It
+// returns when ctx is done.
+func (c *Client) WatchLockState(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.IsLocked(ctx) // records the state; errors leave it unchanged
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package bitwarden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockState_CachesAndReportsTransitions(t *testing.T) {
+	ctx := context.Background()
+	var locked atomic.Bool
+	var statusCalls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/status":
+			statusCalls.Add(1)
+			status := "unlocked"
+			if locked.Load() {
+				status = "locked"
+			}
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"` + status + `"}}}`))
+		case "/unlock":
+			locked.Store(false)
+			w.Write([]byte(`{"success":true,"data":{"raw":"session"}}`))
+		default:
+			locked.Store(true)
+			w.Write([]byte(`{"success":true}`))
+		}
+	}))
+	defer ts.Close()
+	c := clientWithPrompter(ts, &mockPrompter{}, false)
+
+	changes := make(chan bool, 4)
+	c.SetLockChangeHandler(func(locked bool) { changes <- locked })
+	expect := func(want bool) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("lock change handler got locked = %v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("lock change handler not called, want locked = %v", want)
+		}
+	}
+
+	// Property reads are answered from the first status check
+	for i := 0; i < 3; i++ {
+		if c.IsLockedSafe(ctx) {
+			t.Fatal("IsLockedSafe() = true, want false")
+		}
+	}
+	if got := statusCalls.Load(); got != 1 {
+		t.Errorf("status requested %d times, want 1", got)
+	}
+
+	if err := c.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expect(true)
+	if !c.IsLockedSafe(ctx) {
+		t.Error("IsLockedSafe() = false after Lock")
+	}
+
+	if _, err := c.Unlock(ctx, "password"); err != nil {
+		t.Fatal(err)
+	}
+	expect(false)
+
+	// bw lock run outside the daemon is found by the next status check
+	locked.Store(true)
+	if _, err := c.IsLocked(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expect(true)
+
+	// No transition, no call
+	c.IsLocked(ctx)
+	select {
+	case got := <-changes:
+		t.Errorf("unexpected lock change %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLockState_LockBeforeFirstStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+	c := clientWithPrompter(ts, &mockPrompter{}, false)
+
+	changes := make(chan bool, 1)
+	c.SetLockChangeHandler(func(locked bool) { changes <- locked })
+
+	if err := c.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		if !got {
+			t.Error("lock change handler got locked = false, want true")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Lock before the first status check did not reach the lock change handler")
+	}
+}
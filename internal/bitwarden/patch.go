@@ -0,0 +1,177 @@
+package bitwarden
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// patchItemJSON applies an update request to the JSON of an item.
+//
+// The request is compared with the request orig, the item it was made from,
+// would have produced, and only what differs is written onto onto: changed
+// values replace the existing ones, objects are patched key by key, and fields
+// the request dropped become null. Everything else, including keys the Item
+// struct doesn't model, is kept from onto. Array elements (URIs, custom
+// fields) that are unchanged keep their existing JSON, wherever they moved.
+func patchItemJSON(orig, onto, updated []byte) ([]byte, error) {
+	before, err := requestValue(orig)
+	if err != nil {
+		return nil, err
+	}
+	base, err := decodeValue(onto)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeValue(updated)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(patchValue(base, before, after))
+}
+
+// rebase prepares an update request for the item as it is now in the vault.
+// Requests made from an item with the same revision date, or not made by
+// ToUpdateRequest, are returned unchanged. Otherwise the request is patched
+// onto current, keeping changes made elsewhere, unless those changed a field
+// the request changes too; that returns a *ConflictError.
+func (r CreateItemRequest) rebase(current *Item) (CreateItemRequest, error) {
+	if len(r.base) == 0 || len(current.raw) == 0 {
+		return r, nil
+	}
+	var orig Item
+	if err := json.Unmarshal(r.base, &orig); err != nil {
+		return r, err
+	}
+	if orig.RevisionDate.Equal(current.RevisionDate) {
+		return r, nil
+	}
+
+	before, err := requestValue(r.base)
+	if err != nil {
+		return r, err
+	}
+	theirs, err := requestValue(current.raw)
+	if err != nil {
+		return r, err
+	}
+	type plain CreateItemRequest
+	data, err := json.Marshal(plain(r))
+	if err != nil {
+		return r, err
+	}
+	ours, err := decodeValue(data)
+	if err != nil {
+		return r, err
+	}
+	if conflicting(before, ours, theirs) {
+		return r, &ConflictError{Current: current}
+	}
+
+	r.onto = current.raw
+	return r, nil
+}
+
+// requestValue returns the decoded update request an item's JSON produces
+func requestValue(itemJSON []byte) (any, error) {
+	var item Item
+	if err := json.Unmarshal(itemJSON, &item); err != nil {
+		return nil, err
+	}
+	req := item.ToUpdateRequest()
+	req.base = nil
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(data)
+}
+
+// decodeValue decodes JSON into generic values, keeping numbers exact
+func decodeValue(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// conflicting reports whether ours and theirs both changed the same value
+// of before, to different results. Objects are compared key by key; other
+// values, including whole arrays, as a unit.
+func conflicting(before, ours, theirs any) bool {
+	if reflect.DeepEqual(before, ours) || reflect.DeepEqual(before, theirs) || reflect.DeepEqual(ours, theirs) {
+		return false
+	}
+	b, ok := before.(map[string]any)
+	o, ok2 := ours.(map[string]any)
+	t, ok3 := theirs.(map[string]any)
+	if !ok || !ok2 || !ok3 {
+		return true
+	}
+	for _, m := range []map[string]any{b, o, t} {
+		for k := range m {
+			if conflicting(b[k], o[k], t[k]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patchValue returns base with the change from before to after applied
+func patchValue(base, before, after any) any {
+	if reflect.DeepEqual(before, after) {
+		return base
+	}
+
+	switch n := after.(type) {
+	case map[string]any:
+		b, ok := base.(map[string]any)
+		o, ok2 := before.(map[string]any)
+		if !ok || !ok2 {
+			return n
+		}
+		out := make(map[string]any, len(b)+len(n))
+		for k, v := range b {
+			out[k] = v
+		}
+		for k, nv := range n {
+			bv, inBase := b[k]
+			if !inBase && reflect.DeepEqual(o[k], nv) {
+				continue
+			}
+			out[k] = patchValue(bv, o[k], nv)
+		}
+		for k := range o {
+			if _, ok := n[k]; !ok {
+				out[k] = nil // omitted from the request, i.e. cleared
+			}
+		}
+		return out
+
+	case []any:
+		b, ok := base.([]any)
+		o, ok2 := before.([]any)
+		if !ok || !ok2 || len(b) != len(o) {
+			return n
+		}
+		out := make([]any, len(n))
+		used := make([]bool, len(o))
+		for i, nv := range n {
+			out[i] = nv
+			for j, ov := range o {
+				if !used[j] && reflect.DeepEqual(ov, nv) {
+					out[i] = b[j]
+					used[j] = true
+					break
+				}
+			}
+		}
+		return out
+	}
+
+	return after
+}
@@ -0,0 +1,145 @@
+package bitwarden
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// servedItem is a login as bw serve returns it, with data Item doesn't model
+const servedItem = `{
+	"object": "item",
+	"id": "item-1",
+	"organizationId": null,
+	"folderId": null,
+	"collectionIds": ["coll-1"],
+	"type": 1,
+	"name": "GitHub",
+	"notes": "keep me",
+	"favorite": false,
+	"reprompt": 0,
+	"login": {
+		"uris": [
+			{"match": null, "uri": "https://github.com", "uriChecksum": "abc"},
+			{"match": 3, "uri": "https://gist.github.com/", "uriChecksum": "def"}
+		],
+		"username": "joe",
+		"password": "old",
+		"totp": null,
+		"passwordRevisionDate": "2024-01-01T00:00:00.000Z",
+		"fido2Credentials": [{"credentialId": "cred-1", "rpId": "github.com"}]
+	},
+	"fields": [
+		{"name": "linked", "value": null, "type": 3, "linkedId": 100},
+		{"name": "token", "value": "t", "type": 1, "linkedId": null}
+	],
+	"passwordHistory": [{"lastUsedDate": "2023-01-01T00:00:00.000Z", "password": "older"}],
+	"revisionDate": "2024-01-01T00:00:00.000Z",
+	"creationDate": "2023-01-01T00:00:00.000Z"
+}`
+
+// encodeRequest marshals a request and decodes it into generic JSON values
+func encodeRequest(t *testing.T, req CreateItemRequest) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return out
+}
+
+func TestItem_ToUpdateRequest_KeepsUnmodeledData(t *testing.T) {
+	var item Item
+	if err := json.Unmarshal([]byte(servedItem), &item); err != nil {
+		t.Fatal(err)
+	}
+
+	password := "new"
+	item.Login.Password = &password
+	item.Fields = append(item.Fields, Field{Name: "added", Value: "x"})
+
+	out := encodeRequest(t, item.ToUpdateRequest())
+
+	login := out["login"].(map[string]any)
+	if login["password"] != "new" {
+		t.Errorf("password = %v, want new", login["password"])
+	}
+	for _, key := range []string{"fido2Credentials", "passwordRevisionDate"} {
+		if _, ok := login[key]; !ok {
+			t.Errorf("login.%s dropped", key)
+		}
+	}
+	uris := login["uris"].([]any)
+	if len(uris) != 2 || uris[0].(map[string]any)["uriChecksum"] != "abc" || uris[1].(map[string]any)["match"] != float64(3) {
+		t.Errorf("uris = %v, want both URIs untouched", uris)
+	}
+	for _, key := range []string{"passwordHistory", "collectionIds", "id"} {
+		if _, ok := out[key]; !ok {
+			t.Errorf("%s dropped", key)
+		}
+	}
+
+	fields := out["fields"].([]any)
+	if len(fields) != 3 {
+		t.Fatalf("fields = %v, want 3", fields)
+	}
+	if linked := fields[0].(map[string]any); linked["linkedId"] != float64(100) {
+		t.Errorf("linked field = %v, want linkedId kept", linked)
+	}
+	if added := fields[2].(map[string]any); added["name"] != "added" || added["value"] != "x" {
+		t.Errorf("added field = %v", added)
+	}
+}
+
+func TestItem_ToUpdateRequest_ClearsRemovedFields(t *testing.T) {
+	var item Item
+	if err := json.Unmarshal([]byte(servedItem), &item); err != nil {
+		t.Fatal(err)
+	}
+
+	item.Notes = nil
+	item.Fields = nil
+
+	out := encodeRequest(t, item.ToUpdateRequest())
+	for _, key := range []string{"notes", "fields"} {
+		if v, ok := out[key]; !ok || v != nil {
+			t.Errorf("%s = %v (present %v), want null", key, v, ok)
+		}
+	}
+	if out["name"] != "GitHub" {
+		t.Errorf("name = %v, want GitHub", out["name"])
+	}
+}
+
+func TestItem_Clone_KeepsOriginalJSON(t *testing.T) {
+	var item Item
+	if err := json.Unmarshal([]byte(servedItem), &item); err != nil {
+		t.Fatal(err)
+	}
+	c, err := item.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Name = "Renamed"
+
+	out := encodeRequest(t, c.ToUpdateRequest())
+	if out["name"] != "Renamed" {
+		t.Errorf("name = %v, want Renamed", out["name"])
+	}
+	if _, ok := out["passwordHistory"]; !ok {
+		t.Error("clone lost the original JSON")
+	}
+}
+
+func TestCreateItemRequest_NewItemsEncodePlainly(t *testing.T) {
+	out := encodeRequest(t, CreateItemRequest{Type: ItemTypeSecureNote, Name: "note"})
+	if _, ok := out["login"]; ok {
+		t.Errorf("unexpected login in %v", out)
+	}
+	if out["name"] != "note" {
+		t.Errorf("name = %v, want note", out["name"])
+	}
+}
@@ -0,0 +1,58 @@
+package bitwarden
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Values of Item.Reprompt
+const (
+	RepromptNone     = 0
+	RepromptPassword = 1 // master password re-prompt
+)
+
+// DefaultRepromptGrace is how long a master password entry satisfies
+// re-prompt protected items unless SetRepromptGrace says otherwise
+const DefaultRepromptGrace = time.Minute
+
+// SetRepromptGrace sets how long after the master password was last entered
+// re-prompt protected items may be used without asking again. Zero asks
+// every time.
+func (c *Client) SetRepromptGrace(d time.Duration) {
+	c.unlockMu.Lock()
+	c.repromptGrace = d
+	c.unlockMu.Unlock()
+}
+
+// VerifyReprompt asks for the master password again before the secret of an
+// item with master password re-prompt is released. It returns nil at once
+// for other items and within the grace window after an earlier re-prompt.
+// Entering the password to unlock the vault does not count as a re-prompt.
+//
+// The password is checked by unlocking with it, which bw serve verifies
+// against the master password hash even if the vault is already unlocked.
+func (c *Client) VerifyReprompt(ctx context.Context, item *Item) error {
+	if item == nil || item.Reprompt != RepromptPassword {
+		return nil
+	}
+
+	// Serialize with unlock prompts so one password entry covers every waiter
+	c.unlockMu.Lock()
+	defer c.unlockMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if last := c.verifiedAt.Load(); last != 0 && c.repromptGrace > 0 &&
+		time.Since(time.Unix(0, last)) < c.repromptGrace {
+		return nil
+	}
+
+	if err := c.promptAndUnlock(ctx, fmt.Sprintf("%q is protected by master password re-prompt.", item.Name)); err != nil {
+		return err
+	}
+	c.verifiedAt.Store(time.Now().UnixNano())
+	return nil
+}
@@ -0,0 +1,175 @@
+package bitwarden
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRepromptServer returns a bw serve stand-in whose master password is "right"
+func newRepromptServer(t *testing.T, unlockCalls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/unlock":
+			unlockCalls.Add(1)
+			b, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(b), `"password":"right"`) {
+				w.Write([]byte(`{"success":true,"data":{"raw":"token"}}`))
+				return
+			}
+			w.Write([]byte(`{"success":false,"data":{"message":"Invalid master password."}}`))
+		default:
+			w.Write([]byte(`{"success":true}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestVerifyReprompt_UnprotectedItem(t *testing.T) {
+	var unlockCalls atomic.Int32
+	prompter := &mockPrompter{password: "right"}
+	c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+
+	if err := c.VerifyReprompt(context.Background(), &Item{Name: "plain"}); err != nil {
+		t.Fatalf("VerifyReprompt() error = %v", err)
+	}
+	if prompter.callCount.Load() != 0 {
+		t.Errorf("Prompter called %d times, want 0", prompter.callCount.Load())
+	}
+}
+
+func TestVerifyReprompt_GraceWindow(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		wantPrompts int32
+	}{
+		{"no grace asks every time", 0, 2},
+		{"grace covers the second read", time.Hour, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unlockCalls atomic.Int32
+			prompter := &mockPrompter{password: "right"}
+			c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+			c.SetRepromptGrace(tt.grace)
+			item := &Item{Name: "bank", Reprompt: RepromptPassword}
+
+			for i := 0; i < 2; i++ {
+				if err := c.VerifyReprompt(context.Background(), item); err != nil {
+					t.Fatalf("VerifyReprompt() error = %v", err)
+				}
+			}
+			if got := prompter.callCount.Load(); got != tt.wantPrompts {
+				t.Errorf("Prompter called %d times, want %d", got, tt.wantPrompts)
+			}
+			if got := unlockCalls.Load(); got != tt.wantPrompts {
+				t.Errorf("Unlock called %d times, want %d", got, tt.wantPrompts)
+			}
+			if !strings.Contains(prompter.lastErrMsg, `"bank"`) {
+				t.Errorf("prompt message %q does not name the item", prompter.lastErrMsg)
+			}
+		})
+	}
+}
+
+func TestVerifyReprompt_LockEndsGrace(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var unlockCalls atomic.Int32
+	prompter := &mockPrompter{password: "right"}
+	c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+	c.SetRepromptGrace(time.Hour)
+	item := &Item{Name: "bank", Reprompt: RepromptPassword}
+
+	if err := c.VerifyReprompt(context.Background(), item); err != nil {
+		t.Fatalf("VerifyReprompt() error = %v", err)
+	}
+	if err := c.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.VerifyReprompt(context.Background(), item); err != nil {
+		t.Fatalf("VerifyReprompt() error = %v", err)
+	}
+	if got := prompter.callCount.Load(); got != 2 {
+		t.Errorf("Prompter called %d times, want 2", got)
+	}
+}
+
+func TestVerifyReprompt_UnlockDoesNotCount(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var unlockCalls atomic.Int32
+	prompter := &mockPrompter{password: "right"}
+	c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+	c.SetRepromptGrace(time.Hour)
+
+	c.unlockMu.Lock()
+	err := c.promptAndUnlock(context.Background(), "")
+	c.unlockMu.Unlock()
+	if err != nil {
+		t.Fatalf("promptAndUnlock() error = %v", err)
+	}
+
+	if err := c.VerifyReprompt(context.Background(), &Item{Name: "bank", Reprompt: RepromptPassword}); err != nil {
+		t.Fatalf("VerifyReprompt() error = %v", err)
+	}
+	if got := prompter.callCount.Load(); got != 2 {
+		t.Errorf("Prompter called %d times, want 2: an unlock must not satisfy a re-prompt", got)
+	}
+}
+
+func TestVerifyReprompt_Failures(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	item := &Item{Name: "bank", Reprompt: RepromptPassword}
+
+	t.Run("wrong then right password", func(t *testing.T) {
+		var unlockCalls atomic.Int32
+		prompter := &retryPrompter{passwords: []string{"wrong", "right"}}
+		c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+
+		if err := c.VerifyReprompt(context.Background(), item); err != nil {
+			t.Fatalf("VerifyReprompt() error = %v", err)
+		}
+		if len(prompter.errMsgs) != 2 || !strings.Contains(prompter.errMsgs[1], "Incorrect password") {
+			t.Errorf("prompt messages = %q, want retry feedback", prompter.errMsgs)
+		}
+	})
+
+	t.Run("wrong password every time", func(t *testing.T) {
+		var unlockCalls atomic.Int32
+		prompter := &mockPrompter{password: "wrong"}
+		c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+
+		err := c.VerifyReprompt(context.Background(), item)
+		if !errors.Is(err, ErrMaxRetriesExceeded) {
+			t.Errorf("VerifyReprompt() error = %v, want %v", err, ErrMaxRetriesExceeded)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		var unlockCalls atomic.Int32
+		prompter := &mockPrompter{err: ErrUserCancelled}
+		c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+
+		err := c.VerifyReprompt(context.Background(), item)
+		if !errors.Is(err, ErrUserCancelled) {
+			t.Errorf("VerifyReprompt() error = %v, want %v", err, ErrUserCancelled)
+		}
+		if unlockCalls.Load() != 0 {
+			t.Errorf("Unlock called %d times, want 0", unlockCalls.Load())
+		}
+	})
+}
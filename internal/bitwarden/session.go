@@ -353,8 +353,10 @@ func getPromptOrder(cfg SessionConfig) []promptMethod {
 // PromptForPassword prompts the user for their master password using a GUI dialog.
 // If errMsg is non-empty, it's displayed as part of the prompt (for retry feedback).
 // It tries various GUI methods, falling back through the chain in order of security.
+// The dialog is attached to parent where the backend supports it: zenity and
+// kdialog on X11, Noctalia for either window system.
 // Returns the password, an optional ResultNotifier for two-phase communication, and an error.
-func (sm *SessionManager) PromptForPassword(errMsg string) (string, ResultNotifier, error) {
+func (sm *SessionManager) PromptForPassword(errMsg string, parent ParentWindow) (string, ResultNotifier, error) {
 	// Build minimal config from session manager fields
 	cfg := SessionConfig{
 		NoctaliaEnabled:      sm.noctaliaEnabled,
@@ -377,7 +379,7 @@ func (sm *SessionManager) PromptForPassword(errMsg string) (string, ResultNotifi
 			if sm.noctaliaClient == nil {
 				continue
 			}
-			password, notifier, err := sm.promptNoctalia(errMsg)
+			password, notifier, err := sm.promptNoctalia(errMsg, parent)
 			if err == nil {
 				return password, notifier, nil
 			}
@@ -394,7 +396,7 @@ func (sm *SessionManager) PromptForPassword(errMsg string) (string, ResultNotifi
 			if !commandExists("zenity") {
 				continue
 			}
-			password, err := sm.promptZenity(errMsg)
+			password, err := sm.promptZenity(errMsg, parent)
 			if err == nil {
 				return password, nil, nil
 			}
@@ -406,7 +408,7 @@ func (sm *SessionManager) PromptForPassword(errMsg string) (string, ResultNotifi
 			if !commandExists("kdialog") {
 				continue
 			}
-			password, err := sm.promptKDialog(errMsg)
+			password, err := sm.promptKDialog(errMsg, parent)
 			if err == nil {
 				return password, nil, nil
 			}
@@ -465,7 +467,7 @@ func (sm *SessionManager) PromptForPassword(errMsg string) (string, ResultNotifi
 
 // promptNoctalia uses the Noctalia agent for a password dialog with two-phase retry support.
 // Returns the password, a notifier function for sending results, and an error.
-func (sm *SessionManager) promptNoctalia(errMsg string) (string, ResultNotifier, error) {
+func (sm *SessionManager) promptNoctalia(errMsg string, parent ParentWindow) (string, ResultNotifier, error) {
 	if sm.noctaliaClient == nil {
 		return "", nil, noctalia.ErrSocketNotFound
 	}
@@ -508,7 +510,7 @@ func (sm *SessionManager) promptNoctalia(errMsg string) (string, ResultNotifier,
 		message = errMsg + "\n" + message
 	}
 
-	password, session, err := sm.noctaliaClient.RequestPasswordWithSession(ctx, "Bitwarden Keyring", message)
+	password, session, err := sm.noctaliaClient.RequestPasswordWithSession(ctx, "Bitwarden Keyring", message, parent.String())
 	if err != nil {
 		return "", nil, err
 	}
@@ -530,30 +532,39 @@ func (sm *SessionManager) promptNoctalia(errMsg string) (string, ResultNotifier,
 }
 
 // promptZenity uses zenity for a GTK password dialog
-func (sm *SessionManager) promptZenity(errMsg string) (string, error) {
+func (sm *SessionManager) promptZenity(errMsg string, parent ParentWindow) (string, error) {
 	text := "Enter your Bitwarden Master Password:"
 	if errMsg != "" {
 		text = errMsg + "\n" + text
 	}
-	return runPromptCommand(exec.Command("zenity",
+	args := []string{
 		"--password",
 		"--title=Bitwarden Keyring",
-		"--text="+text,
+		"--text=" + text,
 		"--timeout=120",
-	))
+	}
+	// zenity can only attach to X11 windows
+	if parent.System == WindowX11 {
+		args = append(args, "--attach="+parent.Handle)
+	}
+	return runPromptCommand(exec.Command("zenity", args...))
 }
 
 // promptKDialog uses kdialog for a KDE password dialog
-func (sm *SessionManager) promptKDialog(errMsg string) (string, error) {
+func (sm *SessionManager) promptKDialog(errMsg string, parent ParentWindow) (string, error) {
 	message := "Enter your Bitwarden Master Password:"
 	if errMsg != "" {
 		message = errMsg + "\n" + message
 	}
-	return runPromptCommand(exec.Command("kdialog",
+	args := []string{
 		"--password",
 		message,
 		"--title", "Bitwarden Keyring",
-	))
+	}
+	if parent.System == WindowX11 {
+		args = append(args, "--attach", parent.Handle)
+	}
+	return runPromptCommand(exec.Command("kdialog", args...))
 }
 
 // promptRofi uses rofi in dmenu mode for password input
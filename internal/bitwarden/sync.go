@@ -0,0 +1,97 @@
+package bitwarden
+
+import (
+	"context"
+	"time"
+
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// SetItemChangeHandler registers fn to be called with the changes each
+// refresh of the item cache finds, such as items added, edited or deleted in
+// the browser extension or on another device. Calls are made on their own
+// goroutine, one at a time, so fn may use the client. Without the item cache
+// there is nothing to compare and fn is never called. Call before the client
+// is shared.
+func (c *Client) SetItemChangeHandler(fn func(ItemChanges)) {
+	c.onItemChanges = fn
+}
+
+// notifyItemChanges passes non-empty changes to the item change handler
+func (c *Client) notifyItemChanges(changes ItemChanges) {
+	fn := c.onItemChanges
+	if fn == nil || changes.Empty() {
+		return
+	}
+	go func() {
+		c.changesMu.Lock()
+		defer c.changesMu.Unlock()
+		fn(changes)
+	}()
+}
+
+// SetSyncHandler registers fn to be called after each sync through SyncNow,
+// including background syncs, so that vault data the item cache doesn't
+// cover, such as folders, can be re-read. Calls are made on their own
+// goroutine, one at a time and in turn with the item change handler. Call
+// before the client is shared.
+func (c *Client) SetSyncHandler(fn func()) {
+	c.onSync = fn
+}
+
+// notifySynced calls the sync handler
+func (c *Client) notifySynced() {
+	fn := c.onSync
+	if fn == nil {
+		return
+	}
+	go func() {
+		c.changesMu.Lock()
+		defer c.changesMu.Unlock()
+		fn()
+	}()
+}
+
+// RunSync syncs the vault with the Bitwarden server every interval and right
+// after each unlock, then refreshes the item cache so the item change
+// handler learns about changes made elsewhere. A locked vault is skipped
+// rather than unlocked. It returns when ctx is done.
+func (c *Client) RunSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.unlocked:
+		}
+		if err := c.syncIfUnlocked(ctx); err != nil {
+			logging.L.With("component", "bitwarden").Warn("background sync failed", "error", err)
+		}
+	}
+}
+
+// syncIfUnlocked runs one background sync unless the vault is locked
+func (c *Client) syncIfUnlocked(ctx context.Context) error {
+	if locked, err := c.IsLocked(ctx); err != nil || locked {
+		return nil
+	}
+	return c.SyncNow(ctx)
+}
+
+// SyncNow syncs the vault with the Bitwarden server and refreshes the item
+// cache right away, so the item change handler hears about changes made
+// elsewhere without waiting for the next read. Like other requests it may
+// prompt to unlock the vault.
+func (c *Client) SyncNow(ctx context.Context) error {
+	if err := c.Sync(ctx); err != nil {
+		return err
+	}
+	c.notifySynced()
+	if c.cache == nil {
+		return nil
+	}
+	return c.refreshItemCache(ctx)
+}
@@ -0,0 +1,69 @@
+package bitwarden
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSyncIfUnlocked_ReportsItemChanges(t *testing.T) {
+	ctx := context.Background()
+	v := &fakeVault{items: []Item{loginItem("a", "A", "https://example.com"), loginItem("b", "B", "https://example.org")}}
+	c := newCachedClient(t, v)
+
+	changes := make(chan ItemChanges, 2)
+	c.SetItemChangeHandler(func(ic ItemChanges) { changes <- ic })
+
+	if _, err := c.ListItems(ctx); err != nil {
+		t.Fatal(err)
+	}
+	first := <-changes
+	if !first.Reloaded || len(first.Added) != 2 {
+		t.Errorf("first load = %+v, want a reload of both items", first)
+	}
+
+	v.mu.Lock()
+	v.items[0].Name = "A2"
+	v.items[0].RevisionDate = v.items[0].RevisionDate.Add(time.Minute)
+	v.items = v.items[:1]
+	v.mu.Unlock()
+
+	if err := c.syncIfUnlocked(ctx); err != nil {
+		t.Fatalf("syncIfUnlocked() error = %v", err)
+	}
+	select {
+	case got := <-changes:
+		if got.Reloaded || len(got.Updated) != 1 || got.Updated[0].Name != "A2" || len(got.Removed) != 1 || got.Removed[0].ID != "b" {
+			t.Errorf("changes = %+v, want a updated and b removed", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("item change handler not called")
+	}
+
+	// Nothing changed, nothing to report
+	if err := c.syncIfUnlocked(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		t.Errorf("unexpected changes %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSyncNow_CallsSyncHandler(t *testing.T) {
+	v := &fakeVault{}
+	c := newCachedClient(t, v)
+
+	synced := make(chan struct{}, 1)
+	c.SetSyncHandler(func() { synced <- struct{}{} })
+
+	if err := c.SyncNow(context.Background()); err != nil {
+		t.Fatalf("SyncNow() error = %v", err)
+	}
+	select {
+	case <-synced:
+	case <-time.After(time.Second):
+		t.Fatal("sync handler not called")
+	}
+}
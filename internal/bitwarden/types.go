@@ -1,6 +1,7 @@
 package bitwarden
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 )
@@ -8,8 +9,20 @@ import (
 // Common errors
 var (
 	ErrVaultLocked = errors.New("vault is locked")
+	ErrConflict    = errors.New("item was changed in the vault")
 )
 
+// ConflictError is returned by UpdateItem when the item was changed in the
+// vault since it was read and the change overlaps the update. It wraps
+// ErrConflict and carries the item as it is now.
+type ConflictError struct {
+	Current *Item
+}
+
+func (e *ConflictError) Error() string { return ErrConflict.Error() }
+
+func (e *ConflictError) Unwrap() error { return ErrConflict }
+
 // ItemType represents the type of Bitwarden vault item
 type ItemType int
 
@@ -40,6 +53,19 @@ type Item struct {
 	RevisionDate   time.Time   `json:"revisionDate"`
 	CreationDate   time.Time   `json:"creationDate"`
 	DeletedDate    *time.Time  `json:"deletedDate,omitempty"`
+
+	raw json.RawMessage // the item as bw serve returned it, see ToUpdateRequest
+}
+
+// UnmarshalJSON decodes an item and keeps the original JSON, so updates can
+// carry over data the Item struct doesn't model
+func (i *Item) UnmarshalJSON(data []byte) error {
+	type plain Item
+	if err := json.Unmarshal(data, (*plain)(i)); err != nil {
+		return err
+	}
+	i.raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 // Login represents login credentials in a Bitwarden item
@@ -53,9 +79,19 @@ type Login struct {
 // URI represents a URI associated with a login item
 type URI struct {
 	URI   string `json:"uri"`
-	Match *int   `json:"match,omitempty"`
+	Match *int   `json:"match,omitempty"` // one of the URIMatch* values, nil for the default
 }
 
+// URI match detection types, the values of URI.Match
+const (
+	URIMatchDomain            = 0 // same registrable domain (the default)
+	URIMatchHost              = 1 // same host and port
+	URIMatchStartsWith        = 2 // URL starts with the URI
+	URIMatchExact             = 3 // URL equals the URI
+	URIMatchRegularExpression = 4 // URI is a case-insensitive regular expression
+	URIMatchNever             = 5 // never matches
+)
+
 // SecureNote represents a secure note item
 type SecureNote struct {
 	Type int `json:"type"`
@@ -73,13 +109,24 @@ type Card struct {
 
 // Identity represents an identity item
 type Identity struct {
-	Title      *string `json:"title"`
-	FirstName  *string `json:"firstName"`
-	MiddleName *string `json:"middleName"`
-	LastName   *string `json:"lastName"`
-	Email      *string `json:"email"`
-	Phone      *string `json:"phone"`
-	Company    *string `json:"company"`
+	Title          *string `json:"title"`
+	FirstName      *string `json:"firstName"`
+	MiddleName     *string `json:"middleName"`
+	LastName       *string `json:"lastName"`
+	Address1       *string `json:"address1"`
+	Address2       *string `json:"address2"`
+	Address3       *string `json:"address3"`
+	City           *string `json:"city"`
+	State          *string `json:"state"`
+	PostalCode     *string `json:"postalCode"`
+	Country        *string `json:"country"`
+	Company        *string `json:"company"`
+	Email          *string `json:"email"`
+	Phone          *string `json:"phone"`
+	SSN            *string `json:"ssn"`
+	Username       *string `json:"username"`
+	PassportNumber *string `json:"passportNumber"`
+	LicenseNumber  *string `json:"licenseNumber"`
 }
 
 // SSHKey represents an SSH key item
@@ -102,6 +149,11 @@ type Folder struct {
 	Name string `json:"name"`
 }
 
+// FolderRequest represents a request to create or rename a folder
+type FolderRequest struct {
+	Name string `json:"name"`
+}
+
 // APIResponse wraps Bitwarden API responses
 type APIResponse[T any] struct {
 	Success bool    `json:"success"`
@@ -147,23 +199,68 @@ type StatusResponse struct {
 	} `json:"data"`
 }
 
+// LastSyncTime returns when the vault was last synced with the server, or the
+// zero time if it never was or bw serve reported a time it can't parse
+func (s *StatusResponse) LastSyncTime() time.Time {
+	lastSync := s.Data.Template.LastSync
+	if lastSync == nil || *lastSync == "" {
+		return time.Time{}
+	}
+	// Try RFC3339Nano first (Bitwarden format), then RFC3339
+	t, err := time.Parse(time.RFC3339Nano, *lastSync)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, *lastSync)
+		if err != nil {
+			return time.Time{}
+		}
+	}
+	return t
+}
+
 // CreateItemRequest represents a request to create a new item
 type CreateItemRequest struct {
-	OrganizationID *string  `json:"organizationId,omitempty"`
-	FolderID       *string  `json:"folderId,omitempty"`
-	Type           ItemType `json:"type"`
-	Name           string   `json:"name"`
-	Notes          *string  `json:"notes,omitempty"`
-	Favorite       bool     `json:"favorite"`
-	Login          *Login   `json:"login,omitempty"`
-	SSHKey         *SSHKey  `json:"sshKey,omitempty"`
-	Fields         []Field  `json:"fields,omitempty"`
-	Reprompt       int      `json:"reprompt"`
+	OrganizationID *string     `json:"organizationId,omitempty"`
+	FolderID       *string     `json:"folderId,omitempty"`
+	Type           ItemType    `json:"type"`
+	Name           string      `json:"name"`
+	Notes          *string     `json:"notes,omitempty"`
+	Favorite       bool        `json:"favorite"`
+	Login          *Login      `json:"login,omitempty"`
+	SecureNote     *SecureNote `json:"secureNote,omitempty"`
+	Card           *Card       `json:"card,omitempty"`
+	Identity       *Identity   `json:"identity,omitempty"`
+	SSHKey         *SSHKey     `json:"sshKey,omitempty"`
+	Fields         []Field     `json:"fields,omitempty"`
+	Reprompt       int         `json:"reprompt"`
+
+	base json.RawMessage // original item JSON the request was made from
+	onto json.RawMessage // newer item JSON to patch instead of base, see rebase
+}
+
+// MarshalJSON encodes the request. Requests built by ToUpdateRequest encode
+// as the original item JSON (or the newer one set by rebase) with only the
+// fields changed since it was read replaced, see patchItemJSON.
+func (r CreateItemRequest) MarshalJSON() ([]byte, error) {
+	type plain CreateItemRequest
+	if len(r.base) == 0 {
+		return json.Marshal(plain(r))
+	}
+	updated, err := json.Marshal(plain(r))
+	if err != nil {
+		return nil, err
+	}
+	onto := r.onto
+	if len(onto) == 0 {
+		onto = r.base
+	}
+	return patchItemJSON(r.base, onto, updated)
 }
 
 // ToUpdateRequest creates a CreateItemRequest from an existing Item,
 // preserving all fields for updates. This ensures that non-omitempty fields
-// like Favorite and Reprompt are not silently zeroed during updates.
+// like Favorite and Reprompt are not silently zeroed during updates, and that
+// data the Item struct doesn't model (password history, passkeys, collection
+// IDs, ...) survives the update.
 func (i *Item) ToUpdateRequest() CreateItemRequest {
 	return CreateItemRequest{
 		OrganizationID: i.OrganizationID,
@@ -173,8 +270,26 @@ func (i *Item) ToUpdateRequest() CreateItemRequest {
 		Notes:          i.Notes,
 		Favorite:       i.Favorite,
 		Login:          i.Login,
+		SecureNote:     i.SecureNote,
+		Card:           i.Card,
+		Identity:       i.Identity,
 		SSHKey:         i.SSHKey,
 		Fields:         i.Fields,
 		Reprompt:       i.Reprompt,
+		base:           i.raw,
+	}
+}
+
+// Clone returns a deep copy of the item
+func (i *Item) Clone() (*Item, error) {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	var c Item
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
 	}
+	c.raw = i.raw // never modified in place, so safe to share
+	return &c, nil
 }
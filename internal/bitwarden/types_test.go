@@ -150,3 +150,52 @@ func TestItem_ToUpdateRequest_PreservesSSHKey(t *testing.T) {
 		t.Errorf("Login should be nil for SSH key items: got %v", req.Login)
 	}
 }
+
+func TestItem_ToUpdateRequest_PreservesCardIdentityAndNote(t *testing.T) {
+	number := "4111111111111111"
+	email := "jane@example.com"
+
+	tests := []struct {
+		name  string
+		item  *Item
+		check func(t *testing.T, req CreateItemRequest)
+	}{
+		{
+			name: "secure note",
+			item: &Item{Type: ItemTypeSecureNote, SecureNote: &SecureNote{Type: 0}},
+			check: func(t *testing.T, req CreateItemRequest) {
+				if req.SecureNote == nil {
+					t.Error("SecureNote not preserved")
+				}
+			},
+		},
+		{
+			name: "card",
+			item: &Item{Type: ItemTypeCard, Card: &Card{Number: &number}},
+			check: func(t *testing.T, req CreateItemRequest) {
+				if req.Card == nil || req.Card.Number == nil || *req.Card.Number != number {
+					t.Errorf("Card not preserved: got %+v", req.Card)
+				}
+			},
+		},
+		{
+			name: "identity",
+			item: &Item{Type: ItemTypeIdentity, Identity: &Identity{Email: &email}},
+			check: func(t *testing.T, req CreateItemRequest) {
+				if req.Identity == nil || req.Identity.Email == nil || *req.Identity.Email != email {
+					t.Errorf("Identity not preserved: got %+v", req.Identity)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.item.ToUpdateRequest()
+			if req.Type != tt.item.Type {
+				t.Errorf("Type not preserved: got %v, want %v", req.Type, tt.item.Type)
+			}
+			tt.check(t, req)
+		})
+	}
+}
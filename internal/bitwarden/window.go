@@ -0,0 +1,71 @@
+package bitwarden
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Window systems a ParentWindow can belong to
+const (
+	WindowX11     = "x11"
+	WindowWayland = "wayland"
+)
+
+// ParentWindow identifies the window of the application that asked for a
+// prompt, so the dialog can be shown on top of it. The zero value means no
+// parent is known.
+type ParentWindow struct {
+	System string // WindowX11 or WindowWayland; empty if unknown
+	Handle string // X11 window ID in decimal, or an exported xdg-foreign handle
+}
+
+// ParseParentWindow parses the window ID a client passed to Prompt.Prompt.
+// It accepts the "x11:<xid>" and "wayland:<handle>" forms used by
+// xdg-desktop-portal, and a bare X11 window ID in decimal or hex as sent by
+// older clients. Anything else yields the zero ParentWindow.
+func ParseParentWindow(windowID string) ParentWindow {
+	system, handle, found := strings.Cut(windowID, ":")
+	if !found {
+		system, handle = WindowX11, windowID
+	}
+
+	switch system {
+	case WindowX11:
+		xid, err := strconv.ParseUint(handle, 0, 32)
+		if err != nil || xid == 0 {
+			return ParentWindow{}
+		}
+		return ParentWindow{System: WindowX11, Handle: strconv.FormatUint(xid, 10)}
+	case WindowWayland:
+		if handle == "" {
+			return ParentWindow{}
+		}
+		return ParentWindow{System: WindowWayland, Handle: handle}
+	}
+	return ParentWindow{}
+}
+
+// String returns the window in "<system>:<handle>" form, or "" if unknown
+func (w ParentWindow) String() string {
+	if w.System == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", w.System, w.Handle)
+}
+
+// parentWindowKey carries a ParentWindow in a context
+type parentWindowKey struct{}
+
+// WithParentWindow returns a context whose password prompts are shown on
+// top of the given window
+func WithParentWindow(ctx context.Context, w ParentWindow) context.Context {
+	return context.WithValue(ctx, parentWindowKey{}, w)
+}
+
+// parentWindowFrom returns the window set by WithParentWindow, if any
+func parentWindowFrom(ctx context.Context) ParentWindow {
+	w, _ := ctx.Value(parentWindowKey{}).(ParentWindow)
+	return w
+}
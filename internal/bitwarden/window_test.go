@@ -0,0 +1,99 @@
+package bitwarden
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseParentWindow(t *testing.T) {
+	tests := []struct {
+		windowID string
+		want     ParentWindow
+	}{
+		{"", ParentWindow{}},
+		{"x11:0x3a00007", ParentWindow{System: WindowX11, Handle: "60817415"}},
+		{"x11:60817415", ParentWindow{System: WindowX11, Handle: "60817415"}},
+		{"60817415", ParentWindow{System: WindowX11, Handle: "60817415"}},
+		{"0x3a00007", ParentWindow{System: WindowX11, Handle: "60817415"}},
+		{"wayland:3d3b9d6e-5c1f-4f0b", ParentWindow{System: WindowWayland, Handle: "3d3b9d6e-5c1f-4f0b"}},
+		{"x11:not-a-window", ParentWindow{}},
+		{"x11:0", ParentWindow{}},
+		{"wayland:", ParentWindow{}},
+		{"quartz:1", ParentWindow{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.windowID, func(t *testing.T) {
+			if got := ParseParentWindow(tt.windowID); got != tt.want {
+				t.Errorf("ParseParentWindow(%q) = %+v, want %+v", tt.windowID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptForPassword_AttachesToParent(t *testing.T) {
+	x11 := ParentWindow{System: WindowX11, Handle: "42"}
+	wayland := ParentWindow{System: WindowWayland, Handle: "abc"}
+
+	tests := []struct {
+		name     string
+		tool     string
+		parent   ParentWindow
+		wantArgs string // empty if no attach argument is expected
+	}{
+		{"zenity x11", "zenity", x11, "--attach=42"},
+		{"zenity wayland", "zenity", wayland, ""},
+		{"zenity none", "zenity", ParentWindow{}, ""},
+		{"kdialog x11", "kdialog", x11, "--attach 42"},
+		{"kdialog wayland", "kdialog", wayland, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := filepath.Join(t.TempDir(), "args")
+			fakePromptTool(t, tt.tool, `echo "$@" > `+argsFile+`; echo pw`)
+			sm := &SessionManager{}
+
+			password, _, err := sm.PromptForPassword("", tt.parent)
+			if err != nil {
+				t.Fatalf("PromptForPassword() error = %v", err)
+			}
+			if password != "pw" {
+				t.Errorf("PromptForPassword() = %q, want %q", password, "pw")
+			}
+
+			b, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := string(b)
+			if tt.wantArgs != "" && !strings.Contains(args, tt.wantArgs) {
+				t.Errorf("%s args %q do not contain %q", tt.tool, args, tt.wantArgs)
+			}
+			if tt.wantArgs == "" && strings.Contains(args, "--attach") {
+				t.Errorf("%s args %q attach to a window", tt.tool, args)
+			}
+		})
+	}
+}
+
+func TestVerifyReprompt_PassesParentWindow(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var unlockCalls atomic.Int32
+	prompter := &mockPrompter{password: "right"}
+	c := clientWithPrompter(newRepromptServer(t, &unlockCalls), prompter, true)
+
+	parent := ParentWindow{System: WindowX11, Handle: "42"}
+	ctx := WithParentWindow(context.Background(), parent)
+	if err := c.VerifyReprompt(ctx, &Item{Name: "bank", Reprompt: RepromptPassword}); err != nil {
+		t.Fatalf("VerifyReprompt() error = %v", err)
+	}
+	if prompter.lastParent != parent {
+		t.Errorf("prompter got parent %+v, want %+v", prompter.lastParent, parent)
+	}
+}
@@ -0,0 +1,306 @@
+package dbus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/fileutil"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// validAliasName matches alias names that can be used as a D-Bus object path element
+var validAliasName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+var (
+	errInvalidAlias     = errors.New("invalid alias name")
+	errNoSuchCollection = errors.New("no such collection")
+)
+
+// AliasStore persists alias → collection path mappings to a JSON file so that
+// aliases set by clients survive daemon restarts.
+type AliasStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAliasStore creates an alias store backed by the given file path.
+// An empty path disables persistence.
+func NewAliasStore(path string) *AliasStore {
+	return &AliasStore{path: path}
+}
+
+// DefaultAliasFilePath returns the default alias file location:
+// $XDG_CONFIG_HOME/bitwarden-keyring/aliases.json
+func DefaultAliasFilePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.Getenv("HOME")
+	}
+	return filepath.Join(configDir, "bitwarden-keyring", "aliases.json")
+}
+
+// Load reads the persisted aliases. A missing file yields an empty map.
+func (s *AliasStore) Load() (map[string]dbus.ObjectPath, error) {
+	aliases := make(map[string]dbus.ObjectPath)
+	if s == nil || s.path == "" {
+		return aliases, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return aliases, nil
+		}
+		return nil, fmt.Errorf("failed to read alias file: %w", err)
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse alias file: %w", err)
+	}
+
+	for name, path := range raw {
+		if !validAliasName.MatchString(name) || !dbus.ObjectPath(path).IsValid() {
+			logging.L.With("component", "dbus").Warn("ignoring invalid alias entry", "alias", name)
+			continue
+		}
+		aliases[name] = dbus.ObjectPath(path)
+	}
+	return aliases, nil
+}
+
+// Save atomically writes the given aliases to the alias file.
+func (s *AliasStore) Save(aliases map[string]dbus.ObjectPath) error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw := make(map[string]string, len(aliases))
+	for name, path := range aliases {
+		raw[name] = string(path)
+	}
+
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := fileutil.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save alias file: %w", err)
+	}
+	return nil
+}
+
+// builtinAliases are the aliases that always exist, with their initial targets
+func builtinAliases() map[string]dbus.ObjectPath {
+	return map[string]dbus.ObjectPath{
+		"default": DefaultCollectionPath,
+		"session": SessionCollectionPath,
+	}
+}
+
+// LoadAliases initializes the alias table from the built-in aliases and the
+// persisted alias file. Persisted entries override built-in targets.
+func (cm *CollectionManager) LoadAliases() error {
+	aliases := builtinAliases()
+
+	stored, err := cm.aliasStore.Load()
+	if err != nil {
+		cm.mu.Lock()
+		cm.aliases = aliases
+		cm.mu.Unlock()
+		return err
+	}
+	for name, path := range stored {
+		aliases[name] = path
+	}
+
+	cm.mu.Lock()
+	cm.aliases = aliases
+	cm.mu.Unlock()
+	return nil
+}
+
+// ReloadAliases re-reads the alias file, e.g. after it was edited by hand,
+// and exports and unexports alias paths to match
+func (cm *CollectionManager) ReloadAliases() error {
+	cm.mu.RLock()
+	previous := make([]string, 0, len(cm.aliases))
+	for name := range cm.aliases {
+		previous = append(previous, name)
+	}
+	cm.mu.RUnlock()
+
+	loadErr := cm.LoadAliases()
+
+	cm.mu.Lock()
+	for _, name := range previous {
+		if _, ok := cm.aliases[name]; !ok {
+			cm.unexportAlias(name)
+		}
+	}
+	cm.mu.Unlock()
+
+	if err := cm.ExportAliases(); err != nil {
+		return err
+	}
+	return loadErr
+}
+
+// ReadAlias returns the collection path an alias points to, or NoPrompt ("/")
+// if the alias is unknown or its collection is not currently exported.
+func (cm *CollectionManager) ReadAlias(name string) dbus.ObjectPath {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	target, ok := cm.aliases[name]
+	if !ok {
+		return NoPrompt
+	}
+	if _, exists := cm.collections[target]; !exists {
+		return NoPrompt
+	}
+	return target
+}
+
+// SetAlias points an alias at a collection, persists the change and exports
+// the collection at /org/freedesktop/secrets/aliases/<name>. Passing NoPrompt
+// ("/") as the collection removes the alias; built-in aliases are reset to
+// their initial target instead. CollectionChanged is emitted for the old and
+// new target collections when the alias is re-pointed.
+func (cm *CollectionManager) SetAlias(name string, collection dbus.ObjectPath) error {
+	if !validAliasName.MatchString(name) {
+		return errInvalidAlias
+	}
+
+	target := collection
+	if target == NoPrompt {
+		target = builtinAliases()[name] // empty for non-built-in aliases
+	}
+
+	cm.mu.Lock()
+	if cm.aliases == nil {
+		cm.aliases = builtinAliases()
+	}
+	previous := cm.aliases[name]
+
+	if target == "" {
+		delete(cm.aliases, name)
+		cm.unexportAlias(name)
+	} else {
+		coll, ok := cm.collections[target]
+		if !ok {
+			cm.mu.Unlock()
+			return errNoSuchCollection
+		}
+		if err := cm.exportAlias(name, coll); err != nil {
+			cm.mu.Unlock()
+			return fmt.Errorf("failed to export alias %s: %w", name, err)
+		}
+		cm.aliases[name] = target
+	}
+	cm.mu.Unlock()
+
+	cm.saveAliases()
+
+	if previous != target {
+		if previous != "" {
+			EmitCollectionChanged(cm.conn, previous)
+		}
+		if target != "" {
+			EmitCollectionChanged(cm.conn, target)
+		}
+	}
+	return nil
+}
+
+// ExportAliases exports every alias whose target collection is currently
+// exported. It is safe to call repeatedly, e.g. after new collections appear.
+func (cm *CollectionManager) ExportAliases() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for name, target := range cm.aliases {
+		coll, ok := cm.collections[target]
+		if !ok {
+			continue
+		}
+		if err := cm.exportAlias(name, coll); err != nil {
+			return fmt.Errorf("failed to export alias %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// dropAliasesFor removes non-built-in aliases pointing at a removed collection
+// and resets built-in ones to their initial target. Must be called with cm.mu held.
+func (cm *CollectionManager) dropAliasesFor(path dbus.ObjectPath) bool {
+	changed := false
+	builtins := builtinAliases()
+	for name, target := range cm.aliases {
+		if target != path {
+			continue
+		}
+		changed = true
+		if builtin, ok := builtins[name]; ok {
+			cm.aliases[name] = builtin
+			if coll, ok := cm.collections[builtin]; ok {
+				if err := cm.exportAlias(name, coll); err != nil {
+					logging.L.With("component", "dbus").Warn("failed to re-export alias", "alias", name, "error", err)
+				}
+			}
+			continue
+		}
+		delete(cm.aliases, name)
+		cm.unexportAlias(name)
+	}
+	return changed
+}
+
+// saveAliases persists the current alias table, logging failures
+func (cm *CollectionManager) saveAliases() {
+	cm.mu.RLock()
+	snapshot := make(map[string]dbus.ObjectPath, len(cm.aliases))
+	for k, v := range cm.aliases {
+		snapshot[k] = v
+	}
+	cm.mu.RUnlock()
+
+	if err := cm.aliasStore.Save(snapshot); err != nil {
+		logging.L.With("component", "dbus").Warn("failed to persist aliases", "error", err)
+	}
+}
+
+// exportAlias exports a collection at its alias path. Must be called with cm.mu held.
+func (cm *CollectionManager) exportAlias(name string, coll *Collection) error {
+	if cm.conn == nil {
+		return nil
+	}
+	return exportDBusObject(cm.conn, coll, aliasPath(name), CollectionInterface, CollectionIntrospectXML, true)
+}
+
+// unexportAlias removes the alias path export. Must be called with cm.mu held.
+func (cm *CollectionManager) unexportAlias(name string) {
+	if cm.conn == nil {
+		return
+	}
+	unexportDBusObject(cm.conn, aliasPath(name), CollectionInterface, true)
+}
+
+// aliasPath returns the object path under which an alias is exported
+func aliasPath(name string) dbus.ObjectPath {
+	return dbus.ObjectPath(AliasPath + name)
+}
@@ -0,0 +1,197 @@
+package dbus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func newAliasTestManager(t *testing.T) (*CollectionManager, dbus.ObjectPath) {
+	t.Helper()
+	cm := &CollectionManager{
+		collections: make(map[dbus.ObjectPath]*Collection),
+		aliases:     builtinAliases(),
+		aliasStore:  NewAliasStore(filepath.Join(t.TempDir(), "aliases.json")),
+	}
+	cm.collections[DefaultCollectionPath] = cm.newCollection(DefaultCollectionPath, "default", "Default", "")
+	workPath := CollectionPathFromFolderID("folder1")
+	cm.collections[workPath] = cm.newCollection(workPath, "folder1", "Work", "folder1")
+	return cm, workPath
+}
+
+func TestAliasStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "aliases.json")
+	store := NewAliasStore(path)
+
+	want := map[string]dbus.ObjectPath{
+		"default": DefaultCollectionPath,
+		"work":    CollectionPathFromFolderID("folder1"),
+	}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Load() = %v, want %v", got, want)
+	}
+	for name, p := range want {
+		if got[name] != p {
+			t.Errorf("Load()[%q] = %s, want %s", name, got[name], p)
+		}
+	}
+}
+
+func TestAliasStore_LoadMissingAndInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	got, err := NewAliasStore(filepath.Join(dir, "missing.json")).Load()
+	if err != nil || len(got) != 0 {
+		t.Errorf("Load() on missing file = %v, %v; want empty, nil", got, err)
+	}
+
+	path := filepath.Join(dir, "aliases.json")
+	if err := os.WriteFile(path, []byte(`{"ok":"/org/freedesktop/secrets/collections/default","bad-name":"/x","bad_path":"not a path"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = NewAliasStore(path).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != 1 || got["ok"] != DefaultCollectionPath {
+		t.Errorf("Load() = %v, want only the valid entry", got)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAliasStore(path).Load(); err == nil {
+		t.Error("Load() on corrupt file should return an error")
+	}
+}
+
+func TestCollectionManager_SetAlias(t *testing.T) {
+	cm, workPath := newAliasTestManager(t)
+
+	if got := cm.ReadAlias("default"); got != DefaultCollectionPath {
+		t.Errorf("ReadAlias(default) = %s, want %s", got, DefaultCollectionPath)
+	}
+	if got := cm.ReadAlias("work"); got != NoPrompt {
+		t.Errorf("ReadAlias(work) before set = %s, want /", got)
+	}
+
+	if err := cm.SetAlias("work", workPath); err != nil {
+		t.Fatalf("SetAlias() error = %v", err)
+	}
+	if got := cm.ReadAlias("work"); got != workPath {
+		t.Errorf("ReadAlias(work) = %s, want %s", got, workPath)
+	}
+
+	if err := cm.SetAlias("default", workPath); err != nil {
+		t.Fatalf("SetAlias(default) error = %v", err)
+	}
+	if got := cm.ReadAlias("default"); got != workPath {
+		t.Errorf("ReadAlias(default) after re-point = %s, want %s", got, workPath)
+	}
+
+	// Aliases survive a reload from disk
+	reloaded := &CollectionManager{collections: cm.collections, aliasStore: cm.aliasStore}
+	if err := reloaded.LoadAliases(); err != nil {
+		t.Fatalf("LoadAliases() error = %v", err)
+	}
+	if got := reloaded.ReadAlias("work"); got != workPath {
+		t.Errorf("reloaded ReadAlias(work) = %s, want %s", got, workPath)
+	}
+	if got := reloaded.ReadAlias("default"); got != workPath {
+		t.Errorf("reloaded ReadAlias(default) = %s, want %s", got, workPath)
+	}
+
+	// "/" removes custom aliases and resets built-in ones
+	if err := cm.SetAlias("work", NoPrompt); err != nil {
+		t.Fatalf("SetAlias(work, /) error = %v", err)
+	}
+	if got := cm.ReadAlias("work"); got != NoPrompt {
+		t.Errorf("ReadAlias(work) after removal = %s, want /", got)
+	}
+	if err := cm.SetAlias("default", NoPrompt); err != nil {
+		t.Fatalf("SetAlias(default, /) error = %v", err)
+	}
+	if got := cm.ReadAlias("default"); got != DefaultCollectionPath {
+		t.Errorf("ReadAlias(default) after reset = %s, want %s", got, DefaultCollectionPath)
+	}
+}
+
+func TestCollectionManager_SetAlias_Errors(t *testing.T) {
+	cm, workPath := newAliasTestManager(t)
+
+	if err := cm.SetAlias("has-dash", workPath); !errors.Is(err, errInvalidAlias) {
+		t.Errorf("SetAlias(invalid name) error = %v, want errInvalidAlias", err)
+	}
+	if err := cm.SetAlias("", workPath); !errors.Is(err, errInvalidAlias) {
+		t.Errorf("SetAlias(empty name) error = %v, want errInvalidAlias", err)
+	}
+	if err := cm.SetAlias("work", CollectionPathFromFolderID("missing")); !errors.Is(err, errNoSuchCollection) {
+		t.Errorf("SetAlias(unknown collection) error = %v, want errNoSuchCollection", err)
+	}
+}
+
+func TestCollectionManager_DropAliasesFor(t *testing.T) {
+	cm, workPath := newAliasTestManager(t)
+
+	for _, name := range []string{"work", "default"} {
+		if err := cm.SetAlias(name, workPath); err != nil {
+			t.Fatalf("SetAlias(%s) error = %v", name, err)
+		}
+	}
+
+	// Mirror RemoveCollection without touching the bus
+	cm.mu.Lock()
+	delete(cm.collections, workPath)
+	if !cm.dropAliasesFor(workPath) {
+		t.Error("dropAliasesFor() = false, want true")
+	}
+	cm.mu.Unlock()
+	cm.saveAliases()
+
+	if got := cm.ReadAlias("work"); got != NoPrompt {
+		t.Errorf("ReadAlias(work) after collection removal = %s, want /", got)
+	}
+	if got := cm.ReadAlias("default"); got != DefaultCollectionPath {
+		t.Errorf("ReadAlias(default) after collection removal = %s, want %s", got, DefaultCollectionPath)
+	}
+
+	stored, err := cm.aliasStore.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored["work"]; ok {
+		t.Error("removed alias should not be persisted")
+	}
+}
+
+func TestCollectionManager_ReloadAliases(t *testing.T) {
+	cm, workPath := newAliasTestManager(t)
+	if err := cm.SetAlias("work", workPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is edited by hand: work is dropped, default re-pointed
+	if err := cm.aliasStore.Save(map[string]dbus.ObjectPath{"default": workPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.ReloadAliases(); err != nil {
+		t.Fatalf("ReloadAliases() error = %v", err)
+	}
+	if got := cm.ReadAlias("work"); got != NoPrompt {
+		t.Errorf("ReadAlias(work) = %s, want /", got)
+	}
+	if got := cm.ReadAlias("default"); got != workPath {
+		t.Errorf("ReadAlias(default) = %s, want %s", got, workPath)
+	}
+}
@@ -3,23 +3,30 @@ package dbus
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
-	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
 	"github.com/joe/bitwarden-keyring/internal/mapping"
 )
 
-// Collection represents a secret collection exposed via D-Bus
+// Collection represents a secret collection exposed via D-Bus.
+// The default collection holds items without a Bitwarden folder; every other
+// vault collection is backed by exactly one Bitwarden folder. The session
+// collection keeps its items in memory only.
 type Collection struct {
 	conn           *dbus.Conn
 	path           dbus.ObjectPath
 	name           string
 	label          string
+	folderID       string       // empty for the default and session collections
+	memory         *memoryStore // non-nil for the in-memory session collection
 	bwClient       *bitwarden.Client
 	itemManager    *ItemManager
 	sessionManager *SessionManager
+	manager        *CollectionManager
 	mu             sync.RWMutex
 }
 
@@ -30,7 +37,8 @@ type CollectionManager struct {
 	itemManager    *ItemManager
 	sessionManager *SessionManager
 	collections    map[dbus.ObjectPath]*Collection
-	defaultAlias   dbus.ObjectPath
+	aliases        map[string]dbus.ObjectPath
+	aliasStore     *AliasStore
 	mu             sync.RWMutex
 }
 
@@ -42,7 +50,22 @@ func NewCollectionManager(conn *dbus.Conn, bwClient *bitwarden.Client, itemManag
 		itemManager:    itemManager,
 		sessionManager: sessionManager,
 		collections:    make(map[dbus.ObjectPath]*Collection),
-		defaultAlias:   DefaultCollectionPath,
+		aliases:        builtinAliases(),
+	}
+}
+
+// newCollection builds an unexported collection object owned by this manager
+func (cm *CollectionManager) newCollection(path dbus.ObjectPath, name, label, folderID string) *Collection {
+	return &Collection{
+		conn:           cm.conn,
+		path:           path,
+		name:           name,
+		label:          label,
+		folderID:       folderID,
+		bwClient:       cm.bwClient,
+		itemManager:    cm.itemManager,
+		sessionManager: cm.sessionManager,
+		manager:        cm,
 	}
 }
 
@@ -57,16 +80,31 @@ func (cm *CollectionManager) EnsureDefaultCollection() (*Collection, error) {
 		return coll, nil
 	}
 
-	coll := &Collection{
-		conn:           cm.conn,
-		path:           path,
-		name:           "default",
-		label:          "Default",
-		bwClient:       cm.bwClient,
-		itemManager:    cm.itemManager,
-		sessionManager: cm.sessionManager,
+	coll := cm.newCollection(path, "default", "Default", "")
+
+	if err := cm.exportCollection(coll); err != nil {
+		return nil, err
 	}
 
+	cm.collections[path] = coll
+	return coll, nil
+}
+
+// EnsureSessionCollection ensures the in-memory session collection exists.
+// Its items never reach the Bitwarden vault and vanish when the daemon exits.
+func (cm *CollectionManager) EnsureSessionCollection() (*Collection, error) {
+	path := SessionCollectionPath
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if coll, ok := cm.collections[path]; ok {
+		return coll, nil
+	}
+
+	coll := cm.newCollection(path, "session", "Session", "")
+	coll.memory = newMemoryStore()
+
 	if err := cm.exportCollection(coll); err != nil {
 		return nil, err
 	}
@@ -75,6 +113,184 @@ func (cm *CollectionManager) EnsureDefaultCollection() (*Collection, error) {
 	return coll, nil
 }
 
+// RefreshFolders synchronizes the exported folder collections with the
+// folders currently in the vault. New folders are exported, renamed folders
+// update their label and folders that no longer exist are unexported.
+// The matching CollectionCreated/Changed/Deleted signals are emitted.
+func (cm *CollectionManager) RefreshFolders(ctx context.Context) error {
+	folders, err := cm.bwClient.ListFolders(ctx)
+	if err != nil {
+		return err
+	}
+
+	var created, changed, deleted []dbus.ObjectPath
+
+	cm.mu.Lock()
+	seen := make(map[dbus.ObjectPath]bool, len(folders))
+	for _, folder := range folders {
+		// bw serve reports the implicit "No Folder" entry with an empty ID
+		if folder.ID == "" {
+			continue
+		}
+		path := CollectionPathFromFolderID(folder.ID)
+		seen[path] = true
+
+		if coll, ok := cm.collections[path]; ok {
+			coll.mu.Lock()
+			if coll.label != folder.Name {
+				coll.label = folder.Name
+				changed = append(changed, path)
+			}
+			coll.mu.Unlock()
+			continue
+		}
+
+		coll := cm.newCollection(path, SanitizeID(folder.ID), folder.Name, folder.ID)
+		if err := cm.exportCollection(coll); err != nil {
+			logging.L.With("component", "dbus").Warn("failed to export folder collection", "folder", folder.ID, "error", err)
+			continue
+		}
+		cm.collections[path] = coll
+		created = append(created, path)
+	}
+
+	aliasesChanged := false
+	for path, coll := range cm.collections {
+		if coll.folderID == "" || seen[path] {
+			continue
+		}
+		delete(cm.collections, path)
+		unexportDBusObject(cm.conn, path, CollectionInterface, true)
+		if cm.dropAliasesFor(path) {
+			aliasesChanged = true
+		}
+		deleted = append(deleted, path)
+	}
+	cm.mu.Unlock()
+
+	if aliasesChanged {
+		cm.saveAliases()
+	}
+	// Aliases persisted from an earlier run may point at folders that were
+	// only just exported
+	if len(created) > 0 {
+		if err := cm.ExportAliases(); err != nil {
+			logging.L.With("component", "dbus").Warn("failed to export aliases", "error", err)
+		}
+	}
+
+	for _, path := range deleted {
+		cm.itemManager.RemoveCollectionItems(path)
+		EmitCollectionDeleted(cm.conn, path)
+	}
+	for _, path := range created {
+		EmitCollectionCreated(cm.conn, path)
+	}
+	for _, path := range changed {
+		EmitCollectionChanged(cm.conn, path)
+	}
+
+	return nil
+}
+
+// RefreshFoldersIfUnlocked refreshes the folder collections only when the
+// vault is already unlocked, so that reading collection state never triggers
+// a password prompt. Errors are logged rather than returned.
+func (cm *CollectionManager) RefreshFoldersIfUnlocked(ctx context.Context) {
+	locked, err := cm.bwClient.IsLocked(ctx)
+	if err != nil || locked {
+		return
+	}
+	if err := cm.RefreshFolders(ctx); err != nil {
+		logging.L.With("component", "dbus").Warn("failed to refresh folder collections", "error", err)
+	}
+}
+
+// CreateFolderCollection creates a new Bitwarden folder with the given name
+// and exports it as a collection. If a folder collection with the same label
+// already exists it is returned instead.
+func (cm *CollectionManager) CreateFolderCollection(ctx context.Context, name string) (*Collection, error) {
+	if coll, ok := cm.findByLabel(name); ok {
+		return coll, nil
+	}
+
+	folder, err := cm.bwClient.CreateFolder(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	path := CollectionPathFromFolderID(folder.ID)
+	coll := cm.newCollection(path, SanitizeID(folder.ID), folder.Name, folder.ID)
+
+	cm.mu.Lock()
+	if existing, ok := cm.collections[path]; ok {
+		cm.mu.Unlock()
+		return existing, nil
+	}
+	if err := cm.exportCollection(coll); err != nil {
+		cm.mu.Unlock()
+		return nil, err
+	}
+	cm.collections[path] = coll
+	cm.mu.Unlock()
+
+	EmitCollectionCreated(cm.conn, path)
+	return coll, nil
+}
+
+// RemoveCollection unexports a folder collection and all items exported under
+// it. Aliases pointing at the collection are dropped.
+func (cm *CollectionManager) RemoveCollection(path dbus.ObjectPath) {
+	cm.mu.Lock()
+	_, ok := cm.collections[path]
+	aliasesChanged := false
+	if ok {
+		delete(cm.collections, path)
+		unexportDBusObject(cm.conn, path, CollectionInterface, true)
+		aliasesChanged = cm.dropAliasesFor(path)
+	}
+	cm.mu.Unlock()
+
+	if aliasesChanged {
+		cm.saveAliases()
+	}
+	if ok {
+		cm.itemManager.RemoveCollectionItems(path)
+	}
+}
+
+// findByLabel returns the folder collection with the given label, if any
+func (cm *CollectionManager) findByLabel(label string) (*Collection, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	for _, coll := range cm.collections {
+		if coll.folderID == "" {
+			continue
+		}
+		coll.mu.RLock()
+		match := coll.label == label
+		coll.mu.RUnlock()
+		if match {
+			return coll, true
+		}
+	}
+	return nil, false
+}
+
+// CollectionForItem returns the collection a Bitwarden item belongs to.
+// Items without a folder, or in a folder that has not been exported yet,
+// belong to the default collection.
+func (cm *CollectionManager) CollectionForItem(item *bitwarden.Item) *Collection {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if item.FolderID != nil && *item.FolderID != "" {
+		if coll, ok := cm.collections[CollectionPathFromFolderID(*item.FolderID)]; ok {
+			return coll
+		}
+	}
+	return cm.collections[DefaultCollectionPath]
+}
+
 // GetCollection retrieves a collection by path
 func (cm *CollectionManager) GetCollection(path dbus.ObjectPath) (*Collection, bool) {
 	cm.mu.RLock()
@@ -83,7 +299,7 @@ func (cm *CollectionManager) GetCollection(path dbus.ObjectPath) (*Collection, b
 	return coll, ok
 }
 
-// GetCollectionPaths returns all collection paths
+// GetCollectionPaths returns all collection paths, sorted for stable output
 func (cm *CollectionManager) GetCollectionPaths() []dbus.ObjectPath {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -92,16 +308,10 @@ func (cm *CollectionManager) GetCollectionPaths() []dbus.ObjectPath {
 	for path := range cm.collections {
 		paths = append(paths, path)
 	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
 	return paths
 }
 
-// GetDefaultAlias returns the default collection path
-func (cm *CollectionManager) GetDefaultAlias() dbus.ObjectPath {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-	return cm.defaultAlias
-}
-
 // exportCollection exports a collection to D-Bus
 func (cm *CollectionManager) exportCollection(coll *Collection) error {
 	return exportDBusObject(cm.conn, coll, coll.path, CollectionInterface, CollectionIntrospectXML, true)
@@ -112,30 +322,65 @@ func (c *Collection) Path() dbus.ObjectPath {
 	return c.path
 }
 
+// FolderID returns the Bitwarden folder ID backing this collection,
+// or an empty string for the default collection.
+func (c *Collection) FolderID() string {
+	return c.folderID
+}
+
+// folderName returns the Bitwarden folder name of the collection's items,
+// used as their bitwarden:folder attribute. It is empty for a nil collection
+// and for collections not backed by a folder.
+func (c *Collection) folderName() string {
+	if c == nil || c.folderID == "" {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.label
+}
+
+// store returns the backend holding this collection's items
+func (c *Collection) store() itemStore {
+	if c.memory != nil {
+		return c.memory
+	}
+	return c.bwClient
+}
+
+// contains reports whether an item from the collection's store belongs to
+// this collection
+func (c *Collection) contains(item *bitwarden.Item) bool {
+	// The in-memory store only ever holds this collection's items
+	if c.manager == nil || c.memory != nil {
+		return true
+	}
+	return c.manager.CollectionForItem(item) == c
+}
+
+// scope is a collectionResolver restricting results to this collection
+func (c *Collection) scope(item *bitwarden.Item) *Collection {
+	if c.contains(item) {
+		return c
+	}
+	return nil
+}
+
 // lastSyncUnix returns the last sync time from Bitwarden status as a Unix timestamp.
 // Returns 0 if the status call fails or lastSync is empty.
 // Note: Status() uses doRequest which already checks HTTP status codes >= 400, so
 // we don't need to check status.Success here - an API error would have been returned
 // as an HTTP error.
 func (c *Collection) lastSyncUnix(ctx context.Context) int64 {
+	if c.memory != nil {
+		return c.memory.modifiedUnix()
+	}
 	status, err := c.bwClient.Status(ctx)
 	if err != nil {
 		return 0
 	}
-	lastSync := status.Data.Template.LastSync
-	if lastSync == nil || *lastSync == "" {
-		return 0
-	}
-	// Try RFC3339Nano first (Bitwarden format), then RFC3339
-	t, err := time.Parse(time.RFC3339Nano, *lastSync)
-	if err != nil {
-		t, err = time.Parse(time.RFC3339, *lastSync)
-		if err != nil {
-			return 0
-		}
-	}
-	u := t.Unix()
-	if u < 0 {
+	u := status.LastSyncTime().Unix()
+	if u < 0 { // the zero time: never synced
 		return 0
 	}
 	return u
@@ -147,7 +392,7 @@ func hasMeaningfulAttrs(attrs map[string]string) bool {
 	identityKeys := []string{
 		"label", "service", "domain", "server", "username", "user",
 		mapping.AttrService, mapping.AttrUsername, mapping.AttrDomain,
-		mapping.AttrServer,
+		mapping.AttrServer, mapping.AttrID, mapping.AttrName,
 	}
 	for _, key := range identityKeys {
 		if v, ok := attrs[key]; ok && v != "" {
@@ -157,17 +402,37 @@ func hasMeaningfulAttrs(attrs map[string]string) bool {
 	return false
 }
 
-// Delete deletes the collection (D-Bus method)
+// Delete deletes the collection (D-Bus method).
+// Deleting a folder collection deletes the Bitwarden folder; its items are
+// kept and move to the default collection.
 func (c *Collection) Delete() (dbus.ObjectPath, *dbus.Error) {
+	if c.memory != nil {
+		return NoPrompt, toDBusError(fmt.Errorf("cannot delete session collection"))
+	}
+
 	// We don't support deleting the default collection
-	return NoPrompt, toDBusError(fmt.Errorf("cannot delete default collection"))
+	if c.folderID == "" {
+		return NoPrompt, toDBusError(fmt.Errorf("cannot delete default collection"))
+	}
+
+	ctx := context.Background()
+	if err := c.bwClient.DeleteFolder(ctx, c.folderID); err != nil {
+		return NoPrompt, toDBusError(err)
+	}
+
+	if c.manager != nil {
+		c.manager.RemoveCollection(c.path)
+	}
+	EmitCollectionDeleted(c.conn, c.path)
+
+	return NoPrompt, nil
 }
 
 // SearchItems searches for items matching the given attributes (D-Bus method)
 func (c *Collection) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, *dbus.Error) {
 	ctx := context.Background()
 
-	results, err := searchAndFilterItems(ctx, c.bwClient, c.itemManager, c, attributes)
+	results, err := searchAndFilterItems(ctx, c.store(), c.itemManager, c.scope, attributes)
 	if err != nil {
 		return nil, toDBusError(err)
 	}
@@ -206,39 +471,48 @@ func (c *Collection) CreateItem(properties map[string]dbus.Variant, secret Secre
 		}
 	}
 
+	// The bitwarden:type attribute selects the item type; logins are the default
+	itemType := mapping.ItemTypeFromAttributes(attrs)
+
 	// Build URI from attributes
 	uri := mapping.BuildURIFromAttributes(attrs)
-	username := mapping.GetUsername(attrs)
 
 	// If replace is true, try to find and update existing item
 	// Only replace if attrs contains meaningful identity attributes
 	if replace && attrs != nil && hasMeaningfulAttrs(attrs) {
 		var items []bitwarden.Item
 		var err error
-		if uri != "" {
-			items, err = c.bwClient.SearchItems(ctx, uri)
+		if uri != "" && itemType == bitwarden.ItemTypeLogin {
+			items, err = c.store().SearchItems(ctx, uri)
 		} else {
-			items, err = c.bwClient.ListItems(ctx)
+			items, err = c.store().ListItems(ctx)
 		}
 		if err != nil {
 			// If we can't fetch candidate items, we can't safely replace - fail the operation
 			return NoPrompt, NoPrompt, toDBusError(
 				fmt.Errorf("cannot check for existing items: %w", err))
 		}
-		for _, item := range items {
-			if mapping.MatchesAttributes(&item, attrs) {
-				// Update existing item with all attributes first
-				mapping.UpdateItemFromAttributes(&item, attrs)
+		// Only replace items of the requested type
+		matchAttrs := make(map[string]string, len(attrs)+1)
+		for k, v := range attrs {
+			matchAttrs[k] = v
+		}
+		matchAttrs[mapping.AttrType] = mapping.ItemTypeName(&bitwarden.Item{Type: itemType})
 
-				// Then set password and label
-				password := string(decryptedValue)
-				item.Login.Password = &password
+		for _, item := range items {
+			if c.contains(&item) && mapping.PortalAppID(&item) == "" && mapping.MatchesAttributes(&item, matchAttrs) {
+				// Set the secret first so that attributes such as an
+				// identity's email win over the secret payload
+				if err := mapping.SetItemSecret(&item, decryptedValue); err != nil {
+					return NoPrompt, NoPrompt, toDBusError(err)
+				}
+				mapping.StoreAttributes(&item, attrs)
 				item.Name = label
 
 				// Use ToUpdateRequest to preserve all fields
 				req := item.ToUpdateRequest()
 
-				updated, err := c.bwClient.UpdateItem(ctx, item.ID, req)
+				updated, err := c.store().UpdateItem(ctx, item.ID, req)
 				if err != nil {
 					return NoPrompt, NoPrompt, toDBusError(err)
 				}
@@ -256,27 +530,25 @@ func (c *Collection) CreateItem(properties map[string]dbus.Variant, secret Secre
 		}
 	}
 
-	// Create new item
-	password := string(decryptedValue)
-	login := &bitwarden.Login{
-		Password: &password,
+	// Create new item. Notes, cards and identities take their payload from
+	// the secret; every item records the full attribute set.
+	item := &bitwarden.Item{Type: itemType, Name: label}
+	if itemType == bitwarden.ItemTypeLogin {
+		item.Login = &bitwarden.Login{}
 	}
-
-	if username != "" {
-		login.Username = &username
-	}
-
-	if uri != "" {
-		login.URIs = []bitwarden.URI{{URI: uri}}
+	if err := mapping.SetItemSecret(item, decryptedValue); err != nil {
+		return NoPrompt, NoPrompt, toDBusError(err)
 	}
+	mapping.StoreAttributes(item, attrs)
+	req := item.ToUpdateRequest()
 
-	req := bitwarden.CreateItemRequest{
-		Type:  bitwarden.ItemTypeLogin,
-		Name:  label,
-		Login: login,
+	// Place the item in this collection's folder
+	if c.folderID != "" {
+		folderID := c.folderID
+		req.FolderID = &folderID
 	}
 
-	created, err := c.bwClient.CreateItem(ctx, req)
+	created, err := c.store().CreateItem(ctx, req)
 	if err != nil {
 		return NoPrompt, NoPrompt, toDBusError(err)
 	}
@@ -311,7 +583,7 @@ func (c *Collection) Get(iface, property string) (dbus.Variant, *dbus.Error) {
 	case "Items":
 		// Return all item paths, ensuring each item is exported
 		ctx := context.Background()
-		paths, err := getLoginItemPaths(ctx, c.bwClient, c.itemManager, c)
+		paths, err := getItemPaths(ctx, c.store(), c.itemManager, c.scope)
 		if err != nil {
 			return dbus.Variant{}, toDBusError(err)
 		}
@@ -322,7 +594,7 @@ func (c *Collection) Get(iface, property string) (dbus.Variant, *dbus.Error) {
 
 	case "Locked":
 		ctx := context.Background()
-		return dbus.MakeVariant(c.bwClient.IsLockedSafe(ctx)), nil
+		return dbus.MakeVariant(c.store().IsLockedSafe(ctx)), nil
 
 	default:
 		return dbus.Variant{}, toDBusError(fmt.Errorf("unknown property: %s", property))
@@ -344,7 +616,16 @@ func (c *Collection) Set(iface, property string, value dbus.Variant) *dbus.Error
 		if !ok {
 			return toDBusError(fmt.Errorf("invalid label type"))
 		}
+
+		// Folder collections are renamed in Bitwarden; the default
+		// collection's label only lives in this process
+		if c.folderID != "" && label != c.label {
+			if _, err := c.bwClient.UpdateFolder(context.Background(), c.folderID, label); err != nil {
+				return toDBusError(err)
+			}
+		}
 		c.label = label
+		EmitCollectionChanged(c.conn, c.path)
 		return nil
 
 	default:
@@ -366,10 +647,10 @@ func (c *Collection) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error)
 
 	ctx := context.Background()
 
-	locked := c.bwClient.IsLockedSafe(ctx)
+	locked := c.store().IsLockedSafe(ctx)
 
 	// Get item paths, using empty list on error
-	paths, err := getLoginItemPaths(ctx, c.bwClient, c.itemManager, c)
+	paths, err := getItemPaths(ctx, c.store(), c.itemManager, c.scope)
 	if err != nil {
 		paths = nil
 	}
@@ -1,8 +1,13 @@
 package dbus
 
 import (
+	"context"
+	"slices"
 	"testing"
 
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
 	"github.com/joe/bitwarden-keyring/internal/mapping"
 )
 
@@ -142,6 +147,8 @@ func TestHasMeaningfulAttrs_AllIdentityKeys(t *testing.T) {
 		mapping.AttrUsername,
 		mapping.AttrDomain,
 		mapping.AttrServer,
+		mapping.AttrID,
+		mapping.AttrName,
 	}
 
 	for _, key := range identityKeys {
@@ -153,3 +160,179 @@ func TestHasMeaningfulAttrs_AllIdentityKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionManager_CollectionForItem(t *testing.T) {
+	cm := &CollectionManager{collections: make(map[dbus.ObjectPath]*Collection)}
+	defaultColl := cm.newCollection(DefaultCollectionPath, "default", "Default", "")
+	workPath := CollectionPathFromFolderID("11111111-2222-3333-4444-555555555555")
+	workColl := cm.newCollection(workPath, "work", "Work", "11111111-2222-3333-4444-555555555555")
+	cm.collections[DefaultCollectionPath] = defaultColl
+	cm.collections[workPath] = workColl
+
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		folderID *string
+		want     *Collection
+	}{
+		{name: "no folder", folderID: nil, want: defaultColl},
+		{name: "empty folder", folderID: strPtr(""), want: defaultColl},
+		{name: "known folder", folderID: strPtr("11111111-2222-3333-4444-555555555555"), want: workColl},
+		{name: "unknown folder falls back to default", folderID: strPtr("99999999-2222-3333-4444-555555555555"), want: defaultColl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &bitwarden.Item{ID: "item", FolderID: tt.folderID}
+			if got := cm.CollectionForItem(item); got != tt.want {
+				t.Errorf("CollectionForItem() = %v, want %v", got.path, tt.want.path)
+			}
+			// scope must agree with CollectionForItem
+			if got := tt.want.scope(item); got != tt.want {
+				t.Errorf("scope() on owning collection = %v, want %v", got, tt.want)
+			}
+			for _, other := range []*Collection{defaultColl, workColl} {
+				if other != tt.want && other.scope(item) != nil {
+					t.Errorf("scope() on %s should exclude item", other.path)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchAndFilterItems_FolderAttribute(t *testing.T) {
+	im := NewItemManager(nil, nil, nil)
+	im.exportFunc = func(*Item) error { return nil }
+
+	cm := &CollectionManager{collections: make(map[dbus.ObjectPath]*Collection), itemManager: im}
+	defaultColl := cm.newCollection(DefaultCollectionPath, "default", "Default", "")
+	workPath := CollectionPathFromFolderID("11111111-2222-3333-4444-555555555555")
+	workColl := cm.newCollection(workPath, "work", "Work/Servers", "11111111-2222-3333-4444-555555555555")
+	cm.collections[DefaultCollectionPath] = defaultColl
+	cm.collections[workPath] = workColl
+
+	folderID := workColl.folderID
+	store := newMemoryStore()
+	store.items["home"] = &bitwarden.Item{ID: "home", Name: "Home", Type: bitwarden.ItemTypeSecureNote}
+	store.items["work"] = &bitwarden.Item{ID: "work", Name: "Work", Type: bitwarden.ItemTypeSecureNote, FolderID: &folderID}
+
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  []dbus.ObjectPath
+	}{
+		{"exact folder", map[string]string{mapping.AttrFolder: "Work/Servers"}, []dbus.ObjectPath{ItemPathInCollection(workPath, "work")}},
+		{"folder glob", map[string]string{mapping.AttrFolder: "Work/*"}, []dbus.ObjectPath{ItemPathInCollection(workPath, "work")}},
+		{"no folder", map[string]string{mapping.AttrFolder: ""}, []dbus.ObjectPath{ItemPathInCollection(DefaultCollectionPath, "home")}},
+		{"folder and id disagree", map[string]string{mapping.AttrFolder: "Work/Servers", mapping.AttrID: "home"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchAndFilterItems(context.Background(), store, im, cm.CollectionForItem, tt.attrs)
+			if err != nil {
+				t.Fatalf("searchAndFilterItems() error = %v", err)
+			}
+			if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+				t.Errorf("searchAndFilterItems(%v) = %v, want %v", tt.attrs, got, tt.want)
+			}
+		})
+	}
+
+	// Exported items report their collection's folder
+	item, ok := im.GetItem(ItemPathInCollection(workPath, "work"))
+	if !ok {
+		t.Fatal("work item not exported")
+	}
+	attrs, dbusErr := item.Get(ItemInterface, "Attributes")
+	if dbusErr != nil {
+		t.Fatalf("Get(Attributes) error = %v", dbusErr)
+	}
+	if got := attrs.Value().(map[string]string); got[mapping.AttrFolder] != "Work/Servers" || got[mapping.AttrID] != "work" {
+		t.Errorf("Attributes = %v, want bitwarden:folder=Work/Servers and bitwarden:id=work", got)
+	}
+}
+
+// loginIndexStore narrows SearchItems to logins, as the vault's URI index does
+type loginIndexStore struct{ *memoryStore }
+
+func (s loginIndexStore) SearchItems(ctx context.Context, searchURL string) ([]bitwarden.Item, error) {
+	items, err := s.ListItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var logins []bitwarden.Item
+	for _, item := range items {
+		if item.Type == bitwarden.ItemTypeLogin {
+			logins = append(logins, item)
+		}
+	}
+	return logins, nil
+}
+
+func TestSearchAndFilterItems_NoteByService(t *testing.T) {
+	im := NewItemManager(nil, nil, nil)
+	im.exportFunc = func(*Item) error { return nil }
+	coll := (&CollectionManager{itemManager: im}).newCollection(DefaultCollectionPath, "default", "Default", "")
+	resolve := func(*bitwarden.Item) *Collection { return coll }
+
+	note := &bitwarden.Item{ID: "note", Name: "API token", Type: bitwarden.ItemTypeSecureNote}
+	mapping.StoreAttributes(note, map[string]string{mapping.AttrType: mapping.TypeNote, mapping.AttrService: "myapi"})
+	mem := newMemoryStore()
+	mem.items["note"] = note
+	mem.items["login"] = &bitwarden.Item{ID: "login", Name: "Site", Type: bitwarden.ItemTypeLogin,
+		Login: &bitwarden.Login{URIs: []bitwarden.URI{{URI: "https://myapi"}}}}
+	store := loginIndexStore{mem}
+
+	got, err := searchAndFilterItems(context.Background(), store, im, resolve, map[string]string{mapping.AttrService: "myapi"})
+	if err != nil {
+		t.Fatalf("searchAndFilterItems() error = %v", err)
+	}
+	if !slices.Contains(got, ItemPathInCollection(DefaultCollectionPath, "note")) {
+		t.Errorf("searchAndFilterItems(service=myapi) = %v, want the note", got)
+	}
+}
+
+func TestCollectionManager_GetCollectionPaths_Sorted(t *testing.T) {
+	cm := &CollectionManager{collections: make(map[dbus.ObjectPath]*Collection)}
+	for _, p := range []dbus.ObjectPath{CollectionPath + "zz", DefaultCollectionPath, CollectionPath + "aa"} {
+		cm.collections[p] = cm.newCollection(p, "", "", "x")
+	}
+
+	got := cm.GetCollectionPaths()
+	want := []dbus.ObjectPath{CollectionPath + "aa", DefaultCollectionPath, CollectionPath + "zz"}
+	if len(got) != len(want) {
+		t.Fatalf("GetCollectionPaths() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetCollectionPaths()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestItemManager_RemoveCollectionItems(t *testing.T) {
+	im := &ItemManager{items: make(map[dbus.ObjectPath]*itemEntry)}
+	im.exportFunc = func(*Item) error { return nil }
+
+	workPath := CollectionPathFromFolderID("folder1")
+	work := &Collection{path: workPath}
+	def := &Collection{path: DefaultCollectionPath}
+
+	if _, err := im.GetOrCreateItem(&bitwarden.Item{ID: "a"}, work); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := im.GetOrCreateItem(&bitwarden.Item{ID: "b"}, def); err != nil {
+		t.Fatal(err)
+	}
+
+	im.RemoveCollectionItems(workPath)
+
+	if _, ok := im.GetItem(ItemPathInCollection(workPath, "a")); ok {
+		t.Error("item in removed collection should be gone")
+	}
+	if _, ok := im.GetItem(ItemPathFromID("b")); !ok {
+		t.Error("item in default collection should remain")
+	}
+}
@@ -2,6 +2,7 @@ package dbus
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
@@ -34,6 +35,17 @@ func TestToDBusError_UserCancelled(t *testing.T) {
 	}
 }
 
+func TestToDBusError_Conflict(t *testing.T) {
+	err := toDBusError(fmt.Errorf("failed to update item: %w", &bitwarden.ConflictError{Current: &bitwarden.Item{}}))
+
+	if err == nil {
+		t.Fatal("toDBusError(ConflictError) returned nil")
+	}
+	if err.Name != ErrConflict {
+		t.Errorf("error name = %q, want %q", err.Name, ErrConflict)
+	}
+}
+
 func TestToDBusError_DefaultError(t *testing.T) {
 	customErr := errors.New("some other error")
 	err := toDBusError(customErr)
@@ -0,0 +1,103 @@
+package dbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// gnomeKeyring implements gnome-keyring's private management interface,
+// which Seahorse and some libraries call on the service object to create and
+// unlock keyrings with a password. Every collection shares the Bitwarden
+// master password, so the password given is always checked against the vault.
+type gnomeKeyring struct {
+	svc *Service
+}
+
+// CreateWithMasterPassword creates a collection without prompting (D-Bus method).
+// The master password must be the vault's; it unlocks the vault if locked.
+func (g *gnomeKeyring) CreateWithMasterPassword(properties map[string]dbus.Variant, master Secret) (dbus.ObjectPath, *dbus.Error) {
+	if err := g.unlockWith(master); err != nil {
+		return NoPrompt, err
+	}
+
+	path, _, err := g.svc.CreateCollection(properties, "")
+	return path, err
+}
+
+// UnlockWithMasterPassword unlocks a collection without prompting (D-Bus method).
+// Unlocking any vault-backed collection unlocks the whole vault.
+func (g *gnomeKeyring) UnlockWithMasterPassword(collection dbus.ObjectPath, master Secret) *dbus.Error {
+	if strings.HasPrefix(string(collection), AliasPath) {
+		collection = g.svc.collectionManager.ReadAlias(strings.TrimPrefix(string(collection), AliasPath))
+	}
+	if _, ok := g.svc.collectionManager.GetCollection(collection); !ok {
+		return &dbus.Error{
+			Name: ErrNoSuchObject,
+			Body: []interface{}{"Collection not found"},
+		}
+	}
+
+	// The session collection is never locked
+	if collection == SessionCollectionPath {
+		return nil
+	}
+
+	return g.unlockWith(master)
+}
+
+// ChangeWithMasterPassword would change a collection's password (D-Bus method).
+// The master password can only be changed in Bitwarden itself.
+func (g *gnomeKeyring) ChangeWithMasterPassword(collection dbus.ObjectPath, original, master Secret) *dbus.Error {
+	return errPasswordChangeNotSupported()
+}
+
+// ChangeWithPrompt would prompt to change a collection's password (D-Bus method).
+// The master password can only be changed in Bitwarden itself.
+func (g *gnomeKeyring) ChangeWithPrompt(collection dbus.ObjectPath) (dbus.ObjectPath, *dbus.Error) {
+	return NoPrompt, errPasswordChangeNotSupported()
+}
+
+// unlockWith decrypts the master password sent over a session and unlocks
+// the vault with it, then tells clients the collections changed
+func (g *gnomeKeyring) unlockWith(master Secret) *dbus.Error {
+	ctx := context.Background()
+
+	session, dbusErr := g.svc.sessionManager.GetSessionOrError(master.Session)
+	if dbusErr != nil {
+		return dbusErr
+	}
+
+	password, err := session.DecryptSecret(master.Value, master.Parameters)
+	if err != nil {
+		return toDBusError(fmt.Errorf("failed to decrypt master password: %w", err))
+	}
+
+	if _, err := g.svc.bwClient.Unlock(ctx, string(password)); err != nil {
+		if errors.Is(err, bitwarden.ErrInvalidPassword) {
+			return &dbus.Error{
+				Name: ErrAccessDenied,
+				Body: []interface{}{"The password was invalid"},
+			}
+		}
+		logging.L.With("component", "dbus").Warn("unlock with master password failed", "error", err)
+		return toDBusError(err)
+	}
+
+	g.svc.collectionManager.RefreshFoldersIfUnlocked(ctx)
+	return nil
+}
+
+// errPasswordChangeNotSupported is returned for password change requests
+func errPasswordChangeNotSupported() *dbus.Error {
+	return &dbus.Error{
+		Name: ErrNotSupported,
+		Body: []interface{}{"The master password can only be changed in Bitwarden"},
+	}
+}
@@ -0,0 +1,131 @@
+package dbus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// newGnomeTestKeyring returns the gnome-keyring interface over a bw serve
+// stand-in whose master password is "right", and a plain session to send it
+func newGnomeTestKeyring(t *testing.T, unlockCalls *atomic.Int32) (*gnomeKeyring, dbus.ObjectPath) {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/unlock":
+			unlockCalls.Add(1)
+			b, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(b), `"password":"right"`) {
+				w.Write([]byte(`{"success":true,"data":{"raw":"token"}}`))
+				return
+			}
+			w.Write([]byte(`{"success":false,"data":{"message":"Invalid master password."}}`))
+		case "/status":
+			// Reported locked so no folder refresh is attempted
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"locked"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, _ := newAliasTestManager(t)
+	cm.collections[SessionCollectionPath] = cm.newCollection(SessionCollectionPath, "", "Session", "")
+	cm.bwClient = testBWClient(t, ts)
+
+	svc := &Service{
+		bwClient:          cm.bwClient,
+		sessionManager:    sm,
+		collectionManager: cm,
+	}
+	return &gnomeKeyring{svc: svc}, session.Path()
+}
+
+func TestGnomeKeyring_UnlockWithMasterPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection dbus.ObjectPath
+		password   string
+		wantErr    string
+		wantUnlock int32
+	}{
+		{"right password", DefaultCollectionPath, "right", "", 1},
+		{"alias path", dbus.ObjectPath(AliasPath + "default"), "right", "", 1},
+		{"wrong password", DefaultCollectionPath, "wrong", ErrAccessDenied, 1},
+		{"unknown collection", CollectionPathFromFolderID("missing"), "right", ErrNoSuchObject, 0},
+		{"session collection", SessionCollectionPath, "wrong", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unlockCalls atomic.Int32
+			g, session := newGnomeTestKeyring(t, &unlockCalls)
+
+			err := g.UnlockWithMasterPassword(tt.collection, Secret{Session: session, Value: []byte(tt.password)})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("UnlockWithMasterPassword() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Name != tt.wantErr) {
+				t.Fatalf("UnlockWithMasterPassword() error = %v, want %s", err, tt.wantErr)
+			}
+			if got := unlockCalls.Load(); got != tt.wantUnlock {
+				t.Errorf("Unlock called %d times, want %d", got, tt.wantUnlock)
+			}
+		})
+	}
+}
+
+func TestGnomeKeyring_CreateWithMasterPassword(t *testing.T) {
+	var unlockCalls atomic.Int32
+	g, session := newGnomeTestKeyring(t, &unlockCalls)
+
+	if _, err := g.CreateWithMasterPassword(nil, Secret{Session: session, Value: []byte("wrong")}); err == nil || err.Name != ErrAccessDenied {
+		t.Fatalf("CreateWithMasterPassword(wrong) error = %v, want %s", err, ErrAccessDenied)
+	}
+
+	// Without a label the default collection is returned
+	path, err := g.CreateWithMasterPassword(nil, Secret{Session: session, Value: []byte("right")})
+	if err != nil {
+		t.Fatalf("CreateWithMasterPassword() error = %v", err)
+	}
+	if path != DefaultCollectionPath {
+		t.Errorf("CreateWithMasterPassword() = %s, want %s", path, DefaultCollectionPath)
+	}
+}
+
+func TestGnomeKeyring_NoSession(t *testing.T) {
+	var unlockCalls atomic.Int32
+	g, _ := newGnomeTestKeyring(t, &unlockCalls)
+
+	err := g.UnlockWithMasterPassword(DefaultCollectionPath, Secret{Session: SessionPath + "missing", Value: []byte("right")})
+	if err == nil || err.Name != ErrNoSession {
+		t.Errorf("UnlockWithMasterPassword() error = %v, want %s", err, ErrNoSession)
+	}
+	if unlockCalls.Load() != 0 {
+		t.Errorf("Unlock called %d times, want 0", unlockCalls.Load())
+	}
+}
+
+func TestGnomeKeyring_ChangeNotSupported(t *testing.T) {
+	g := &gnomeKeyring{}
+
+	if err := g.ChangeWithMasterPassword(DefaultCollectionPath, Secret{}, Secret{}); err == nil || err.Name != ErrNotSupported {
+		t.Errorf("ChangeWithMasterPassword() error = %v, want %s", err, ErrNotSupported)
+	}
+	if _, err := g.ChangeWithPrompt(DefaultCollectionPath); err == nil || err.Name != ErrNotSupported {
+		t.Errorf("ChangeWithPrompt() error = %v, want %s", err, ErrNotSupported)
+	}
+}
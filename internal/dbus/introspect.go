@@ -57,6 +57,26 @@ const ServiceIntrospectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS O
       <arg name="collection" type="o"/>
     </signal>
   </interface>
+  <interface name="org.gnome.keyring.InternalUnsupportedGuiltRiddenInterface">
+    <method name="ChangeWithMasterPassword">
+      <arg name="collection" type="o" direction="in"/>
+      <arg name="original" type="(oayays)" direction="in"/>
+      <arg name="master" type="(oayays)" direction="in"/>
+    </method>
+    <method name="ChangeWithPrompt">
+      <arg name="collection" type="o" direction="in"/>
+      <arg name="prompt" type="o" direction="out"/>
+    </method>
+    <method name="CreateWithMasterPassword">
+      <arg name="attributes" type="a{sv}" direction="in"/>
+      <arg name="master" type="(oayays)" direction="in"/>
+      <arg name="collection" type="o" direction="out"/>
+    </method>
+    <method name="UnlockWithMasterPassword">
+      <arg name="collection" type="o" direction="in"/>
+      <arg name="master" type="(oayays)" direction="in"/>
+    </method>
+  </interface>
   <interface name="org.freedesktop.DBus.Properties">
     <method name="Get">
       <arg name="interface" type="s" direction="in"/>
@@ -156,6 +176,12 @@ const ItemIntrospectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Obje
     <property name="Created" type="t" access="read"/>
     <property name="Modified" type="t" access="read"/>
   </interface>
+  <interface name="io.github.joe.BitwardenKeyring.Item">
+    <method name="GetTOTP">
+      <arg name="code" type="s" direction="out"/>
+      <arg name="remaining" type="u" direction="out"/>
+    </method>
+  </interface>
   <interface name="org.freedesktop.DBus.Properties">
     <method name="Get">
       <arg name="interface" type="s" direction="in"/>
@@ -211,3 +237,40 @@ const PromptIntrospectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Ob
     </method>
   </interface>
 </node>`
+
+const PortalIntrospectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+"http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="org.freedesktop.impl.portal.Secret">
+    <method name="RetrieveSecret">
+      <arg name="handle" type="o" direction="in"/>
+      <arg name="app_id" type="s" direction="in"/>
+      <arg name="fd" type="h" direction="in"/>
+      <arg name="options" type="a{sv}" direction="in"/>
+      <arg name="response" type="u" direction="out"/>
+      <arg name="results" type="a{sv}" direction="out"/>
+    </method>
+    <property name="version" type="u" access="read"/>
+  </interface>
+  <interface name="org.freedesktop.DBus.Properties">
+    <method name="Get">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="Set">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="in"/>
+    </method>
+    <method name="GetAll">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="properties" type="a{sv}" direction="out"/>
+    </method>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect">
+      <arg name="xml" type="s" direction="out"/>
+    </method>
+  </interface>
+</node>`
@@ -2,11 +2,16 @@ package dbus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/godbus/dbus/v5"
+	"github.com/joe/bitwarden-keyring/internal/access"
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
 	"github.com/joe/bitwarden-keyring/internal/mapping"
 )
 
@@ -19,6 +24,7 @@ type Item struct {
 	sessionManager *SessionManager
 	collection     *Collection
 	itemManager    *ItemManager
+	selector       string // non-empty for a read-only view returning this secret
 	mu             sync.RWMutex
 }
 
@@ -35,6 +41,7 @@ type ItemManager struct {
 	bwClient       *bitwarden.Client
 	sessionManager *SessionManager
 	items          map[dbus.ObjectPath]*itemEntry
+	access         *access.Controller // nil allows every caller
 	mu             sync.RWMutex
 	exportFunc     func(*Item) error // for testing; defaults to exportItem
 }
@@ -49,9 +56,31 @@ func NewItemManager(conn *dbus.Conn, bwClient *bitwarden.Client, sessionManager
 	}
 }
 
-// GetOrCreateItem gets or creates an Item for a Bitwarden item
+// GetOrCreateItem gets or creates an Item for a Bitwarden item.
+// The item is exported below the given collection's path; a nil collection
+// places it in the default collection.
 func (im *ItemManager) GetOrCreateItem(bwItem *bitwarden.Item, collection *Collection) (*Item, error) {
+	return im.getOrCreate(bwItem, collection, "")
+}
+
+// GetOrCreateSecretView gets or creates a secret view of an item: a read-only
+// Item below the item's path whose secret is the one named by selector (see
+// mapping.SelectSecret). Searches with bitwarden:secret return these views, so
+// clients such as secret-tool can look up TOTP codes or custom fields like
+// passwords.
+func (im *ItemManager) GetOrCreateSecretView(bwItem *bitwarden.Item, collection *Collection, selector string) (*Item, error) {
+	return im.getOrCreate(bwItem, collection, selector)
+}
+
+// getOrCreate implements GetOrCreateItem and GetOrCreateSecretView
+func (im *ItemManager) getOrCreate(bwItem *bitwarden.Item, collection *Collection, selector string) (*Item, error) {
 	path := ItemPathFromID(bwItem.ID)
+	if collection != nil {
+		path = ItemPathInCollection(collection.path, bwItem.ID)
+	}
+	if selector != "" {
+		path += dbus.ObjectPath("/" + secretViewElement(selector))
+	}
 
 	// Fast path: check if entry exists
 	im.mu.RLock()
@@ -108,6 +137,7 @@ func (im *ItemManager) GetOrCreateItem(bwItem *bitwarden.Item, collection *Colle
 		sessionManager: im.sessionManager,
 		collection:     collection,
 		itemManager:    im,
+		selector:       selector,
 	}
 
 	// Use injected export function if set (for testing), else use real export
@@ -157,8 +187,11 @@ func (im *ItemManager) GetItem(path dbus.ObjectPath) (*Item, bool) {
 	return entry.item, true
 }
 
-// RemoveItem removes an item and unexports all its D-Bus interfaces
+// RemoveItem removes an item and its secret views, if any, and unexports all
+// their D-Bus interfaces
 func (im *ItemManager) RemoveItem(path dbus.ObjectPath) {
+	im.removeChildren(path)
+
 	// First, get the entry outside the lock
 	im.mu.RLock()
 	entry, ok := im.items[path]
@@ -179,17 +212,84 @@ func (im *ItemManager) RemoveItem(path dbus.ObjectPath) {
 	// Unexport all interfaces that were exported (only if export succeeded and conn exists)
 	if entry.err == nil && im.conn != nil {
 		unexportDBusObject(im.conn, path, ItemInterface, true)
+		if err := im.conn.Export(nil, path, KeyringItemInterface); err != nil {
+			logging.L.With("component", "dbus").Warn("failed to unexport interface", "interface", KeyringItemInterface, "path", path, "error", err)
+		}
+	}
+}
+
+// RemoveVaultItems removes and unexports every item backed by the vault,
+// keeping those of the session collection. It is used when the vault locks,
+// so that no copy of vault data outlives the lock; searches export the items
+// again once it is unlocked.
+func (im *ItemManager) RemoveVaultItems() {
+	for _, objs := range im.vaultItems() {
+		for _, item := range objs {
+			im.RemoveItem(item.path)
+		}
+	}
+}
+
+// RemoveCollectionItems removes and unexports every item exported below the
+// given collection path. It is used when a collection disappears.
+func (im *ItemManager) RemoveCollectionItems(collPath dbus.ObjectPath) {
+	im.removeChildren(collPath)
+}
+
+// removeChildren removes every item exported below path
+func (im *ItemManager) removeChildren(parent dbus.ObjectPath) {
+	prefix := string(parent) + "/"
+
+	im.mu.RLock()
+	var paths []dbus.ObjectPath
+	for path := range im.items {
+		if strings.HasPrefix(string(path), prefix) {
+			paths = append(paths, path)
+		}
+	}
+	im.mu.RUnlock()
+
+	for _, path := range paths {
+		im.RemoveItem(path)
 	}
 }
 
-// exportItem exports an item to D-Bus
+// exportItem exports an item to D-Bus, with GetTOTP on KeyringItemInterface
 func (im *ItemManager) exportItem(item *Item) error {
-	return exportDBusObject(im.conn, item, item.path, ItemInterface, ItemIntrospectXML, true)
+	if err := exportDBusObject(im.conn, item, item.path, ItemInterface, ItemIntrospectXML, true); err != nil {
+		return err
+	}
+	return im.conn.ExportMethodTable(map[string]interface{}{"GetTOTP": item.GetTOTP}, item.path, KeyringItemInterface)
 }
 
-// ItemPathFromID creates an item path from a Bitwarden item ID
+// secretViewElement returns the object path element of a secret view.
+// Selector keywords keep their name ("totp", "card_number"); custom fields
+// get a "field_" prefix with characters invalid in paths escaped as _XX.
+func secretViewElement(selector string) string {
+	switch selector {
+	case mapping.SecretPassword, mapping.SecretNotes, mapping.SecretCardNumber, mapping.SecretSSHKey, mapping.SecretTOTP:
+		return strings.ReplaceAll(selector, "-", "_")
+	}
+	var b strings.Builder
+	b.WriteString("field_")
+	for _, c := range []byte(selector) {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// ItemPathFromID creates an item path in the default collection from a Bitwarden item ID
 func ItemPathFromID(id string) dbus.ObjectPath {
-	return dbus.ObjectPath(CollectionPath + "default/" + SanitizeID(id))
+	return ItemPathInCollection(DefaultCollectionPath, id)
+}
+
+// ItemPathInCollection creates an item path below the given collection path
+func ItemPathInCollection(collPath dbus.ObjectPath, id string) dbus.ObjectPath {
+	return dbus.ObjectPath(string(collPath) + "/" + SanitizeID(id))
 }
 
 // Path returns the item's object path
@@ -204,8 +304,20 @@ func (i *Item) ID() string {
 	return i.bwItem.ID
 }
 
+// store returns the backend holding this item: the session collection's
+// in-memory store, or the Bitwarden vault
+func (i *Item) store() itemStore {
+	if i.collection != nil && i.collection.memory != nil {
+		return i.collection.memory
+	}
+	return i.bwClient
+}
+
 // Delete deletes the item (D-Bus method)
 func (i *Item) Delete() (dbus.ObjectPath, *dbus.Error) {
+	if i.selector != "" {
+		return NoPrompt, errSecretViewReadOnly()
+	}
 	ctx := context.Background()
 
 	i.mu.RLock()
@@ -213,7 +325,7 @@ func (i *Item) Delete() (dbus.ObjectPath, *dbus.Error) {
 	collPath := i.collection.path
 	i.mu.RUnlock()
 
-	if err := i.bwClient.DeleteItem(ctx, id); err != nil {
+	if err := i.store().DeleteItem(ctx, id); err != nil {
 		return NoPrompt, toDBusError(err)
 	}
 
@@ -226,13 +338,81 @@ func (i *Item) Delete() (dbus.ObjectPath, *dbus.Error) {
 	return NoPrompt, nil
 }
 
-// GetSecret returns the item's secret (D-Bus method)
-func (i *Item) GetSecret(sessionPath dbus.ObjectPath) (Secret, *dbus.Error) {
+// GetSecret returns the item's secret (D-Bus method).
+// The payload and content type depend on the item type, see mapping.ItemSecret.
+// The caller must be allowed by the access controller. Reading a vault item
+// unlocks the vault first, like GetSecrets.
+func (i *Item) GetSecret(sender dbus.Sender, sessionPath dbus.ObjectPath) (Secret, *dbus.Error) {
+	if err := i.ensureUnlocked(); err != nil {
+		return Secret{}, toDBusError(err)
+	}
+	if err := i.checkAccess(sender); err != nil {
+		return Secret{}, toDBusError(err)
+	}
+	if err := i.verifyReprompt(); err != nil {
+		return Secret{}, toDBusError(err)
+	}
+	return i.secret(sessionPath)
+}
+
+// ensureUnlocked makes sure the vault is unlocked before the data of a vault
+// item is handed out, asking for the master password unless --strict-unlock
+// is set. A locked vault or a dismissed prompt yields ErrVaultLocked. Items of
+// the in-memory session collection are readable while the vault is locked.
+func (i *Item) ensureUnlocked() error {
+	if i.bwClient == nil || (i.collection != nil && i.collection.memory != nil) {
+		return nil
+	}
+	err := i.bwClient.EnsureUnlocked(context.Background())
+	if errors.Is(err, bitwarden.ErrUserCancelled) {
+		return bitwarden.ErrVaultLocked
+	}
+	return err
+}
+
+// checkAccess asks the access controller whether sender may read the secret
+func (i *Item) checkAccess(sender dbus.Sender) error {
+	if i.itemManager == nil {
+		return nil
+	}
+	return i.itemManager.access.Check(string(sender), i.accessTarget())
+}
+
+// accessTarget describes the item to the access controller
+func (i *Item) accessTarget() access.Target {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
+	return access.Target{ID: i.bwItem.ID, Label: i.bwItem.Name}
+}
 
-	if i.bwItem.Login == nil || i.bwItem.Login.Password == nil {
-		return Secret{}, toDBusError(fmt.Errorf("item has no password"))
+// verifyReprompt asks for the master password again if the item is
+// protected by Bitwarden's master password re-prompt
+func (i *Item) verifyReprompt() error {
+	if i.bwClient == nil {
+		return nil
+	}
+	i.mu.RLock()
+	target := &bitwarden.Item{Name: i.bwItem.Name, Reprompt: i.bwItem.Reprompt}
+	i.mu.RUnlock()
+
+	return i.bwClient.VerifyReprompt(context.Background(), target)
+}
+
+// secret returns the item's secret encrypted for the given session
+func (i *Item) secret(sessionPath dbus.ObjectPath) (Secret, *dbus.Error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var plaintext []byte
+	var contentType string
+	var err error
+	if i.selector != "" {
+		plaintext, contentType, err = mapping.SelectSecret(i.bwItem, i.selector, time.Now())
+	} else {
+		plaintext, contentType, err = mapping.ItemSecret(i.bwItem)
+	}
+	if err != nil {
+		return Secret{}, toDBusError(err)
 	}
 
 	// Get session for encryption
@@ -241,8 +421,6 @@ func (i *Item) GetSecret(sessionPath dbus.ObjectPath) (Secret, *dbus.Error) {
 		return Secret{}, dbusErr
 	}
 
-	plaintext := []byte(*i.bwItem.Login.Password)
-
 	// Encrypt if needed
 	value, params, err := session.EncryptSecret(plaintext)
 	if err != nil {
@@ -253,7 +431,7 @@ func (i *Item) GetSecret(sessionPath dbus.ObjectPath) (Secret, *dbus.Error) {
 		Session:     sessionPath,
 		Parameters:  params,
 		Value:       value,
-		ContentType: "text/plain",
+		ContentType: contentType,
 	}
 
 	return secret, nil
@@ -261,6 +439,9 @@ func (i *Item) GetSecret(sessionPath dbus.ObjectPath) (Secret, *dbus.Error) {
 
 // SetSecret sets the item's secret (D-Bus method)
 func (i *Item) SetSecret(secret Secret) *dbus.Error {
+	if i.selector != "" {
+		return errSecretViewReadOnly()
+	}
 	ctx := context.Background()
 
 	// Decrypt the secret if using encrypted session
@@ -277,28 +458,84 @@ func (i *Item) SetSecret(secret Secret) *dbus.Error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	// Guard against nil Login struct
-	if i.bwItem.Login == nil {
-		return toDBusError(fmt.Errorf("item has no login credentials"))
+	changed, err := i.bwItem.Clone()
+	if err != nil {
+		return toDBusError(err)
+	}
+	if err := mapping.SetItemSecret(changed, decryptedValue); err != nil {
+		return toDBusError(err)
 	}
 
-	password := string(decryptedValue)
-	i.bwItem.Login.Password = &password
+	if err := i.update(ctx, changed); err != nil {
+		return toDBusError(err)
+	}
 
-	// Use ToUpdateRequest to preserve all fields
-	req := i.bwItem.ToUpdateRequest()
+	// Emit ItemChanged signal using actual collection path
+	EmitItemChanged(i.conn, i.collection.path, i.path)
+
+	return nil
+}
+
+// GetTOTP returns the item's current TOTP code and the seconds it stays
+// valid (D-Bus method on KeyringItemInterface). Like GetSecret it is subject
+// to the lock, access confirmation and master password re-prompt.
+func (i *Item) GetTOTP(sender dbus.Sender) (string, uint32, *dbus.Error) {
+	if err := i.ensureUnlocked(); err != nil {
+		return "", 0, toDBusError(err)
+	}
+	if err := i.checkAccess(sender); err != nil {
+		return "", 0, toDBusError(err)
+	}
+	if err := i.verifyReprompt(); err != nil {
+		return "", 0, toDBusError(err)
+	}
 
-	updated, err := i.bwClient.UpdateItem(ctx, i.bwItem.ID, req)
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if !mapping.HasTOTP(i.bwItem) {
+		return "", 0, &dbus.Error{Name: ErrNotSupported, Body: []interface{}{"Item has no TOTP key"}}
+	}
+	code, remaining, err := mapping.ItemTOTP(i.bwItem, time.Now())
 	if err != nil {
-		return toDBusError(err)
+		return "", 0, toDBusError(err)
 	}
+	return code, uint32(remaining / time.Second), nil
+}
 
+// update writes changed, a modified clone of bwItem, to the store and makes
+// the result the item's state. Like every write it is merged with changes
+// made elsewhere since the item was read; on a conflict bwItem is reloaded,
+// so a retry applies on top of the other change. On any other failure bwItem
+// is left as it was. Callers must hold i.mu.
+func (i *Item) update(ctx context.Context, changed *bitwarden.Item) error {
+	// Use ToUpdateRequest to preserve all fields
+	updated, err := i.store().UpdateItem(ctx, changed.ID, changed.ToUpdateRequest())
+	if err != nil {
+		var conflict *bitwarden.ConflictError
+		if errors.As(err, &conflict) {
+			i.bwItem = conflict.Current
+		}
+		return err
+	}
 	i.bwItem = updated
+	return nil
+}
 
-	// Emit ItemChanged signal using actual collection path
-	EmitItemChanged(i.conn, i.collection.path, i.path)
+// attributes returns the item's attributes with folder as bitwarden:folder;
+// secret views add their bitwarden:secret selector so they stay
+// distinguishable from the item. Callers must hold i.mu.
+func (i *Item) attributes(folder string) map[string]string {
+	attrs := mapping.ItemToAttributes(i.bwItem)
+	attrs[mapping.AttrFolder] = folder
+	if i.selector != "" {
+		attrs[mapping.AttrSecret] = i.selector
+	}
+	return attrs
+}
 
-	return nil
+// errSecretViewReadOnly is returned when a client tries to modify a secret view
+func errSecretViewReadOnly() *dbus.Error {
+	return &dbus.Error{Name: ErrNotSupported, Body: []interface{}{"Secret views are read-only"}}
 }
 
 // Get implements org.freedesktop.DBus.Properties.Get
@@ -307,20 +544,22 @@ func (i *Item) Get(iface, property string) (dbus.Variant, *dbus.Error) {
 		return dbus.Variant{}, toDBusError(fmt.Errorf("unknown interface: %s", iface))
 	}
 
-	// Handle Locked property separately since it needs to query bwClient
+	// Handle Locked property separately since it needs to query the store
 	// without holding the lock
 	if property == "Locked" {
 		ctx := context.Background()
-		return dbus.MakeVariant(i.bwClient.IsLockedSafe(ctx)), nil
+		return dbus.MakeVariant(i.store().IsLockedSafe(ctx)), nil
 	}
 
+	// The collection lock is never taken while holding the item's lock
+	folder := i.collection.folderName()
+
 	i.mu.RLock()
 	defer i.mu.RUnlock()
 
 	switch property {
 	case "Attributes":
-		attrs := mapping.ItemToAttributes(i.bwItem)
-		return dbus.MakeVariant(attrs), nil
+		return dbus.MakeVariant(i.attributes(folder)), nil
 
 	case "Label":
 		return dbus.MakeVariant(i.bwItem.Name), nil
@@ -342,27 +581,31 @@ func (i *Item) Set(iface, property string, value dbus.Variant) *dbus.Error {
 		return toDBusError(fmt.Errorf("unknown interface: %s", iface))
 	}
 
+	if i.selector != "" {
+		return errSecretViewReadOnly()
+	}
+
 	ctx := context.Background()
 
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
+	changed, err := i.bwItem.Clone()
+	if err != nil {
+		return toDBusError(err)
+	}
+
 	switch property {
 	case "Label":
 		label, ok := value.Value().(string)
 		if !ok {
 			return toDBusError(fmt.Errorf("invalid label type"))
 		}
-		i.bwItem.Name = label
-
-		// Use ToUpdateRequest to preserve all fields
-		req := i.bwItem.ToUpdateRequest()
+		changed.Name = label
 
-		updated, err := i.bwClient.UpdateItem(ctx, i.bwItem.ID, req)
-		if err != nil {
+		if err := i.update(ctx, changed); err != nil {
 			return toDBusError(err)
 		}
-		i.bwItem = updated
 
 		// Emit ItemChanged signal using actual collection path
 		EmitItemChanged(i.conn, i.collection.path, i.path)
@@ -375,18 +618,13 @@ func (i *Item) Set(iface, property string, value dbus.Variant) *dbus.Error {
 			return toDBusError(fmt.Errorf("invalid attributes type: expected map[string]string"))
 		}
 
-		// Update item from attributes
-		mapping.UpdateItemFromAttributes(i.bwItem, attrs)
-
-		// Use ToUpdateRequest to preserve all fields
-		req := i.bwItem.ToUpdateRequest()
+		// Record the new attribute set, replacing the previous one
+		mapping.StoreAttributes(changed, attrs)
 
 		// Save to Bitwarden
-		updated, err := i.bwClient.UpdateItem(ctx, i.bwItem.ID, req)
-		if err != nil {
+		if err := i.update(ctx, changed); err != nil {
 			return toDBusError(fmt.Errorf("failed to update item: %w", err))
 		}
-		i.bwItem = updated
 
 		// Emit ItemChanged signal using actual collection path
 		EmitItemChanged(i.conn, i.collection.path, i.path)
@@ -406,14 +644,15 @@ func (i *Item) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
 
 	// Check lock state before holding the lock
 	ctx := context.Background()
-	locked := i.bwClient.IsLockedSafe(ctx)
+	locked := i.store().IsLockedSafe(ctx)
+	folder := i.collection.folderName()
 
 	i.mu.RLock()
 	defer i.mu.RUnlock()
 
 	props := map[string]dbus.Variant{
 		"Locked":     dbus.MakeVariant(locked),
-		"Attributes": dbus.MakeVariant(mapping.ItemToAttributes(i.bwItem)),
+		"Attributes": dbus.MakeVariant(i.attributes(folder)),
 		"Label":      dbus.MakeVariant(i.bwItem.Name),
 		"Created":    dbus.MakeVariant(uint64(i.bwItem.CreationDate.Unix())),
 		"Modified":   dbus.MakeVariant(uint64(i.bwItem.RevisionDate.Unix())),
@@ -1,14 +1,20 @@
 package dbus
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/godbus/dbus/v5"
+	"github.com/joe/bitwarden-keyring/internal/access"
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/mapping"
 )
 
 // mockExportFunc is a controllable export function for testing
@@ -378,3 +384,381 @@ func TestItemManager_UpdateExistingItem(t *testing.T) {
 		t.Errorf("item name = %q, expected %q", name, "updated")
 	}
 }
+
+type denyPrompter struct{ calls int }
+
+func (p *denyPrompter) PromptForAccess(string) (bitwarden.AccessChoice, error) {
+	p.calls++
+	return bitwarden.AccessDeny, nil
+}
+
+func TestItem_GetSecret_AccessControl(t *testing.T) {
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := NewItemManager(nil, nil, sm)
+	im.exportFunc = func(*Item) error { return nil }
+	prompter := &denyPrompter{}
+	// Without a bus connection callers cannot be identified, so every
+	// remote read is confirmed and answers are never remembered
+	im.access = access.NewController(nil, access.NewPolicy(""), prompter)
+
+	password := "s3cret"
+	item, err := im.GetOrCreateItem(&bitwarden.Item{
+		ID:    "item-1",
+		Type:  bitwarden.ItemTypeLogin,
+		Name:  "GitHub",
+		Login: &bitwarden.Login{Password: &password},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, dbusErr := item.GetSecret(":1.42", session.Path())
+	if dbusErr == nil || dbusErr.Name != ErrAccessDenied {
+		t.Fatalf("GetSecret() error = %v, want %s", dbusErr, ErrAccessDenied)
+	}
+	if prompter.calls != 1 {
+		t.Errorf("prompted %d times, want 1", prompter.calls)
+	}
+
+	// In-process calls carry no sender and are not confirmed
+	secret, dbusErr := item.GetSecret("", session.Path())
+	if dbusErr != nil {
+		t.Fatalf("GetSecret() without sender error = %v", dbusErr)
+	}
+	if string(secret.Value) != password {
+		t.Errorf("GetSecret() = %q, want %q", secret.Value, password)
+	}
+	if prompter.calls != 1 {
+		t.Errorf("in-process read prompted; calls = %d", prompter.calls)
+	}
+}
+
+func TestService_GetSecrets_AccessControl(t *testing.T) {
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := NewItemManager(nil, nil, sm)
+	im.exportFunc = func(*Item) error { return nil }
+	prompter := &denyPrompter{}
+	im.access = access.NewController(nil, access.NewPolicy(""), prompter)
+	svc := &Service{sessionManager: sm, itemManager: im}
+
+	coll := &Collection{path: SessionCollectionPath, memory: newMemoryStore()}
+	password := "s3cret"
+	var paths []dbus.ObjectPath
+	for _, id := range []string{"item-1", "item-2"} {
+		item, err := im.GetOrCreateItem(&bitwarden.Item{
+			ID:    id,
+			Type:  bitwarden.ItemTypeLogin,
+			Login: &bitwarden.Login{Password: &password},
+		}, coll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, item.Path())
+	}
+
+	_, dbusErr := svc.GetSecrets(":1.42", paths, session.Path())
+	if dbusErr == nil || dbusErr.Name != ErrAccessDenied {
+		t.Errorf("GetSecrets() error = %v, want %s", dbusErr, ErrAccessDenied)
+	}
+	if prompter.calls != 1 {
+		t.Errorf("prompted %d times, want once for the batch", prompter.calls)
+	}
+
+	secrets, dbusErr := svc.GetSecrets("", paths, session.Path())
+	if dbusErr != nil || len(secrets) != 2 {
+		t.Errorf("in-process GetSecrets() = %d secrets, %v, want 2", len(secrets), dbusErr)
+	}
+}
+
+func TestItem_GetSecret_Locked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"template":{"status":"locked"}}}`))
+	}))
+	defer ts.Close()
+
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := testBWClient(t, ts)
+	client.SetAutoUnlock(false)
+	im := NewItemManager(nil, client, sm)
+	im.exportFunc = func(*Item) error { return nil }
+	def := &Collection{path: DefaultCollectionPath}
+	sessionColl := &Collection{path: SessionCollectionPath, memory: newMemoryStore()}
+
+	password := "s3cret"
+	key := "JBSWY3DPEHPK3PXP"
+	login := func(id string) *bitwarden.Item {
+		return &bitwarden.Item{ID: id, Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{Password: &password, TOTP: &key}}
+	}
+	vaultItem, err := im.GetOrCreateItem(login("vault"), def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionItem, err := im.GetOrCreateItem(login("session"), sessionColl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, dbusErr := vaultItem.GetSecret("", session.Path()); dbusErr == nil || dbusErr.Name != ErrIsLocked {
+		t.Errorf("GetSecret() on a locked vault error = %v, want %s", dbusErr, ErrIsLocked)
+	}
+	if _, _, dbusErr := vaultItem.GetTOTP(""); dbusErr == nil || dbusErr.Name != ErrIsLocked {
+		t.Errorf("GetTOTP() on a locked vault error = %v, want %s", dbusErr, ErrIsLocked)
+	}
+	if secret, dbusErr := sessionItem.GetSecret("", session.Path()); dbusErr != nil || string(secret.Value) != password {
+		t.Errorf("session item GetSecret() = %q, %v, want %q", secret.Value, dbusErr, password)
+	}
+
+	im.RemoveVaultItems()
+	if _, ok := im.GetItem(vaultItem.Path()); ok {
+		t.Error("vault item still exported after RemoveVaultItems")
+	}
+	if _, ok := im.GetItem(sessionItem.Path()); !ok {
+		t.Error("session item removed by RemoveVaultItems")
+	}
+}
+
+func TestItem_GetSecret_AfterLock(t *testing.T) {
+	var locked atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/lock":
+			locked.Store(true)
+			w.Write([]byte(`{"success":true}`))
+		case "/status":
+			status := "unlocked"
+			if locked.Load() {
+				status = "locked"
+			}
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"` + status + `"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// --strict-unlock: a locked vault answers IsLocked instead of prompting
+	client := testBWClient(t, ts)
+	client.SetAutoUnlock(false)
+	im := NewItemManager(nil, client, sm)
+	im.exportFunc = func(*Item) error { return nil }
+
+	password := "s3cret"
+	item, err := im.GetOrCreateItem(&bitwarden.Item{
+		ID:    "item-1",
+		Type:  bitwarden.ItemTypeLogin,
+		Login: &bitwarden.Login{Password: &password},
+	}, &Collection{path: DefaultCollectionPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret, dbusErr := item.GetSecret("", session.Path()); dbusErr != nil || string(secret.Value) != password {
+		t.Fatalf("GetSecret() before lock = %q, %v, want %q", secret.Value, dbusErr, password)
+	}
+	if err := client.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if secret, dbusErr := item.GetSecret("", session.Path()); dbusErr == nil || dbusErr.Name != ErrIsLocked {
+		t.Errorf("GetSecret() after lock = %q, %v, want %s", secret.Value, dbusErr, ErrIsLocked)
+	}
+}
+
+func TestItem_FailedUpdateKeepsItem(t *testing.T) {
+	password := "old"
+	stored := &bitwarden.Item{ID: "item-1", Name: "Mail", Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{Password: &password}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/status":
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"unlocked"}}}`))
+		case r.URL.Path == "/object/item/item-1" && r.Method == "GET":
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": stored})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := NewItemManager(nil, testBWClient(t, ts), sm)
+	im.exportFunc = func(*Item) error { return nil }
+	local, err := stored.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := im.GetOrCreateItem(local, &Collection{path: DefaultCollectionPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dbusErr := item.SetSecret(Secret{Session: session.Path(), Value: []byte("new")}); dbusErr == nil {
+		t.Error("SetSecret() succeeded although the update failed")
+	}
+	if dbusErr := item.Set(ItemInterface, "Label", dbus.MakeVariant("Renamed")); dbusErr == nil {
+		t.Error("Set(Label) succeeded although the update failed")
+	}
+	if dbusErr := item.Set(ItemInterface, "Attributes", dbus.MakeVariant(map[string]string{"app": "mail"})); dbusErr == nil {
+		t.Error("Set(Attributes) succeeded although the update failed")
+	}
+
+	secret, dbusErr := item.GetSecret("", session.Path())
+	if dbusErr != nil || string(secret.Value) != "old" {
+		t.Errorf("GetSecret() after failed update = %q, %v, want old", secret.Value, dbusErr)
+	}
+	if label, _ := item.Get(ItemInterface, "Label"); label.Value() != "Mail" {
+		t.Errorf("Label after failed update = %v, want Mail", label.Value())
+	}
+	if attrs, _ := item.Get(ItemInterface, "Attributes"); attrs.Value().(map[string]string)["app"] != "" {
+		t.Errorf("Attributes after failed update = %v, want no app", attrs.Value())
+	}
+}
+
+func TestItem_TOTPView(t *testing.T) {
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := NewItemManager(nil, nil, sm)
+	im.exportFunc = func(*Item) error { return nil }
+
+	password := "s3cret"
+	key := "JBSWY3DPEHPK3PXP"
+	bwItem := &bitwarden.Item{
+		ID:    "item-1",
+		Type:  bitwarden.ItemTypeLogin,
+		Name:  "GitHub",
+		Login: &bitwarden.Login{Password: &password, TOTP: &key},
+	}
+	item, err := im.GetOrCreateItem(bwItem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := im.GetOrCreateSecretView(bwItem, nil, mapping.SecretTOTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Path() != item.Path()+"/totp" {
+		t.Errorf("view path = %s, want below %s", view.Path(), item.Path())
+	}
+
+	code, remaining, dbusErr := item.GetTOTP("")
+	if dbusErr != nil || len(code) != 6 || remaining == 0 || remaining > 30 {
+		t.Fatalf("GetTOTP() = %q, %d, %v", code, remaining, dbusErr)
+	}
+
+	// The view's secret is the code; the item's is still the password
+	secret, dbusErr := view.GetSecret("", session.Path())
+	if dbusErr != nil {
+		t.Fatalf("view GetSecret() error = %v", dbusErr)
+	}
+	if len(secret.Value) != 6 || string(secret.Value) == password {
+		t.Errorf("view secret = %q, want a TOTP code", secret.Value)
+	}
+	if secret, _ := item.GetSecret("", session.Path()); string(secret.Value) != password {
+		t.Errorf("item secret = %q, want the password", secret.Value)
+	}
+
+	attrs, _ := view.Get(ItemInterface, "Attributes")
+	if attrs.Value().(map[string]string)["bitwarden:secret"] != "totp" {
+		t.Errorf("view attributes = %v, want bitwarden:secret=totp", attrs.Value())
+	}
+	if err := view.SetSecret(Secret{Session: session.Path(), Value: []byte("x")}); err == nil || err.Name != ErrNotSupported {
+		t.Errorf("view SetSecret() error = %v, want %s", err, ErrNotSupported)
+	}
+	if _, err := view.Delete(); err == nil || err.Name != ErrNotSupported {
+		t.Errorf("view Delete() error = %v, want %s", err, ErrNotSupported)
+	}
+
+	// Removing the item removes its view
+	im.RemoveItem(item.Path())
+	if _, ok := im.GetItem(view.Path()); ok {
+		t.Error("TOTP view still registered after its item was removed")
+	}
+}
+
+func TestItem_GetTOTP_NoKey(t *testing.T) {
+	im := NewItemManager(nil, nil, nil)
+	im.exportFunc = func(*Item) error { return nil }
+	item, err := im.GetOrCreateItem(&bitwarden.Item{ID: "item-2", Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, dbusErr := item.GetTOTP(""); dbusErr == nil || dbusErr.Name != ErrNotSupported {
+		t.Errorf("GetTOTP() error = %v, want %s", dbusErr, ErrNotSupported)
+	}
+}
+
+func TestItem_FieldView(t *testing.T) {
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := NewItemManager(nil, nil, sm)
+	im.exportFunc = func(*Item) error { return nil }
+
+	password := "s3cret"
+	bwItem := &bitwarden.Item{
+		ID:     "item-3",
+		Type:   bitwarden.ItemTypeLogin,
+		Login:  &bitwarden.Login{Password: &password},
+		Fields: []bitwarden.Field{{Name: "api token", Value: "tok", Type: 1}},
+	}
+	item, err := im.GetOrCreateItem(bwItem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := im.GetOrCreateSecretView(bwItem, nil, "api token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Path() != item.Path()+"/field_api_20token" {
+		t.Errorf("view path = %s", view.Path())
+	}
+	if secret, err := view.GetSecret("", session.Path()); err != nil || string(secret.Value) != "tok" {
+		t.Errorf("view GetSecret() = %q, %v, want tok", secret.Value, err)
+	}
+
+	im.RemoveItem(item.Path())
+	if _, ok := im.GetItem(view.Path()); ok {
+		t.Error("field view still registered after its item was removed")
+	}
+}
@@ -0,0 +1,170 @@
+package dbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+	"github.com/joe/bitwarden-keyring/internal/mapping"
+)
+
+// portalSecretSize is the length of generated app secrets in bytes
+const portalSecretSize = 64
+
+// Response codes of xdg-desktop-portal backends
+const (
+	portalResponseSuccess   uint32 = 0
+	portalResponseCancelled uint32 = 1
+	portalResponseFailed    uint32 = 2
+)
+
+// portalSecret implements org.freedesktop.impl.portal.Secret, the backend
+// xdg-desktop-portal asks for the master secret of a sandboxed app. Each app
+// gets a random secret stored as a secure note in the vault, which the app
+// then uses to encrypt its own keyring file inside the sandbox.
+type portalSecret struct {
+	bwClient *bitwarden.Client
+	mu       sync.Mutex // serializes find-or-create so an app gets one item
+
+	// portalOwner returns the unique name owning PortalDesktopBusName;
+	// replaceable in tests
+	portalOwner func() (string, error)
+}
+
+// RetrieveSecret writes the app's secret to fd (D-Bus method). Only
+// xdg-desktop-portal may call it: it vouches for appID, which anyone else
+// could choose freely to read another app's secret.
+func (p *portalSecret) RetrieveSecret(sender dbus.Sender, handle dbus.ObjectPath, appID string, fd dbus.UnixFD, options map[string]dbus.Variant) (uint32, map[string]dbus.Variant, *dbus.Error) {
+	log := logging.L.With("component", "portal")
+	results := map[string]dbus.Variant{}
+
+	f := os.NewFile(uintptr(fd), "portal-secret")
+	if f == nil {
+		return portalResponseFailed, results, nil
+	}
+	defer f.Close()
+
+	if owner, err := p.portalOwner(); err != nil || string(sender) != owner {
+		log.Warn("secret requested by a caller other than xdg-desktop-portal", "sender", sender, "app", appID, "error", err)
+		return portalResponseFailed, results, &dbus.Error{Name: ErrAccessDenied, Body: []interface{}{"Only xdg-desktop-portal may retrieve app secrets"}}
+	}
+
+	if appID == "" {
+		log.Warn("secret requested without an app ID")
+		return portalResponseFailed, results, nil
+	}
+
+	// The portal has no Prompt object to hand out, so unlock right here
+	ctx := bitwarden.WithUnlockPrompt(context.Background())
+
+	secret, err := p.appSecret(ctx, appID)
+	if err != nil {
+		if errors.Is(err, bitwarden.ErrUserCancelled) {
+			return portalResponseCancelled, results, nil
+		}
+		log.Warn("failed to retrieve app secret", "app", appID, "error", err)
+		return portalResponseFailed, results, nil
+	}
+
+	if _, err := f.Write(secret); err != nil {
+		log.Warn("failed to write app secret", "app", appID, "error", err)
+		return portalResponseFailed, results, nil
+	}
+
+	log.Info("app secret retrieved", "app", appID)
+	return portalResponseSuccess, results, nil
+}
+
+// appSecret returns the secret stored for appID, generating and storing one
+// the first time the app asks
+func (p *portalSecret) appSecret(ctx context.Context, appID string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items, err := p.bwClient.ListItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if mapping.PortalAppID(&items[i]) != appID {
+			continue
+		}
+		if items[i].Notes == nil {
+			return nil, fmt.Errorf("portal secret item %s is empty", items[i].ID)
+		}
+		return base64.StdEncoding.DecodeString(*items[i].Notes)
+	}
+
+	secret := make([]byte, portalSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(secret)
+
+	if _, err := p.bwClient.CreateItem(ctx, bitwarden.CreateItemRequest{
+		Type:       bitwarden.ItemTypeSecureNote,
+		Name:       "Flatpak secret: " + appID,
+		Notes:      &encoded,
+		SecureNote: &bitwarden.SecureNote{},
+		Fields:     []bitwarden.Field{{Name: mapping.PortalAppIDFieldName, Value: appID}},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	logging.L.With("component", "portal").Info("created app secret", "app", appID)
+	return secret, nil
+}
+
+// Get implements org.freedesktop.DBus.Properties.Get
+func (p *portalSecret) Get(iface, property string) (dbus.Variant, *dbus.Error) {
+	if iface != PortalSecretInterface || property != "version" {
+		return dbus.Variant{}, toDBusError(fmt.Errorf("unknown property: %s.%s", iface, property))
+	}
+	return dbus.MakeVariant(uint32(1)), nil
+}
+
+// Set implements org.freedesktop.DBus.Properties.Set
+func (p *portalSecret) Set(iface, property string, value dbus.Variant) *dbus.Error {
+	return toDBusError(fmt.Errorf("property %s is read-only", property))
+}
+
+// GetAll implements org.freedesktop.DBus.Properties.GetAll
+func (p *portalSecret) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != PortalSecretInterface {
+		return nil, toDBusError(fmt.Errorf("unknown interface: %s", iface))
+	}
+	return map[string]dbus.Variant{"version": dbus.MakeVariant(uint32(1))}, nil
+}
+
+// ExportPortal exports the Flatpak secret portal backend on the service's
+// connection. xdg-desktop-portal finds it through bitwarden-keyring.portal.
+func (s *Service) ExportPortal() error {
+	portal := &portalSecret{bwClient: s.bwClient, portalOwner: busNameOwner(s.conn, PortalDesktopBusName)}
+	if err := exportDBusObject(s.conn, portal, PortalPath, PortalSecretInterface, PortalIntrospectXML, true); err != nil {
+		return fmt.Errorf("failed to export secret portal: %w", err)
+	}
+	return nil
+}
+
+// busNameOwner returns a function looking up the unique name that currently
+// owns name on conn's bus
+func busNameOwner(conn *dbus.Conn, name string) func() (string, error) {
+	return func() (string, error) {
+		if conn == nil {
+			return "", fmt.Errorf("no bus connection")
+		}
+		var owner string
+		if err := conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, name).Store(&owner); err != nil {
+			return "", fmt.Errorf("failed to look up owner of %s: %w", name, err)
+		}
+		return owner, nil
+	}
+}
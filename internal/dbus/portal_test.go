@@ -0,0 +1,182 @@
+package dbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/mapping"
+)
+
+// newPortalTestServer returns an unlocked bw serve stand-in that keeps
+// created items in memory and counts creations
+func newPortalTestServer(t *testing.T) (*portalSecret, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	var items []bitwarden.Item
+	creates := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/status":
+			w.Write([]byte(`{"success":true,"data":{"template":{"status":"unlocked"}}}`))
+		case r.URL.Path == "/list/object/items":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"data":    map[string]interface{}{"object": "list", "data": items},
+			})
+		case r.URL.Path == "/object/item" && r.Method == "POST":
+			creates++
+			var item bitwarden.Item
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &item)
+			item.ID = fmt.Sprintf("item%d", creates)
+			items = append(items, item)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": item})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	owner := func() (string, error) { return ":1.7", nil }
+	return &portalSecret{bwClient: testBWClient(t, ts), portalOwner: owner}, &creates
+}
+
+func TestPortalSecret_appSecret(t *testing.T) {
+	p, creates := newPortalTestServer(t)
+	ctx := context.Background()
+
+	first, err := p.appSecret(ctx, "org.example.App")
+	if err != nil {
+		t.Fatalf("appSecret() error = %v", err)
+	}
+	if len(first) != portalSecretSize {
+		t.Errorf("secret is %d bytes, want %d", len(first), portalSecretSize)
+	}
+
+	again, err := p.appSecret(ctx, "org.example.App")
+	if err != nil {
+		t.Fatalf("appSecret() error = %v", err)
+	}
+	if !bytes.Equal(first, again) {
+		t.Error("second call returned a different secret")
+	}
+
+	other, err := p.appSecret(ctx, "org.example.Other")
+	if err != nil {
+		t.Fatalf("appSecret() error = %v", err)
+	}
+	if bytes.Equal(first, other) {
+		t.Error("two apps share a secret")
+	}
+
+	if *creates != 2 {
+		t.Errorf("created %d items, want 2", *creates)
+	}
+}
+
+func TestPortalSecret_RetrieveSecret(t *testing.T) {
+	p, _ := newPortalTestServer(t)
+
+	tests := []struct {
+		name         string
+		sender       dbus.Sender
+		appID        string
+		wantResponse uint32
+		wantErr      string
+		wantLen      int
+	}{
+		{"app", ":1.7", "org.example.App", portalResponseSuccess, "", portalSecretSize},
+		{"no app ID", ":1.7", "", portalResponseFailed, "", 0},
+		{"not the portal", ":1.42", "org.example.App", portalResponseFailed, ErrAccessDenied, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			// RetrieveSecret owns and closes the descriptor it is given
+			fd, err := syscall.Dup(int(w.Fd()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+
+			response, _, dbusErr := p.RetrieveSecret(tt.sender, "/request/1", tt.appID, dbus.UnixFD(fd), nil)
+			if (dbusErr == nil && tt.wantErr != "") || (dbusErr != nil && dbusErr.Name != tt.wantErr) {
+				t.Fatalf("RetrieveSecret() error = %v, want %q", dbusErr, tt.wantErr)
+			}
+			if response != tt.wantResponse {
+				t.Errorf("RetrieveSecret() response = %d, want %d", response, tt.wantResponse)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("read %d bytes, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestPortalSecret_HiddenFromCollections(t *testing.T) {
+	p, _ := newPortalTestServer(t)
+	ctx := context.Background()
+	if _, err := p.appSecret(ctx, "org.example.App"); err != nil {
+		t.Fatalf("appSecret() error = %v", err)
+	}
+
+	im := NewItemManager(nil, nil, nil)
+	im.exportFunc = func(*Item) error { return nil }
+	coll := (&CollectionManager{itemManager: im}).newCollection(DefaultCollectionPath, "default", "Default", "")
+	resolve := func(*bitwarden.Item) *Collection { return coll }
+
+	paths, err := getItemPaths(ctx, p.bwClient, im, resolve)
+	if err != nil {
+		t.Fatalf("getItemPaths() error = %v", err)
+	}
+	if len(paths) != 0 {
+		t.Errorf("getItemPaths() = %v, want no portal items", paths)
+	}
+
+	attrs := map[string]string{mapping.PortalAppIDFieldName: "org.example.App"}
+	paths, err = searchAndFilterItems(ctx, p.bwClient, im, resolve, attrs)
+	if err != nil {
+		t.Fatalf("searchAndFilterItems() error = %v", err)
+	}
+	if len(paths) != 0 {
+		t.Errorf("searchAndFilterItems(%v) = %v, want no portal items", attrs, paths)
+	}
+}
+
+func TestPortalSecret_Version(t *testing.T) {
+	p := &portalSecret{}
+	v, err := p.Get(PortalSecretInterface, "version")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.Value() != uint32(1) {
+		t.Errorf("version = %v, want 1", v.Value())
+	}
+}
@@ -28,11 +28,12 @@ type Prompt struct {
 
 // PromptManager manages prompt objects
 type PromptManager struct {
-	conn     *dbus.Conn
-	bwClient *bitwarden.Client
-	prompts  map[dbus.ObjectPath]*Prompt
-	counter  uint64
-	mu       sync.RWMutex
+	conn       *dbus.Conn
+	bwClient   *bitwarden.Client
+	prompts    map[dbus.ObjectPath]*Prompt
+	counter    uint64
+	mu         sync.RWMutex
+	onUnlocked func() // called after a prompt unlocked the vault; may be nil
 }
 
 // NewPromptManager creates a new prompt manager
@@ -92,7 +93,9 @@ func (p *Prompt) Path() dbus.ObjectPath {
 	return p.path
 }
 
-// Prompt triggers the prompt (D-Bus method)
+// Prompt triggers the prompt (D-Bus method).
+// The password dialog is attached to the window identified by windowID where
+// the prompt backend supports it.
 func (p *Prompt) Prompt(windowID string) *dbus.Error {
 	p.mu.Lock()
 	if p.done {
@@ -104,6 +107,7 @@ func (p *Prompt) Prompt(windowID string) *dbus.Error {
 	// Use sync.Once to ensure only one unlock goroutine starts
 	p.startOnce.Do(func() {
 		ctx, cancel := context.WithCancel(context.Background())
+		ctx = bitwarden.WithParentWindow(ctx, bitwarden.ParseParentWindow(windowID))
 		p.mu.Lock()
 		p.cancel = cancel
 		p.mu.Unlock()
@@ -142,9 +146,9 @@ func (p *Prompt) Dismiss() *dbus.Error {
 
 // doUnlock performs the actual unlock operation
 func (p *Prompt) doUnlock(ctx context.Context) {
-	// ListItems triggers auto-unlock in the client
-	// We don't need the result, just the unlock side-effect
-	_, err := p.bwClient.ListItems(ctx)
+	// ListItems triggers auto-unlock in the client, which prompts here even
+	// in strict mode. We don't need the result, just the unlock side-effect
+	_, err := p.bwClient.ListItems(bitwarden.WithUnlockPrompt(ctx))
 	if err != nil {
 		// Check if context was cancelled (dismiss was called)
 		if errors.Is(err, context.Canceled) {
@@ -166,6 +170,10 @@ func (p *Prompt) doUnlock(ctx context.Context) {
 	objects := p.objects
 	p.mu.Unlock()
 
+	if p.manager != nil && p.manager.onUnlocked != nil {
+		p.manager.onUnlocked()
+	}
+
 	// Emit Completed signal with the unlocked objects
 	p.completeOnce(false, objects)
 }
@@ -10,24 +10,32 @@ import (
 	"github.com/joe/bitwarden-keyring/internal/mapping"
 )
 
-// searchAndFilterItems searches for Bitwarden items matching the given attributes,
+// collectionResolver maps a Bitwarden item to the collection it is exported
+// under, or returns nil if the item is outside the caller's scope.
+type collectionResolver func(item *bitwarden.Item) *Collection
+
+// searchAndFilterItems searches the store for items matching the given attributes,
 // filters them, and returns their D-Bus object paths. This is a shared helper used
 // by both Service.searchItemsInternal and Collection.SearchItems.
 //
 // The function:
-// 1. Builds a URI from attributes for optimized search
-// 2. Searches (if URI exists) or lists all items
-// 3. Filters results using MatchesAttributes
-// 4. Creates/retrieves D-Bus Item objects for each match
+//  1. Builds a URI from attributes for optimized search
+//  2. Searches by URI when the lookup is restricted to logins, otherwise
+//     lists all items, since notes, cards and identities carry their
+//     service only in stored attributes and are not indexed by URI
+//  3. Filters results using MatchesAttributes and the collection resolver,
+//     leaving out portal secret items (see mapping.PortalAppID),
+//     matching bitwarden:folder against the resolved collection's folder
+//  4. Creates/retrieves D-Bus Item objects (secret views for bitwarden:secret)
 func searchAndFilterItems(
 	ctx context.Context,
-	bwClient *bitwarden.Client,
+	store itemStore,
 	itemManager *ItemManager,
-	coll *Collection,
+	resolve collectionResolver,
 	attrs map[string]string,
 ) ([]dbus.ObjectPath, error) {
-	if coll == nil {
-		return nil, fmt.Errorf("collection is nil")
+	if resolve == nil {
+		return nil, fmt.Errorf("collection resolver is nil")
 	}
 
 	uri := mapping.BuildURIFromAttributes(attrs)
@@ -35,21 +43,34 @@ func searchAndFilterItems(
 	var items []bitwarden.Item
 	var err error
 
-	if uri != "" {
-		items, err = bwClient.SearchItems(ctx, uri)
+	if uri != "" && attrs[mapping.AttrType] == mapping.TypeLogin {
+		items, err = store.SearchItems(ctx, uri)
 	} else {
-		items, err = bwClient.ListItems(ctx)
+		items, err = store.ListItems(ctx)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	selector := attrs[mapping.AttrSecret]
+	folder, byFolder := attrs[mapping.AttrFolder]
+
 	var results []dbus.ObjectPath
 	for _, item := range items {
+		if mapping.PortalAppID(&item) != "" {
+			continue // only served to xdg-desktop-portal
+		}
 		if mapping.MatchesAttributes(&item, attrs) {
+			coll := resolve(&item)
+			if coll == nil {
+				continue
+			}
+			if byFolder && !mapping.MatchPattern(coll.folderName(), folder) {
+				continue
+			}
 			itemCopy := item
-			dbusItem, err := itemManager.GetOrCreateItem(&itemCopy, coll)
+			dbusItem, err := itemManager.GetOrCreateSecretView(&itemCopy, coll, selector)
 			if err != nil {
 				continue
 			}
@@ -60,27 +81,32 @@ func searchAndFilterItems(
 	return results, nil
 }
 
-// getLoginItemPaths lists all login-type items from Bitwarden, ensures each is
+// getItemPaths lists all items of a supported type from the store that the
+// resolver places in scope, except portal secret items, ensures each is
 // exported as a D-Bus Item, and returns their object paths. Items that fail to
 // export are silently skipped.
-func getLoginItemPaths(
+func getItemPaths(
 	ctx context.Context,
-	bwClient *bitwarden.Client,
+	store itemStore,
 	itemManager *ItemManager,
-	coll *Collection,
+	resolve collectionResolver,
 ) ([]dbus.ObjectPath, error) {
-	if coll == nil {
-		return nil, fmt.Errorf("collection is nil")
+	if resolve == nil {
+		return nil, fmt.Errorf("collection resolver is nil")
 	}
 
-	items, err := bwClient.ListItems(ctx)
+	items, err := store.ListItems(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	paths := make([]dbus.ObjectPath, 0, len(items))
 	for _, item := range items {
-		if item.Type == bitwarden.ItemTypeLogin {
+		if mapping.ItemTypeName(&item) != "" && mapping.PortalAppID(&item) == "" {
+			coll := resolve(&item)
+			if coll == nil {
+				continue
+			}
 			itemCopy := item
 			dbusItem, err := itemManager.GetOrCreateItem(&itemCopy, coll)
 			if err != nil {
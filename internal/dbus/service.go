@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/godbus/dbus/v5"
 
+	"github.com/joe/bitwarden-keyring/internal/access"
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
 	"github.com/joe/bitwarden-keyring/internal/logging"
 )
@@ -28,6 +30,14 @@ func toDBusError(err error) *dbus.Error {
 		}
 	}
 
+	// Map a refused access confirmation to AccessDenied
+	if errors.Is(err, access.ErrDenied) {
+		return &dbus.Error{
+			Name: ErrAccessDenied,
+			Body: []interface{}{"Access denied"},
+		}
+	}
+
 	// Map ErrUserCancelled to PromptDismissed error
 	if errors.Is(err, bitwarden.ErrUserCancelled) {
 		return &dbus.Error{
@@ -36,6 +46,14 @@ func toDBusError(err error) *dbus.Error {
 		}
 	}
 
+	// Map a write that overlaps a change made elsewhere to Conflict
+	if errors.Is(err, bitwarden.ErrConflict) {
+		return &dbus.Error{
+			Name: ErrConflict,
+			Body: []interface{}{"Item was changed in the vault; re-read it and retry"},
+		}
+	}
+
 	// For all other errors (including APIError), return a generic "backend error"
 	// This prevents leaking HTTP bodies or other sensitive information
 	return &dbus.Error{
@@ -60,6 +78,7 @@ func NewService(conn *dbus.Conn, bwClient *bitwarden.Client) (*Service, error) {
 	sessionManager := NewSessionManager(conn)
 	itemManager := NewItemManager(conn, bwClient, sessionManager)
 	collectionManager := NewCollectionManager(conn, bwClient, itemManager, sessionManager)
+	collectionManager.aliasStore = NewAliasStore(DefaultAliasFilePath())
 	promptManager := NewPromptManager(conn, bwClient)
 
 	svc := &Service{
@@ -71,14 +90,56 @@ func NewService(conn *dbus.Conn, bwClient *bitwarden.Client) (*Service, error) {
 		promptManager:     promptManager,
 	}
 
+	// Show folder collections as soon as a prompt unlocks
+	promptManager.onUnlocked = func() {
+		collectionManager.RefreshFoldersIfUnlocked(context.Background())
+	}
+
 	// Ensure default collection exists
 	if _, err := collectionManager.EnsureDefaultCollection(); err != nil {
 		return nil, err
 	}
 
+	// The session collection keeps secrets in memory only
+	if _, err := collectionManager.EnsureSessionCollection(); err != nil {
+		return nil, err
+	}
+
+	// Restore aliases set by clients in earlier runs
+	if err := collectionManager.LoadAliases(); err != nil {
+		logging.L.With("component", "dbus").Warn("failed to load aliases, using defaults", "error", err)
+	}
+
+	// Export folder collections right away if the vault is already unlocked;
+	// otherwise they appear once it is unlocked, see NotifyLockChanged
+	collectionManager.RefreshFoldersIfUnlocked(context.Background())
+
 	return svc, nil
 }
 
+// SessionCount returns the number of open Secret Service sessions
+func (s *Service) SessionCount() int {
+	return s.sessionManager.SessionCount()
+}
+
+// RefreshFolders brings the folder collections up to date with the vault if
+// it is unlocked. It is the bitwarden.Client sync handler; property reads
+// don't refresh, so panels polling Collections cause no vault requests.
+func (s *Service) RefreshFolders() {
+	s.collectionManager.RefreshFoldersIfUnlocked(context.Background())
+}
+
+// ReloadAliases re-reads the alias file, see CollectionManager.ReloadAliases
+func (s *Service) ReloadAliases() error {
+	return s.collectionManager.ReloadAliases()
+}
+
+// SetAccessController makes secret reads subject to the given access
+// controller. It must be called before Export.
+func (s *Service) SetAccessController(c *access.Controller) {
+	s.itemManager.access = c
+}
+
 // Export exports the service to D-Bus
 func (s *Service) Export() error {
 	// Export the service interface
@@ -96,20 +157,15 @@ func (s *Service) Export() error {
 		return fmt.Errorf("failed to export introspection: %w", err)
 	}
 
-	// Export alias path for default collection
-	// This allows clients to access /org/freedesktop/secrets/aliases/default directly
-	aliasDefaultPath := dbus.ObjectPath(AliasPath + "default")
-	coll, _ := s.collectionManager.GetCollection(DefaultCollectionPath)
-	if coll != nil {
-		if err := s.conn.Export(coll, aliasDefaultPath, CollectionInterface); err != nil {
-			return fmt.Errorf("failed to export collection at alias path: %w", err)
-		}
-		if err := s.conn.Export(coll, aliasDefaultPath, PropertiesInterface); err != nil {
-			return fmt.Errorf("failed to export properties at alias path: %w", err)
-		}
-		if err := s.conn.Export(introspectable(CollectionIntrospectXML), aliasDefaultPath, "org.freedesktop.DBus.Introspectable"); err != nil {
-			return fmt.Errorf("failed to export introspection at alias path: %w", err)
-		}
+	// Export gnome-keyring's management interface next to the service
+	if err := s.conn.Export(&gnomeKeyring{svc: s}, ServicePath, GnomeKeyringInterface); err != nil {
+		return fmt.Errorf("failed to export %s: %w", GnomeKeyringInterface, err)
+	}
+
+	// Export alias paths such as /org/freedesktop/secrets/aliases/default
+	// so clients can address collections through their aliases directly
+	if err := s.collectionManager.ExportAliases(); err != nil {
+		return err
 	}
 
 	// Request the bus name
@@ -146,14 +202,43 @@ func (s *Service) OpenSession(algorithm string, input dbus.Variant) (dbus.Varian
 	return output, session.Path(), nil
 }
 
-// CreateCollection creates a new collection (D-Bus method)
+// CreateCollection creates a new collection (D-Bus method).
+// Each collection is backed by a Bitwarden folder named after the requested
+// label; requests without a label resolve to the default collection. If the
+// alias already points at a collection that collection is returned, otherwise
+// the alias is set to the returned collection.
 func (s *Service) CreateCollection(properties map[string]dbus.Variant, alias string) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
-	// We only support the default collection for now
-	coll, err := s.collectionManager.EnsureDefaultCollection()
+	label := ""
+	if labelVar, ok := properties[PropCollLabel]; ok {
+		if l, ok := labelVar.Value().(string); ok {
+			label = l
+		}
+	}
+
+	// An alias that already resolves to a collection wins over creating a new one
+	if alias != "" {
+		if existing := s.collectionManager.ReadAlias(alias); existing != NoPrompt {
+			return existing, NoPrompt, nil
+		}
+	}
+
+	var coll *Collection
+	var err error
+	if label == "" {
+		coll, err = s.collectionManager.EnsureDefaultCollection()
+	} else {
+		coll, err = s.collectionManager.CreateFolderCollection(context.Background(), label)
+	}
 	if err != nil {
 		return NoPrompt, NoPrompt, toDBusError(err)
 	}
 
+	if alias != "" {
+		if err := s.SetAlias(alias, coll.Path()); err != nil {
+			return NoPrompt, NoPrompt, err
+		}
+	}
+
 	return coll.Path(), NoPrompt, nil
 }
 
@@ -168,9 +253,20 @@ func (s *Service) SearchItems(attributes map[string]string) ([]dbus.ObjectPath,
 	}
 
 	if locked {
-		// When locked, return the collection as "locked" so clients can unlock it
-		// We can't enumerate individual items, but clients can call Unlock on the collection
-		return nil, []dbus.ObjectPath{DefaultCollectionPath}, nil
+		// When locked, return the vault collections as "locked" so clients can unlock them
+		// We can't enumerate individual items, but clients can call Unlock on a collection.
+		// Session items never lock and are still returned as unlocked.
+		sessionItems, err := s.searchSessionItems(ctx, attributes)
+		if err != nil {
+			return nil, nil, toDBusError(err)
+		}
+		var lockedPaths []dbus.ObjectPath
+		for _, path := range s.collectionManager.GetCollectionPaths() {
+			if path != SessionCollectionPath {
+				lockedPaths = append(lockedPaths, path)
+			}
+		}
+		return sessionItems, lockedPaths, nil
 	}
 
 	// Return items in unlocked, empty locked
@@ -182,13 +278,45 @@ func (s *Service) SearchItems(attributes map[string]string) ([]dbus.ObjectPath,
 	return items, nil, nil
 }
 
-// searchItemsInternal performs the actual search
+// searchItemsInternal performs the actual search across all collections
 func (s *Service) searchItemsInternal(ctx context.Context, attributes map[string]string) ([]dbus.ObjectPath, error) {
-	coll, err := s.collectionManager.EnsureDefaultCollection()
+	if _, err := s.collectionManager.EnsureDefaultCollection(); err != nil {
+		return nil, err
+	}
+	results, err := searchAndFilterItems(ctx, s.bwClient, s.itemManager, s.collectionManager.CollectionForItem, attributes)
 	if err != nil {
 		return nil, err
 	}
-	return searchAndFilterItems(ctx, s.bwClient, s.itemManager, coll, attributes)
+
+	sessionItems, err := s.searchSessionItems(ctx, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return append(results, sessionItems...), nil
+}
+
+// searchSessionItems searches the in-memory session collection
+func (s *Service) searchSessionItems(ctx context.Context, attributes map[string]string) ([]dbus.ObjectPath, error) {
+	coll, ok := s.collectionManager.GetCollection(SessionCollectionPath)
+	if !ok {
+		return nil, nil
+	}
+	return searchAndFilterItems(ctx, coll.store(), s.itemManager, coll.scope, attributes)
+}
+
+// inSessionCollection reports whether path is the session collection or one of its items
+func inSessionCollection(path dbus.ObjectPath) bool {
+	return path == SessionCollectionPath || strings.HasPrefix(string(path), string(SessionCollectionPath)+"/")
+}
+
+// allInSessionCollection reports whether every path belongs to the session collection
+func allInSessionCollection(paths []dbus.ObjectPath) bool {
+	for _, path := range paths {
+		if !inSessionCollection(path) {
+			return false
+		}
+	}
+	return true
 }
 
 // Unlock unlocks the specified objects (D-Bus method)
@@ -201,7 +329,8 @@ func (s *Service) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.Obj
 		return nil, NoPrompt, toDBusError(err)
 	}
 
-	if !locked {
+	// Session objects are never locked, so no prompt is needed for them alone
+	if !locked || allInSessionCollection(objects) {
 		// Already unlocked, return all objects
 		return objects, NoPrompt, nil
 	}
@@ -215,6 +344,36 @@ func (s *Service) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.Obj
 	return nil, prompt.Path(), nil
 }
 
+// NotifyLockChanged tells clients that the vault was locked or unlocked:
+// every vault-backed collection and exported item emits PropertiesChanged for
+// Locked, and each collection also emits CollectionChanged. On unlock the
+// folder collections are refreshed first. It is the bitwarden.Client lock
+// change handler.
+func (s *Service) NotifyLockChanged(locked bool) {
+	if !locked {
+		s.RefreshFolders()
+	}
+	changed := map[string]dbus.Variant{"Locked": dbus.MakeVariant(locked)}
+	for _, path := range s.collectionManager.GetCollectionPaths() {
+		if path == SessionCollectionPath {
+			continue
+		}
+		EmitPropertiesChanged(s.conn, path, CollectionInterface, changed)
+		EmitCollectionChanged(s.conn, path)
+	}
+	for _, objs := range s.itemManager.vaultItems() {
+		for _, item := range objs {
+			EmitPropertiesChanged(s.conn, item.path, ItemInterface, changed)
+		}
+	}
+}
+
+// ForgetVaultItems unexports the vault's items when the vault has locked,
+// see ItemManager.RemoveVaultItems
+func (s *Service) ForgetVaultItems() {
+	s.itemManager.RemoveVaultItems()
+}
+
 // Lock locks the specified objects (D-Bus method)
 func (s *Service) Lock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
 	ctx := context.Background()
@@ -226,8 +385,10 @@ func (s *Service) Lock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.Objec
 	return objects, NoPrompt, nil
 }
 
-// GetSecrets gets secrets for multiple items (D-Bus method)
-func (s *Service) GetSecrets(items []dbus.ObjectPath, session dbus.ObjectPath) (map[dbus.ObjectPath]Secret, *dbus.Error) {
+// GetSecrets gets secrets for multiple items (D-Bus method).
+// Items the caller is not allowed to read, or whose master password
+// re-prompt was not answered, are omitted from the result.
+func (s *Service) GetSecrets(sender dbus.Sender, items []dbus.ObjectPath, session dbus.ObjectPath) (map[dbus.ObjectPath]Secret, *dbus.Error) {
 	// Validate session
 	if _, dbusErr := s.sessionManager.GetSessionOrError(session); dbusErr != nil {
 		return nil, dbusErr
@@ -235,23 +396,42 @@ func (s *Service) GetSecrets(items []dbus.ObjectPath, session dbus.ObjectPath) (
 
 	ctx := context.Background()
 
-	// Trigger auto-unlock if needed
-	if err := s.bwClient.EnsureUnlocked(ctx); err != nil {
-		if errors.Is(err, bitwarden.ErrUserCancelled) || errors.Is(err, bitwarden.ErrVaultLocked) {
-			return nil, &dbus.Error{Name: ErrIsLocked, Body: []interface{}{"Vault is locked"}}
+	// Trigger auto-unlock if needed; session items are readable while locked
+	if !allInSessionCollection(items) {
+		if err := s.bwClient.EnsureUnlocked(ctx); err != nil {
+			if errors.Is(err, bitwarden.ErrUserCancelled) || errors.Is(err, bitwarden.ErrVaultLocked) {
+				return nil, &dbus.Error{Name: ErrIsLocked, Body: []interface{}{"Vault is locked"}}
+			}
+			return nil, toDBusError(err)
 		}
-		return nil, toDBusError(err)
 	}
 
-	secrets := make(map[dbus.ObjectPath]Secret)
-
+	found := make(map[dbus.ObjectPath]*Item, len(items))
+	targets := make([]access.Target, 0, len(items))
 	for _, itemPath := range items {
 		item, ok := s.itemManager.GetItem(itemPath)
 		if !ok {
 			continue
 		}
+		if _, dup := found[itemPath]; !dup {
+			targets = append(targets, item.accessTarget())
+		}
+		found[itemPath] = item
+	}
 
-		secret, dbusErr := item.GetSecret(session)
+	// One confirmation covers the whole request; refusing it fails the call
+	if err := s.itemManager.access.CheckAll(string(sender), targets); err != nil {
+		return nil, toDBusError(err)
+	}
+
+	secrets := make(map[dbus.ObjectPath]Secret)
+	for itemPath, item := range found {
+		// Items whose re-prompt is dismissed are left out of the result
+		if err := item.verifyReprompt(); err != nil {
+			continue
+		}
+
+		secret, dbusErr := item.secret(session)
 		if dbusErr != nil {
 			continue
 		}
@@ -262,18 +442,27 @@ func (s *Service) GetSecrets(items []dbus.ObjectPath, session dbus.ObjectPath) (
 	return secrets, nil
 }
 
-// ReadAlias returns the collection for the given alias (D-Bus method)
+// ReadAlias returns the collection for the given alias (D-Bus method).
+// Unknown aliases resolve to "/".
 func (s *Service) ReadAlias(name string) (dbus.ObjectPath, *dbus.Error) {
-	if name == "default" {
-		return s.collectionManager.GetDefaultAlias(), nil
-	}
-	return NoPrompt, nil
+	return s.collectionManager.ReadAlias(name), nil
 }
 
-// SetAlias sets the collection for the given alias (D-Bus method)
+// SetAlias points the given alias at a collection, or removes it when the
+// collection is "/" (D-Bus method). Aliases persist across restarts.
 func (s *Service) SetAlias(name string, collection dbus.ObjectPath) *dbus.Error {
-	logging.L.With("component", "dbus").Info("SetAlias not implemented, ignoring", "alias", name, "collection", collection)
-	return nil
+	err := s.collectionManager.SetAlias(name, collection)
+	switch {
+	case err == nil:
+		logging.L.With("component", "dbus").Info("alias set", "alias", name, "collection", collection)
+		return nil
+	case errors.Is(err, errInvalidAlias):
+		return &dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs", Body: []interface{}{"Invalid alias name"}}
+	case errors.Is(err, errNoSuchCollection):
+		return &dbus.Error{Name: ErrNoSuchObject, Body: []interface{}{"No such collection"}}
+	default:
+		return toDBusError(err)
+	}
 }
 
 // Get implements org.freedesktop.DBus.Properties.Get
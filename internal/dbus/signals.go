@@ -8,7 +8,11 @@ import (
 )
 
 // emit emits a D-Bus signal with error logging.
+// It is a no-op without a connection, as for managers built in unit tests.
 func emit(conn *dbus.Conn, path dbus.ObjectPath, signalName string, args ...interface{}) {
+	if conn == nil {
+		return
+	}
 	if err := conn.Emit(path, signalName, args...); err != nil {
 		logging.L.With("component", "dbus").Warn("failed to emit signal", "signal", signalName, "error", err)
 	}
@@ -49,3 +53,9 @@ func EmitCollectionDeleted(conn *dbus.Conn, collectionPath dbus.ObjectPath) {
 func EmitCollectionChanged(conn *dbus.Conn, collectionPath dbus.ObjectPath) {
 	emit(conn, ServicePath, ServiceInterface+".CollectionChanged", collectionPath)
 }
+
+// EmitPropertiesChanged emits org.freedesktop.DBus.Properties.PropertiesChanged
+// on an object for properties of the given interface whose values changed.
+func EmitPropertiesChanged(conn *dbus.Conn, path dbus.ObjectPath, iface string, changed map[string]dbus.Variant) {
+	emit(conn, path, PropertiesInterface+".PropertiesChanged", iface, changed, []string{})
+}
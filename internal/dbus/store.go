@@ -0,0 +1,169 @@
+package dbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+// itemStore is the backend a collection reads and writes its items through.
+// *bitwarden.Client satisfies it for vault-backed collections; the session
+// collection uses a memoryStore.
+type itemStore interface {
+	ListItems(ctx context.Context) ([]bitwarden.Item, error)
+	SearchItems(ctx context.Context, searchURL string) ([]bitwarden.Item, error)
+	CreateItem(ctx context.Context, req bitwarden.CreateItemRequest) (*bitwarden.Item, error)
+	UpdateItem(ctx context.Context, id string, req bitwarden.CreateItemRequest) (*bitwarden.Item, error)
+	DeleteItem(ctx context.Context, id string) error
+	IsLockedSafe(ctx context.Context) bool
+}
+
+// memoryStore is an in-process itemStore. Items are never written to disk or
+// synced to Bitwarden and disappear when the daemon exits. It is never locked.
+type memoryStore struct {
+	items    map[string]*bitwarden.Item
+	modified time.Time // time of the last write, or creation of the store
+	mu       sync.RWMutex
+}
+
+// newMemoryStore creates an empty in-memory item store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		items:    make(map[string]*bitwarden.Item),
+		modified: time.Now().UTC(),
+	}
+}
+
+// ListItems returns copies of all stored items, oldest first
+func (m *memoryStore) ListItems(ctx context.Context) ([]bitwarden.Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := make([]bitwarden.Item, 0, len(m.items))
+	for _, item := range m.items {
+		c, err := item.Clone()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *c)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreationDate.Equal(items[j].CreationDate) {
+			return items[i].CreationDate.Before(items[j].CreationDate)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// SearchItems returns all items; callers narrow the results with
+// mapping.MatchesAttributes just as they do for vault search results.
+func (m *memoryStore) SearchItems(ctx context.Context, searchURL string) ([]bitwarden.Item, error) {
+	return m.ListItems(ctx)
+}
+
+// CreateItem stores a new item under a freshly generated ID
+func (m *memoryStore) CreateItem(ctx context.Context, req bitwarden.CreateItemRequest) (*bitwarden.Item, error) {
+	id, err := newMemoryItemID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	item := &bitwarden.Item{ID: id, CreationDate: now}
+	if err := applyRequest(item, req, now); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.items[id] = item
+	m.modified = now
+	m.mu.Unlock()
+
+	return item.Clone()
+}
+
+// UpdateItem replaces the mutable fields of a stored item
+func (m *memoryStore) UpdateItem(ctx context.Context, id string, req bitwarden.CreateItemRequest) (*bitwarden.Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[id]
+	if !ok {
+		return nil, fmt.Errorf("item not found: %s", id)
+	}
+	now := time.Now().UTC()
+	if err := applyRequest(item, req, now); err != nil {
+		return nil, err
+	}
+	m.modified = now
+	return item.Clone()
+}
+
+// DeleteItem removes an item from the store
+func (m *memoryStore) DeleteItem(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[id]; !ok {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	delete(m.items, id)
+	m.modified = time.Now().UTC()
+	return nil
+}
+
+// IsLockedSafe always reports false: in-memory items are never locked
+func (m *memoryStore) IsLockedSafe(ctx context.Context) bool {
+	return false
+}
+
+// modifiedUnix returns the time of the last write as a Unix timestamp
+func (m *memoryStore) modifiedUnix() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.modified.Unix()
+}
+
+// applyRequest copies the request's fields onto item and bumps its revision date
+func applyRequest(item *bitwarden.Item, req bitwarden.CreateItemRequest, now time.Time) error {
+	// Round-trip through JSON so the store never shares pointers with callers
+	var c bitwarden.CreateItemRequest
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+
+	item.Type = c.Type
+	item.Name = c.Name
+	item.Notes = c.Notes
+	item.Favorite = c.Favorite
+	item.Login = c.Login
+	item.SecureNote = c.SecureNote
+	item.Card = c.Card
+	item.Identity = c.Identity
+	item.SSHKey = c.SSHKey
+	item.Fields = c.Fields
+	item.Reprompt = c.Reprompt
+	item.RevisionDate = now
+	return nil
+}
+
+// newMemoryItemID returns a random 128-bit hex identifier
+func newMemoryItemID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate item ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,134 @@
+package dbus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+func TestMemoryStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	user, pass := "joe", "s3cret"
+	created, err := store.CreateItem(ctx, bitwarden.CreateItemRequest{
+		Type:  bitwarden.ItemTypeLogin,
+		Name:  "token",
+		Login: &bitwarden.Login{Username: &user, Password: &pass},
+	})
+	if err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+	if created.ID == "" || created.CreationDate.IsZero() {
+		t.Fatalf("CreateItem() = %+v, want ID and creation date set", created)
+	}
+
+	// Mutating the returned copy must not affect the stored item
+	*created.Login.Password = "changed"
+	items, err := store.ListItems(ctx)
+	if err != nil {
+		t.Fatalf("ListItems() error = %v", err)
+	}
+	if len(items) != 1 || *items[0].Login.Password != "s3cret" {
+		t.Fatalf("ListItems() = %+v, want stored password unchanged", items)
+	}
+
+	req := items[0].ToUpdateRequest()
+	newPass := "rotated"
+	req.Login.Password = &newPass
+	updated, err := store.UpdateItem(ctx, created.ID, req)
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if *updated.Login.Password != "rotated" {
+		t.Errorf("UpdateItem() password = %q, want %q", *updated.Login.Password, "rotated")
+	}
+
+	if err := store.DeleteItem(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+	if err := store.DeleteItem(ctx, created.ID); err == nil {
+		t.Error("DeleteItem() of a missing item should fail")
+	}
+	if _, err := store.UpdateItem(ctx, created.ID, req); err == nil {
+		t.Error("UpdateItem() of a missing item should fail")
+	}
+	if items, _ := store.ListItems(ctx); len(items) != 0 {
+		t.Errorf("ListItems() after delete = %d items, want 0", len(items))
+	}
+	if store.IsLockedSafe(ctx) {
+		t.Error("memory store should never be locked")
+	}
+}
+
+func TestSessionCollection_ItemLifecycle(t *testing.T) {
+	sm := NewSessionManager(nil)
+	sm.exportFunc = (&mockSessionExport{}).export
+	session, _, err := sm.CreateSession(AlgorithmPlain, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := NewItemManager(nil, nil, sm)
+	im.exportFunc = func(*Item) error { return nil }
+
+	cm := &CollectionManager{
+		collections:    make(map[dbus.ObjectPath]*Collection),
+		itemManager:    im,
+		sessionManager: sm,
+	}
+	coll := cm.newCollection(SessionCollectionPath, "session", "Session", "")
+	coll.memory = newMemoryStore()
+	cm.collections[SessionCollectionPath] = coll
+
+	props := map[string]dbus.Variant{
+		PropItemLabel:      dbus.MakeVariant("ci token"),
+		PropItemAttributes: dbus.MakeVariant(map[string]string{"service": "ci", "username": "bot"}),
+	}
+	itemPath, prompt, dbusErr := coll.CreateItem(props, Secret{Session: session.Path(), Value: []byte("tok")}, false)
+	if dbusErr != nil {
+		t.Fatalf("CreateItem() error = %v", dbusErr)
+	}
+	if prompt != NoPrompt {
+		t.Errorf("CreateItem() prompt = %s, want /", prompt)
+	}
+
+	found, dbusErr := coll.SearchItems(map[string]string{"service": "ci"})
+	if dbusErr != nil {
+		t.Fatalf("SearchItems() error = %v", dbusErr)
+	}
+	if len(found) != 1 || found[0] != itemPath {
+		t.Fatalf("SearchItems() = %v, want [%s]", found, itemPath)
+	}
+
+	item, ok := im.GetItem(itemPath)
+	if !ok {
+		t.Fatal("created item not registered with item manager")
+	}
+	secret, dbusErr := item.GetSecret("", session.Path())
+	if dbusErr != nil {
+		t.Fatalf("GetSecret() error = %v", dbusErr)
+	}
+	if string(secret.Value) != "tok" {
+		t.Errorf("GetSecret() = %q, want %q", secret.Value, "tok")
+	}
+
+	locked, dbusErr := item.Get(ItemInterface, "Locked")
+	if dbusErr != nil || locked.Value() != false {
+		t.Errorf("Locked = %v, %v; want false", locked, dbusErr)
+	}
+
+	if _, dbusErr := item.Delete(); dbusErr != nil {
+		t.Fatalf("Delete() error = %v", dbusErr)
+	}
+	if found, _ := coll.SearchItems(map[string]string{"service": "ci"}); len(found) != 0 {
+		t.Errorf("SearchItems() after delete = %v, want none", found)
+	}
+
+	if _, dbusErr := coll.Delete(); dbusErr == nil {
+		t.Error("deleting the session collection should fail")
+	}
+}
@@ -0,0 +1,149 @@
+package dbus
+
+import (
+	"context"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+	"github.com/joe/bitwarden-keyring/internal/mapping"
+)
+
+// ApplyItemChanges brings exported items up to date with changes made to the
+// vault outside the daemon and emits the matching Secret Service signals. It
+// is the bitwarden.Client item change handler.
+func (s *Service) ApplyItemChanges(changes bitwarden.ItemChanges) {
+	// Items may have moved to folders created since the last refresh
+	s.collectionManager.RefreshFoldersIfUnlocked(context.Background())
+	s.itemManager.applyChanges(changes, s.collectionManager.CollectionForItem)
+}
+
+// applyChanges updates, moves and unexports exported items for a cache diff
+// and emits ItemCreated, ItemChanged and ItemDeleted on their collections.
+// After a reload the cache can't tell new items from known ones, so only
+// the exported items are compared against it and no ItemCreated is emitted.
+func (im *ItemManager) applyChanges(changes bitwarden.ItemChanges, collectionFor func(*bitwarden.Item) *Collection) {
+	exported := im.vaultItems()
+
+	if changes.Reloaded {
+		current := make(map[string]*bitwarden.Item, len(changes.Added))
+		for i := range changes.Added {
+			current[changes.Added[i].ID] = &changes.Added[i]
+		}
+		for id, objs := range exported {
+			item, ok := current[id]
+			if !ok {
+				im.itemRemoved(objs[0].backingItem(), objs, collectionFor)
+				continue
+			}
+			if !objs[0].backingItem().RevisionDate.Equal(item.RevisionDate) {
+				im.itemUpdated(item, objs, collectionFor)
+			}
+		}
+		return
+	}
+
+	for i := range changes.Added {
+		item := &changes.Added[i]
+		if coll := collectionFor(item); coll != nil && served(item) {
+			EmitItemCreated(im.conn, coll.path, ItemPathInCollection(coll.path, item.ID))
+		}
+	}
+	for i := range changes.Updated {
+		im.itemUpdated(&changes.Updated[i], exported[changes.Updated[i].ID], collectionFor)
+	}
+	for i := range changes.Removed {
+		im.itemRemoved(&changes.Removed[i], exported[changes.Removed[i].ID], collectionFor)
+	}
+}
+
+// itemUpdated gives the exported objects of an item its new data and emits
+// ItemChanged. Objects left in a collection the item no longer belongs to are
+// unexported instead, and the move is signalled as ItemDeleted on the old
+// collection and ItemCreated on the new one.
+func (im *ItemManager) itemUpdated(item *bitwarden.Item, objs []*Item, collectionFor func(*bitwarden.Item) *Collection) {
+	coll := collectionFor(item)
+	if coll == nil {
+		return
+	}
+
+	var from *Collection
+	for _, obj := range objs {
+		if obj.collection != coll {
+			from = obj.collection
+			im.RemoveItem(obj.path)
+			continue
+		}
+		updated, err := item.Clone()
+		if err != nil {
+			logging.L.With("component", "dbus").Warn("failed to copy updated item", "id", item.ID, "error", err)
+			continue
+		}
+		obj.mu.Lock()
+		obj.bwItem = updated
+		obj.mu.Unlock()
+	}
+
+	if !served(item) {
+		return
+	}
+	path := ItemPathInCollection(coll.path, item.ID)
+	if from != nil {
+		EmitItemDeleted(im.conn, from.path, ItemPathInCollection(from.path, item.ID))
+		EmitItemCreated(im.conn, coll.path, path)
+		return
+	}
+	EmitItemChanged(im.conn, coll.path, path)
+}
+
+// itemRemoved unexports the objects of a deleted item and emits ItemDeleted
+// on the collection it was in
+func (im *ItemManager) itemRemoved(old *bitwarden.Item, objs []*Item, collectionFor func(*bitwarden.Item) *Collection) {
+	coll := collectionFor(old)
+	for _, obj := range objs {
+		coll = obj.collection
+		im.RemoveItem(obj.path)
+	}
+	if coll != nil && served(old) {
+		EmitItemDeleted(im.conn, coll.path, ItemPathInCollection(coll.path, old.ID))
+	}
+}
+
+// vaultItems returns the exported objects backed by vault items, including
+// secret views, grouped by Bitwarden item ID. Items of the in-memory session
+// collection and items still being exported are left out.
+func (im *ItemManager) vaultItems() map[string][]*Item {
+	im.mu.RLock()
+	entries := make([]*itemEntry, 0, len(im.items))
+	for _, entry := range im.items {
+		entries = append(entries, entry)
+	}
+	im.mu.RUnlock()
+
+	byID := make(map[string][]*Item)
+	for _, entry := range entries {
+		select {
+		case <-entry.ready:
+		default:
+			continue
+		}
+		if entry.err != nil || entry.item.collection == nil || entry.item.collection.memory != nil {
+			continue
+		}
+		id := entry.item.ID()
+		byID[id] = append(byID[id], entry.item)
+	}
+	return byID
+}
+
+// backingItem returns the Bitwarden item behind an exported item
+func (i *Item) backingItem() *bitwarden.Item {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.bwItem
+}
+
+// served reports whether the Secret Service lists items like this one, so
+// that signals about it name an object clients can look up
+func served(item *bitwarden.Item) bool {
+	return mapping.ItemTypeName(item) != ""
+}
@@ -0,0 +1,93 @@
+package dbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+func TestItemManager_ApplyChanges(t *testing.T) {
+	im := &ItemManager{items: make(map[dbus.ObjectPath]*itemEntry)}
+	im.exportFunc = func(*Item) error { return nil }
+
+	cm := &CollectionManager{collections: make(map[dbus.ObjectPath]*Collection)}
+	def := cm.newCollection(DefaultCollectionPath, "default", "Default", "")
+	workPath := CollectionPathFromFolderID("folder1")
+	work := cm.newCollection(workPath, "work", "Work", "folder1")
+	session := cm.newCollection(SessionCollectionPath, "session", "Session", "")
+	session.memory = newMemoryStore()
+	cm.collections[DefaultCollectionPath] = def
+	cm.collections[workPath] = work
+
+	rev := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	note := func(id, name string, folderID *string) bitwarden.Item {
+		return bitwarden.Item{ID: id, Type: bitwarden.ItemTypeSecureNote, Name: name, FolderID: folderID, RevisionDate: rev}
+	}
+	for _, item := range []bitwarden.Item{note("edited", "Old", nil), note("moved", "Moved", nil), note("deleted", "Gone", nil)} {
+		if _, err := im.GetOrCreateItem(&item, def); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept := note("kept", "Kept", nil)
+	if _, err := im.GetOrCreateItem(&kept, session); err != nil {
+		t.Fatal(err)
+	}
+
+	folderID := "folder1"
+	edited := note("edited", "New", nil)
+	moved := note("moved", "Moved", &folderID)
+	im.applyChanges(bitwarden.ItemChanges{
+		Added:   []bitwarden.Item{note("added", "Added", nil)},
+		Updated: []bitwarden.Item{edited, moved},
+		Removed: []bitwarden.Item{note("deleted", "Gone", nil), note("kept", "Kept", nil)},
+	}, cm.CollectionForItem)
+
+	if item, ok := im.GetItem(ItemPathInCollection(DefaultCollectionPath, "edited")); !ok || item.backingItem().Name != "New" {
+		t.Errorf("edited item = %v (exported %v), want name New", item, ok)
+	}
+	if _, ok := im.GetItem(ItemPathInCollection(DefaultCollectionPath, "moved")); ok {
+		t.Error("moved item is still exported in its old collection")
+	}
+	if _, ok := im.GetItem(ItemPathInCollection(DefaultCollectionPath, "deleted")); ok {
+		t.Error("deleted item is still exported")
+	}
+	if _, ok := im.GetItem(ItemPathInCollection(SessionCollectionPath, "kept")); !ok {
+		t.Error("session item was removed by a vault change")
+	}
+}
+
+func TestItemManager_ApplyChanges_Reload(t *testing.T) {
+	im := &ItemManager{items: make(map[dbus.ObjectPath]*itemEntry)}
+	im.exportFunc = func(*Item) error { return nil }
+	def := &Collection{path: DefaultCollectionPath}
+	collectionFor := func(*bitwarden.Item) *Collection { return def }
+
+	rev := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"same", "changed", "gone"} {
+		if _, err := im.GetOrCreateItem(&bitwarden.Item{ID: id, Name: id, RevisionDate: rev}, def); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	im.applyChanges(bitwarden.ItemChanges{
+		Reloaded: true,
+		Added: []bitwarden.Item{
+			{ID: "same", Name: "same", RevisionDate: rev},
+			{ID: "changed", Name: "changed2", RevisionDate: rev.Add(time.Minute)},
+			{ID: "new", Name: "new", RevisionDate: rev},
+		},
+	}, collectionFor)
+
+	if item, ok := im.GetItem(ItemPathFromID("changed")); !ok || item.backingItem().Name != "changed2" {
+		t.Errorf("changed item not refreshed: %v", item)
+	}
+	if _, ok := im.GetItem(ItemPathFromID("gone")); ok {
+		t.Error("vanished item is still exported")
+	}
+	if _, ok := im.GetItem(ItemPathFromID("new")); ok {
+		t.Error("reload exported an item nobody asked for")
+	}
+}
@@ -14,6 +14,7 @@ const (
 	SessionPath    = "/org/freedesktop/secrets/session/"
 	PromptPath     = "/org/freedesktop/secrets/prompt/"
 	AliasPath      = "/org/freedesktop/secrets/aliases/"
+	PortalPath     = dbus.ObjectPath("/org/freedesktop/portal/desktop")
 
 	// Interface names
 	ServiceInterface    = "org.freedesktop.Secret.Service"
@@ -23,10 +24,26 @@ const (
 	PromptInterface     = "org.freedesktop.Secret.Prompt"
 	PropertiesInterface = "org.freedesktop.DBus.Properties"
 
+	// gnome-keyring's private interface on the service object, used by
+	// Seahorse and other keyring management tools
+	GnomeKeyringInterface = "org.gnome.keyring.InternalUnsupportedGuiltRiddenInterface"
+
+	// bitwarden-keyring's own item interface, providing GetTOTP
+	KeyringItemInterface = "io.github.joe.BitwardenKeyring.Item"
+
+	// xdg-desktop-portal backend interface for Flatpak app secrets
+	PortalSecretInterface = "org.freedesktop.impl.portal.Secret"
+
+	// Bus name of xdg-desktop-portal, the only caller of the portal backend
+	PortalDesktopBusName = "org.freedesktop.portal.Desktop"
+
 	// Error names
 	ErrIsLocked     = "org.freedesktop.Secret.Error.IsLocked"
 	ErrNoSession    = "org.freedesktop.Secret.Error.NoSession"
 	ErrNoSuchObject = "org.freedesktop.Secret.Error.NoSuchObject"
+	ErrAccessDenied = "org.freedesktop.DBus.Error.AccessDenied"
+	ErrNotSupported = "org.freedesktop.DBus.Error.NotSupported"
+	ErrConflict     = "io.github.joe.BitwardenKeyring.Error.Conflict"
 
 	// Property keys for D-Bus properties
 	PropItemLabel      = "org.freedesktop.Secret.Item.Label"
@@ -49,6 +66,14 @@ var NoPrompt = dbus.ObjectPath("/")
 // DefaultCollectionPath is the path to the default collection
 var DefaultCollectionPath = dbus.ObjectPath(CollectionPath + "default")
 
+// SessionCollectionPath is the path of the in-memory session collection
+var SessionCollectionPath = dbus.ObjectPath(CollectionPath + "session")
+
+// CollectionPathFromFolderID creates a collection path from a Bitwarden folder ID
+func CollectionPathFromFolderID(id string) dbus.ObjectPath {
+	return dbus.ObjectPath(CollectionPath + SanitizeID(id))
+}
+
 // SanitizeID removes hyphens from UUIDs to make them valid D-Bus object path components
 // D-Bus object paths can only contain [A-Za-z0-9_]
 func SanitizeID(id string) string {
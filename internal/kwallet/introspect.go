@@ -0,0 +1,234 @@
+package kwallet
+
+// IntrospectXML describes the kwalletd interface as served here
+const IntrospectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+"http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="org.kde.KWallet">
+    <method name="isEnabled">
+      <arg name="enabled" type="b" direction="out"/>
+    </method>
+    <method name="open">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="wId" type="x" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="handle" type="i" direction="out"/>
+    </method>
+    <method name="openAsync">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="wId" type="x" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="handleSession" type="b" direction="in"/>
+      <arg name="transactionId" type="i" direction="out"/>
+    </method>
+    <method name="close">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="force" type="b" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="result" type="i" direction="out"/>
+    </method>
+    <method name="isOpen">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="open" type="b" direction="out"/>
+    </method>
+    <method name="isOpen">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="open" type="b" direction="out"/>
+    </method>
+    <method name="sync">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+    </method>
+    <method name="wallets">
+      <arg name="wallets" type="as" direction="out"/>
+    </method>
+    <method name="localWallet">
+      <arg name="wallet" type="s" direction="out"/>
+    </method>
+    <method name="networkWallet">
+      <arg name="wallet" type="s" direction="out"/>
+    </method>
+    <method name="users">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="users" type="as" direction="out"/>
+    </method>
+    <method name="folderList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="folders" type="as" direction="out"/>
+    </method>
+    <method name="hasFolder">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="exists" type="b" direction="out"/>
+    </method>
+    <method name="createFolder">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="created" type="b" direction="out"/>
+    </method>
+    <method name="removeFolder">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="removed" type="b" direction="out"/>
+    </method>
+    <method name="entryList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="entries" type="as" direction="out"/>
+    </method>
+    <method name="hasEntry">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="exists" type="b" direction="out"/>
+    </method>
+    <method name="entryType">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="type" type="i" direction="out"/>
+    </method>
+    <method name="readEntry">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="value" type="ay" direction="out"/>
+    </method>
+    <method name="readMap">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="value" type="ay" direction="out"/>
+    </method>
+    <method name="readPassword">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="value" type="s" direction="out"/>
+    </method>
+    <method name="readMapList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="values" type="a{sv}" direction="out"/>
+    </method>
+    <method name="readPasswordList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="values" type="a{sv}" direction="out"/>
+    </method>
+    <method name="entriesList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="values" type="a{sv}" direction="out"/>
+    </method>
+    <method name="mapList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="values" type="a{sv}" direction="out"/>
+    </method>
+    <method name="passwordList">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="values" type="a{sv}" direction="out"/>
+    </method>
+    <method name="writeEntry">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="value" type="ay" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="result" type="i" direction="out"/>
+    </method>
+    <method name="writeMap">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="value" type="ay" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="result" type="i" direction="out"/>
+    </method>
+    <method name="writePassword">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="value" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="result" type="i" direction="out"/>
+    </method>
+    <method name="removeEntry">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="result" type="i" direction="out"/>
+    </method>
+    <method name="renameEntry">
+      <arg name="handle" type="i" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="oldName" type="s" direction="in"/>
+      <arg name="newName" type="s" direction="in"/>
+      <arg name="appid" type="s" direction="in"/>
+      <arg name="result" type="i" direction="out"/>
+    </method>
+    <method name="folderDoesNotExist">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="missing" type="b" direction="out"/>
+    </method>
+    <method name="keyDoesNotExist">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="folder" type="s" direction="in"/>
+      <arg name="key" type="s" direction="in"/>
+      <arg name="missing" type="b" direction="out"/>
+    </method>
+    <method name="disconnectApplication">
+      <arg name="wallet" type="s" direction="in"/>
+      <arg name="application" type="s" direction="in"/>
+      <arg name="disconnected" type="b" direction="out"/>
+    </method>
+    <method name="closeAllWallets"/>
+    <signal name="walletOpened">
+      <arg name="wallet" type="s"/>
+    </signal>
+    <signal name="walletAsyncOpened">
+      <arg name="tId" type="i"/>
+      <arg name="handle" type="i"/>
+    </signal>
+    <signal name="walletClosed">
+      <arg name="wallet" type="s"/>
+    </signal>
+    <signal name="walletClosed">
+      <arg name="handle" type="i"/>
+    </signal>
+    <signal name="allWalletsClosed"/>
+    <signal name="folderListUpdated">
+      <arg name="wallet" type="s"/>
+    </signal>
+    <signal name="folderUpdated">
+      <arg name="wallet" type="s"/>
+      <arg name="folder" type="s"/>
+    </signal>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect">
+      <arg name="xml" type="s" direction="out"/>
+    </method>
+  </interface>
+</node>`
@@ -0,0 +1,236 @@
+// Package kwallet serves the KWallet D-Bus API of kwalletd5 and kwalletd6
+// from the Bitwarden vault, for KDE applications that don't use the Secret
+// Service. Wallet folders are Bitwarden folders under FolderPrefix and
+// entries are items in them.
+package kwallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/access"
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// Interface is the D-Bus interface of kwalletd
+const Interface = "org.kde.KWallet"
+
+// DefaultWallet is the wallet KDE applications use unless configured otherwise
+const DefaultWallet = "kdewallet"
+
+// daemon is one kwalletd generation KDE applications may look for
+type daemon struct {
+	busName string
+	path    dbus.ObjectPath
+}
+
+// daemons lists the bus names and paths served, KDE Frameworks 5 and 6
+var daemons = []daemon{
+	{"org.kde.kwalletd5", "/modules/kwalletd5"},
+	{"org.kde.kwalletd6", "/modules/kwalletd6"},
+}
+
+// asyncOpenDelay holds back walletAsyncOpened so it arrives after the reply
+// carrying the transaction ID, which clients need to recognize the signal
+const asyncOpenDelay = 50 * time.Millisecond
+
+// walletHandle is a wallet opened by an application. Handles are small
+// sequential numbers, so each is bound to the unique bus name of the
+// connection that opened it and is rejected on any other connection.
+type walletHandle struct {
+	wallet string
+	appID  string
+	sender string // unique bus name of the opener; empty for in-process calls
+}
+
+// Service is the KWallet frontend
+type Service struct {
+	conn   *dbus.Conn
+	store  *store
+	access *access.Controller // nil unless reads are confirmed
+
+	// verifyReprompt is bitwarden.Client.VerifyReprompt; replaceable in tests
+	verifyReprompt func(ctx context.Context, item *bitwarden.Item) error
+
+	mu              sync.Mutex
+	handles         map[int32]walletHandle
+	nextHandle      int32
+	nextTransaction int32
+}
+
+// NewService creates a KWallet frontend backed by bwClient
+func NewService(conn *dbus.Conn, bwClient *bitwarden.Client) *Service {
+	return &Service{
+		conn:           conn,
+		store:          &store{client: bwClient},
+		verifyReprompt: bwClient.VerifyReprompt,
+		handles:        make(map[int32]walletHandle),
+	}
+}
+
+// SetAccessController makes entry reads subject to the given access
+// controller, like Secret Service reads. It must be called before Export.
+func (s *Service) SetAccessController(c *access.Controller) {
+	s.access = c
+}
+
+// Export exports the KWallet interface and requests the kwalletd bus names.
+// It fails if another wallet daemon owns one of them.
+func (s *Service) Export() error {
+	w := &kwalletd{svc: s}
+	for _, d := range daemons {
+		if err := s.conn.ExportWithMap(w, methodNames, d.path, Interface); err != nil {
+			return fmt.Errorf("failed to export %s: %w", d.path, err)
+		}
+		if err := s.conn.Export(introspectable(IntrospectXML), d.path, "org.freedesktop.DBus.Introspectable"); err != nil {
+			return fmt.Errorf("failed to export introspection: %w", err)
+		}
+	}
+
+	for _, d := range daemons {
+		reply, err := s.conn.RequestName(d.busName, dbus.NameFlagDoNotQueue)
+		if err != nil {
+			return fmt.Errorf("failed to request bus name: %w", err)
+		}
+		if reply != dbus.RequestNameReplyPrimaryOwner {
+			return fmt.Errorf("bus name %s already taken", d.busName)
+		}
+	}
+	return nil
+}
+
+// CloseAll closes every open wallet, as kwalletd does when wallets lock.
+// Applications are told through walletClosed and reopen the wallet, which
+// asks for the master password again.
+func (s *Service) CloseAll() {
+	s.mu.Lock()
+	handles := s.handles
+	s.handles = make(map[int32]walletHandle)
+	s.mu.Unlock()
+
+	if len(handles) == 0 {
+		return
+	}
+	for handle, h := range handles {
+		s.emit("walletClosed", handle)
+		s.emit("walletClosed", h.wallet)
+	}
+	s.emit("allWalletsClosed")
+}
+
+// open returns a handle to wallet for appID, usable by sender only, after
+// making sure the vault is unlocked, or -1 if it could not be unlocked
+func (s *Service) open(wallet, appID, sender string) int32 {
+	log := logging.L.With("component", "kwallet")
+	if !validWalletName(wallet) {
+		log.Warn("invalid wallet name", "wallet", wallet, "app", appID)
+		return -1
+	}
+
+	// Reading the folders unlocks the vault, asking for the master password
+	if _, err := s.store.folderList(requestContext(), wallet); err != nil {
+		log.Warn("failed to open wallet", "wallet", wallet, "app", appID, "error", err)
+		return -1
+	}
+
+	s.mu.Lock()
+	s.nextHandle++
+	handle := s.nextHandle
+	s.handles[handle] = walletHandle{wallet: wallet, appID: appID, sender: sender}
+	s.mu.Unlock()
+
+	log.Info("wallet opened", "wallet", wallet, "app", appID)
+	s.emit("walletOpened", wallet)
+	return handle
+}
+
+// wallet returns the wallet behind a handle that sender opened
+func (s *Service) wallet(handle int32, sender string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.handles[handle]
+	if !ok || h.sender != sender {
+		return "", false
+	}
+	return h.wallet, true
+}
+
+// isOpen reports whether any application has wallet open
+func (s *Service) isOpen(wallet string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.handles {
+		if h.wallet == wallet {
+			return true
+		}
+	}
+	return false
+}
+
+// close releases a handle that sender opened; it returns -1 for unknown
+// handles and those of other connections
+func (s *Service) close(handle int32, sender string) int32 {
+	if _, ok := s.wallet(handle, sender); !ok {
+		return -1
+	}
+	return s.closeHandle(handle)
+}
+
+// closeHandle releases a handle whoever opened it; it returns -1 for
+// unknown handles
+func (s *Service) closeHandle(handle int32) int32 {
+	s.mu.Lock()
+	h, ok := s.handles[handle]
+	delete(s.handles, handle)
+	s.mu.Unlock()
+
+	if !ok {
+		return -1
+	}
+	if !s.isOpen(h.wallet) {
+		s.emit("walletClosed", h.wallet)
+	}
+	return 0
+}
+
+// emit sends a kwalletd signal from every exported path
+func (s *Service) emit(name string, args ...interface{}) {
+	if s.conn == nil {
+		return
+	}
+	for _, d := range daemons {
+		if err := s.conn.Emit(d.path, Interface+"."+name, args...); err != nil {
+			logging.L.With("component", "kwallet").Warn("failed to emit signal", "signal", name, "error", err)
+		}
+	}
+}
+
+// validWalletName rejects names that can't be part of a folder name
+func validWalletName(wallet string) bool {
+	for _, r := range wallet {
+		if r == '/' {
+			return false
+		}
+	}
+	return wallet != ""
+}
+
+// requestContext returns the context for vault requests. KWallet has no
+// prompt objects, so the master password is asked for when the vault is
+// locked even in strict unlock mode.
+func requestContext() context.Context {
+	return bitwarden.WithUnlockPrompt(context.Background())
+}
+
+// introspectable implements org.freedesktop.DBus.Introspectable
+type introspectable string
+
+// Introspect returns the introspection XML (D-Bus method)
+func (i introspectable) Introspect() (string, *dbus.Error) {
+	return string(i), nil
+}
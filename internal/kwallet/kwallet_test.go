@@ -0,0 +1,412 @@
+package kwallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/access"
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/mapping"
+)
+
+// fakeVault is an unlocked bw serve stand-in keeping folders and items in
+// memory
+type fakeVault struct {
+	mu      sync.Mutex
+	folders []bitwarden.Folder
+	items   []bitwarden.Item
+	nextID  int
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	reply := func(data interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": data})
+	}
+	list := func(data interface{}) {
+		reply(map[string]interface{}{"object": "list", "data": data})
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.URL.Path == "/status":
+		reply(map[string]interface{}{"template": map[string]string{"status": "unlocked"}})
+	case r.URL.Path == "/list/object/folders":
+		list(v.folders)
+	case r.URL.Path == "/object/folder" && r.Method == "POST":
+		var f bitwarden.Folder
+		json.Unmarshal(body, &f)
+		v.nextID++
+		f.ID = fmt.Sprintf("folder%d", v.nextID)
+		v.folders = append(v.folders, f)
+		reply(f)
+	case strings.HasPrefix(r.URL.Path, "/object/folder/") && r.Method == "DELETE":
+		id := strings.TrimPrefix(r.URL.Path, "/object/folder/")
+		for i, f := range v.folders {
+			if f.ID == id {
+				v.folders = append(v.folders[:i], v.folders[i+1:]...)
+				break
+			}
+		}
+		reply(nil)
+	case r.URL.Path == "/list/object/items":
+		list(v.items)
+	case r.URL.Path == "/object/item" && r.Method == "POST":
+		var item bitwarden.Item
+		json.Unmarshal(body, &item)
+		v.nextID++
+		item.ID = fmt.Sprintf("item%d", v.nextID)
+		v.items = append(v.items, item)
+		reply(item)
+	case strings.HasPrefix(r.URL.Path, "/object/item/"):
+		id := strings.TrimPrefix(r.URL.Path, "/object/item/")
+		for i := range v.items {
+			if v.items[i].ID != id {
+				continue
+			}
+			switch r.Method {
+			case "PUT":
+				var item bitwarden.Item
+				json.Unmarshal(body, &item)
+				item.ID = id
+				v.items[i] = item
+				reply(item)
+			case "DELETE":
+				v.items = append(v.items[:i], v.items[i+1:]...)
+				reply(nil)
+			default:
+				reply(v.items[i])
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newTestService returns a Service without a bus connection, backed by a
+// fresh fakeVault
+func newTestService(t *testing.T) (*Service, *fakeVault) {
+	t.Helper()
+	vault := &fakeVault{}
+	ts := httptest.NewServer(vault)
+	t.Cleanup(ts.Close)
+	port := ts.Listener.Addr().(*net.TCPAddr).Port
+	return NewService(nil, bitwarden.NewClient(port)), vault
+}
+
+func TestSplitFolderName(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantWallet string
+		wantFolder string
+		wantOK     bool
+	}{
+		{"KWallet/kdewallet/Passwords", "kdewallet", "Passwords", true},
+		{"KWallet/kdewallet/Form Data/x", "kdewallet", "Form Data/x", true},
+		{"KWallet/kdewallet", "", "", false},
+		{"KWallet//Passwords", "", "", false},
+		{"Work", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet, folder, ok := splitFolderName(tt.name)
+			if wallet != tt.wantWallet || folder != tt.wantFolder || ok != tt.wantOK {
+				t.Errorf("splitFolderName(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.name, wallet, folder, ok, tt.wantWallet, tt.wantFolder, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestKWallet_Handles(t *testing.T) {
+	svc, _ := newTestService(t)
+	w := &kwalletd{svc: svc}
+
+	if h, _ := w.Open("", "bad/name", 0, "app"); h != -1 {
+		t.Errorf("Open(bad/name) = %d, want -1", h)
+	}
+
+	h, _ := w.Open("", DefaultWallet, 0, "org.example.App")
+	if h <= 0 {
+		t.Fatalf("Open() = %d, want a handle", h)
+	}
+	if open, _ := w.IsOpen("", dbus.MakeVariant(DefaultWallet)); !open {
+		t.Error("IsOpen(wallet) = false after Open")
+	}
+	if open, _ := w.IsOpen("", dbus.MakeVariant(h)); !open {
+		t.Error("IsOpen(handle) = false after Open")
+	}
+	if users, _ := w.Users(DefaultWallet); !reflect.DeepEqual(users, []string{"org.example.App"}) {
+		t.Errorf("Users() = %v", users)
+	}
+
+	if r, _ := w.Close("", h, false, "org.example.App"); r != 0 {
+		t.Errorf("Close() = %d, want 0", r)
+	}
+	if open, _ := w.IsOpen("", dbus.MakeVariant(h)); open {
+		t.Error("IsOpen(handle) = true after Close")
+	}
+	if r, _ := w.Close("", h, false, "org.example.App"); r != -1 {
+		t.Errorf("second Close() = %d, want -1", r)
+	}
+
+	// Closed handles can't be used
+	if r, _ := w.WritePassword("", h, "Passwords", "k", "v", "app"); r != -1 {
+		t.Errorf("WritePassword() on closed handle = %d, want -1", r)
+	}
+
+	h2, _ := w.Open("", DefaultWallet, 0, "app")
+	svc.CloseAll()
+	if open, _ := w.IsOpen("", dbus.MakeVariant(h2)); open {
+		t.Error("handle still open after CloseAll")
+	}
+}
+
+func TestKWallet_Entries(t *testing.T) {
+	svc, vault := newTestService(t)
+	w := &kwalletd{svc: svc}
+	h, _ := w.Open("", DefaultWallet, 0, "app")
+
+	if r, _ := w.WritePassword("", h, "Passwords", "smtp", "secret", "app"); r != 0 {
+		t.Fatalf("WritePassword() = %d", r)
+	}
+	if r, _ := w.WriteMap("", h, "Form Data", "login", encodeQMap(map[string]string{"user": "joe"}), "app"); r != 0 {
+		t.Fatalf("WriteMap() = %d", r)
+	}
+	if r, _ := w.WriteEntry("", h, "Passwords", "blob", []byte{0, 1, 2}, "app"); r != 0 {
+		t.Fatalf("WriteEntry() = %d", r)
+	}
+
+	if folders, _ := w.FolderList("", h, "app"); !reflect.DeepEqual(folders, []string{"Form Data", "Passwords"}) {
+		t.Errorf("FolderList() = %v", folders)
+	}
+	if vault.folders[0].Name != "KWallet/kdewallet/Passwords" {
+		t.Errorf("folder stored as %q", vault.folders[0].Name)
+	}
+	if entries, _ := w.EntryList("", h, "Passwords", "app"); !reflect.DeepEqual(entries, []string{"blob", "smtp"}) {
+		t.Errorf("EntryList() = %v", entries)
+	}
+
+	if pw, _ := w.ReadPassword("", h, "Passwords", "smtp", "app"); pw != "secret" {
+		t.Errorf("ReadPassword() = %q", pw)
+	}
+	if typ, _ := w.EntryType("", h, "Passwords", "smtp", "app"); typ != int32(entryPassword) {
+		t.Errorf("EntryType(password) = %d", typ)
+	}
+	if typ, _ := w.EntryType("", h, "Passwords", "blob", "app"); typ != int32(entryStream) {
+		t.Errorf("EntryType(blob) = %d", typ)
+	}
+	if data, _ := w.ReadEntry("", h, "Passwords", "blob", "app"); !reflect.DeepEqual(data, []byte{0, 1, 2}) {
+		t.Errorf("ReadEntry(blob) = %v", data)
+	}
+	data, _ := w.ReadMap("", h, "Form Data", "login", "app")
+	if m, err := decodeQMap(data); err != nil || m["user"] != "joe" {
+		t.Errorf("ReadMap() = %v, %v", m, err)
+	}
+
+	list, _ := w.ReadPasswordList("", h, "Passwords", "s*", "app")
+	if len(list) != 1 || list["smtp"].Value() != "secret" {
+		t.Errorf("ReadPasswordList() = %v", list)
+	}
+
+	// Updating a password keeps the item
+	before := len(vault.items)
+	w.WritePassword("", h, "Passwords", "smtp", "changed", "app")
+	if pw, _ := w.ReadPassword("", h, "Passwords", "smtp", "app"); pw != "changed" || len(vault.items) != before {
+		t.Errorf("after update ReadPassword() = %q with %d items, want changed with %d", pw, len(vault.items), before)
+	}
+
+	// Writing another type replaces it
+	w.WriteEntry("", h, "Passwords", "smtp", []byte("raw"), "app")
+	if typ, _ := w.EntryType("", h, "Passwords", "smtp", "app"); typ != int32(entryStream) {
+		t.Errorf("EntryType after type change = %d", typ)
+	}
+
+	if r, _ := w.RenameEntry("", h, "Passwords", "blob", "blob2", "app"); r != 0 {
+		t.Errorf("RenameEntry() = %d", r)
+	}
+	if ok, _ := w.HasEntry("", h, "Passwords", "blob2", "app"); !ok {
+		t.Error("HasEntry(renamed) = false")
+	}
+	if missing, _ := w.KeyDoesNotExist(DefaultWallet, "Passwords", "blob"); !missing {
+		t.Error("KeyDoesNotExist(old name) = false")
+	}
+
+	if r, _ := w.RemoveEntry("", h, "Passwords", "blob2", "app"); r != 0 {
+		t.Errorf("RemoveEntry() = %d", r)
+	}
+	if removed, _ := w.RemoveFolder("", h, "Form Data", "app"); !removed {
+		t.Error("RemoveFolder() = false")
+	}
+	if missing, _ := w.FolderDoesNotExist(DefaultWallet, "Form Data"); !missing {
+		t.Error("FolderDoesNotExist(removed) = false")
+	}
+	if len(vault.items) != 1 {
+		t.Errorf("%d items left, want 1", len(vault.items))
+	}
+}
+
+// denyPrompter refuses every access confirmation
+type denyPrompter struct{ calls int }
+
+func (p *denyPrompter) PromptForAccess(string) (bitwarden.AccessChoice, error) {
+	p.calls++
+	return bitwarden.AccessDeny, nil
+}
+
+func TestKWallet_ReadDenied(t *testing.T) {
+	svc, _ := newTestService(t)
+	prompter := &denyPrompter{}
+	svc.SetAccessController(access.NewController(nil, access.NewPolicy(""), prompter))
+	w := &kwalletd{svc: svc}
+	h, _ := w.Open("", DefaultWallet, 0, "app")
+	w.WritePassword("", h, "Passwords", "smtp", "secret", "app")
+
+	remote, _ := w.Open(":1.42", DefaultWallet, 0, "app")
+	if pw, _ := w.ReadPassword(":1.42", remote, "Passwords", "smtp", "app"); pw != "" {
+		t.Errorf("denied ReadPassword() = %q, want empty", pw)
+	}
+	if list, _ := w.PasswordList(":1.42", remote, "Passwords", "app"); len(list) != 0 {
+		t.Errorf("denied PasswordList() = %v, want empty", list)
+	}
+	if prompter.calls != 2 {
+		t.Errorf("prompted %d times, want 2", prompter.calls)
+	}
+
+	// Entry types and keys are not secret
+	if typ, _ := w.EntryType(":1.42", remote, "Passwords", "smtp", "app"); typ != int32(entryPassword) {
+		t.Errorf("EntryType() = %d", typ)
+	}
+	if pw, _ := w.ReadPassword("", h, "Passwords", "smtp", "app"); pw != "secret" {
+		t.Errorf("in-process ReadPassword() = %q", pw)
+	}
+}
+
+func TestKWallet_HandleBoundToSender(t *testing.T) {
+	svc, _ := newTestService(t)
+	w := &kwalletd{svc: svc}
+	h, _ := w.Open(":1.5", DefaultWallet, 0, "org.example.App")
+	if r, _ := w.WritePassword(":1.5", h, "Passwords", "smtp", "secret", "org.example.App"); r != 0 {
+		t.Fatalf("WritePassword() by the opener = %d, want 0", r)
+	}
+
+	// Another connection guessing the handle and app ID gets nowhere
+	if pw, _ := w.ReadPassword(":1.6", h, "Passwords", "smtp", "org.example.App"); pw != "" {
+		t.Errorf("ReadPassword() from another sender = %q, want empty", pw)
+	}
+	if r, _ := w.WritePassword(":1.6", h, "Passwords", "smtp", "stolen", "org.example.App"); r != -1 {
+		t.Errorf("WritePassword() from another sender = %d, want -1", r)
+	}
+	if open, _ := w.IsOpen(":1.6", dbus.MakeVariant(h)); open {
+		t.Error("IsOpen() from another sender = true")
+	}
+	if r, _ := w.Close(":1.6", h, false, "org.example.App"); r != -1 {
+		t.Errorf("Close() from another sender = %d, want -1", r)
+	}
+
+	if pw, _ := w.ReadPassword(":1.5", h, "Passwords", "smtp", "org.example.App"); pw != "secret" {
+		t.Errorf("ReadPassword() by the opener = %q, want secret", pw)
+	}
+}
+
+func TestKWallet_ReadReprompt(t *testing.T) {
+	svc, vault := newTestService(t)
+	var reprompts int
+	repromptErr := bitwarden.ErrUserCancelled
+	svc.verifyReprompt = func(ctx context.Context, item *bitwarden.Item) error {
+		if item.Reprompt != bitwarden.RepromptPassword {
+			return nil
+		}
+		reprompts++
+		return repromptErr
+	}
+	w := &kwalletd{svc: svc}
+	h, _ := w.Open("", DefaultWallet, 0, "app")
+	w.WritePassword("", h, "Passwords", "smtp", "secret", "app")
+	w.WritePassword("", h, "Passwords", "web", "open", "app")
+	for i := range vault.items {
+		if vault.items[i].Name == "smtp" {
+			vault.items[i].Reprompt = bitwarden.RepromptPassword
+		}
+	}
+
+	if pw, _ := w.ReadPassword("", h, "Passwords", "smtp", "app"); pw != "" {
+		t.Errorf("ReadPassword() with re-prompt dismissed = %q, want empty", pw)
+	}
+	list, _ := w.PasswordList("", h, "Passwords", "app")
+	if len(list) != 1 || list["web"].Value() != "open" {
+		t.Errorf("PasswordList() with re-prompt dismissed = %v, want only web", list)
+	}
+
+	repromptErr = nil
+	if pw, _ := w.ReadPassword("", h, "Passwords", "smtp", "app"); pw != "secret" {
+		t.Errorf("ReadPassword() after re-prompt = %q, want secret", pw)
+	}
+	if reprompts != 3 {
+		t.Errorf("re-prompted %d times, want 3", reprompts)
+	}
+}
+
+func TestKWallet_HidesPortalSecrets(t *testing.T) {
+	svc, vault := newTestService(t)
+	w := &kwalletd{svc: svc}
+	h, _ := w.Open("", DefaultWallet, 0, "app")
+	w.WriteEntry("", h, "Passwords", "token", []byte("secret"), "app")
+
+	// A portal secret moved into the wallet folder in Bitwarden
+	for i := range vault.items {
+		vault.items[i].Fields = append(vault.items[i].Fields,
+			bitwarden.Field{Name: mapping.PortalAppIDFieldName, Value: "org.example.App"})
+	}
+
+	if has, _ := w.HasEntry("", h, "Passwords", "token", "app"); has {
+		t.Error("HasEntry() = true for a portal secret")
+	}
+	if data, _ := w.ReadEntry("", h, "Passwords", "token", "app"); len(data) != 0 {
+		t.Errorf("ReadEntry() = %q, want empty", data)
+	}
+}
+
+func TestKWallet_Wallets(t *testing.T) {
+	svc, vault := newTestService(t)
+	w := &kwalletd{svc: svc}
+
+	if wallets, _ := w.Wallets(); !reflect.DeepEqual(wallets, []string{DefaultWallet}) {
+		t.Errorf("Wallets() on empty vault = %v", wallets)
+	}
+
+	vault.folders = []bitwarden.Folder{
+		{ID: "1", Name: "KWallet/work/Passwords"},
+		{ID: "2", Name: "KWallet/work/Other"},
+		{ID: "3", Name: "Personal"},
+	}
+	want := []string{DefaultWallet, "work"}
+	if wallets, _ := w.Wallets(); !reflect.DeepEqual(wallets, want) {
+		t.Errorf("Wallets() = %v, want %v", wallets, want)
+	}
+}
+
+func TestKWallet_IsOpenInvalidArg(t *testing.T) {
+	svc, _ := newTestService(t)
+	w := &kwalletd{svc: svc}
+	if _, err := w.IsOpen("", dbus.MakeVariant(true)); err == nil || err.Name != dbus.ErrMsgInvalidArg.Name {
+		t.Errorf("IsOpen(bool) error = %v, want invalid args", err)
+	}
+}
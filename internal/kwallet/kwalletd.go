@@ -0,0 +1,532 @@
+package kwallet
+
+import (
+	"context"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joe/bitwarden-keyring/internal/access"
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/logging"
+)
+
+// methodNames maps kwalletd's Go methods to their D-Bus names, which start
+// with a lowercase letter
+var methodNames = map[string]string{
+	"IsEnabled":             "isEnabled",
+	"Open":                  "open",
+	"OpenAsync":             "openAsync",
+	"Close":                 "close",
+	"IsOpen":                "isOpen",
+	"Sync":                  "sync",
+	"Wallets":               "wallets",
+	"LocalWallet":           "localWallet",
+	"NetworkWallet":         "networkWallet",
+	"Users":                 "users",
+	"FolderList":            "folderList",
+	"HasFolder":             "hasFolder",
+	"CreateFolder":          "createFolder",
+	"RemoveFolder":          "removeFolder",
+	"EntryList":             "entryList",
+	"HasEntry":              "hasEntry",
+	"EntryType":             "entryType",
+	"ReadEntry":             "readEntry",
+	"ReadMap":               "readMap",
+	"ReadPassword":          "readPassword",
+	"ReadMapList":           "readMapList",
+	"ReadPasswordList":      "readPasswordList",
+	"EntriesList":           "entriesList",
+	"MapList":               "mapList",
+	"PasswordList":          "passwordList",
+	"WriteEntry":            "writeEntry",
+	"WriteMap":              "writeMap",
+	"WritePassword":         "writePassword",
+	"RemoveEntry":           "removeEntry",
+	"RenameEntry":           "renameEntry",
+	"FolderDoesNotExist":    "folderDoesNotExist",
+	"KeyDoesNotExist":       "keyDoesNotExist",
+	"DisconnectApplication": "disconnectApplication",
+	"CloseAllWallets":       "closeAllWallets",
+}
+
+// kwalletd implements the org.kde.KWallet methods. Like kwalletd it reports
+// failures through return values (-1, false or empty results) rather than
+// D-Bus errors, which KWallet clients don't expect.
+type kwalletd struct {
+	svc *Service
+}
+
+// IsEnabled reports whether the wallet subsystem is enabled (D-Bus method)
+func (w *kwalletd) IsEnabled() (bool, *dbus.Error) {
+	return true, nil
+}
+
+// Open opens a wallet and returns its handle, or -1 (D-Bus method). The
+// handle is only valid for calls from the same connection.
+func (w *kwalletd) Open(sender dbus.Sender, wallet string, windowID int64, appID string) (int32, *dbus.Error) {
+	return w.svc.open(wallet, appID, string(sender)), nil
+}
+
+// OpenAsync opens a wallet in the background and returns a transaction ID;
+// walletAsyncOpened carries the handle once the wallet is open (D-Bus method)
+func (w *kwalletd) OpenAsync(sender dbus.Sender, wallet string, windowID int64, appID string, handleSession bool) (int32, *dbus.Error) {
+	w.svc.mu.Lock()
+	w.svc.nextTransaction++
+	tID := w.svc.nextTransaction
+	w.svc.mu.Unlock()
+
+	go func() {
+		time.Sleep(asyncOpenDelay)
+		w.svc.emit("walletAsyncOpened", tID, w.svc.open(wallet, appID, string(sender)))
+	}()
+	return tID, nil
+}
+
+// Close closes a wallet handle (D-Bus method). kwalletd also has
+// close(s wallet, b force), but D-Bus methods can't be overloaded here and
+// every KWallet client closes its handle with this variant.
+func (w *kwalletd) Close(sender dbus.Sender, handle int32, force bool, appID string) (int32, *dbus.Error) {
+	return w.svc.close(handle, string(sender)), nil
+}
+
+// IsOpen reports whether a wallet, given by name or handle, is open
+// (D-Bus method)
+func (w *kwalletd) IsOpen(sender dbus.Sender, walletOrHandle dbus.Variant) (bool, *dbus.Error) {
+	switch v := walletOrHandle.Value().(type) {
+	case string:
+		return w.svc.isOpen(v), nil
+	case int32:
+		_, ok := w.svc.wallet(v, string(sender))
+		return ok, nil
+	}
+	return false, &dbus.ErrMsgInvalidArg
+}
+
+// Sync flushes a wallet to disk; the vault needs no flushing (D-Bus method)
+func (w *kwalletd) Sync(handle int32, appID string) *dbus.Error {
+	return nil
+}
+
+// Wallets lists the wallets in the vault (D-Bus method). The default wallet
+// is always listed so applications don't offer to create it. While the vault
+// is locked only the default wallet is listed, without asking to unlock.
+func (w *kwalletd) Wallets() ([]string, *dbus.Error) {
+	ctx := context.Background()
+	var wallets []string
+	if !w.svc.store.client.IsLockedSafe(ctx) {
+		var err error
+		if wallets, err = w.svc.store.wallets(ctx); err != nil {
+			w.logFailure("wallets", err)
+		}
+	}
+	for _, wallet := range wallets {
+		if wallet == DefaultWallet {
+			return wallets, nil
+		}
+	}
+	wallets = append(wallets, DefaultWallet)
+	sort.Strings(wallets)
+	return wallets, nil
+}
+
+// LocalWallet returns the wallet for local passwords (D-Bus method)
+func (w *kwalletd) LocalWallet() (string, *dbus.Error) {
+	return DefaultWallet, nil
+}
+
+// NetworkWallet returns the wallet for network passwords (D-Bus method)
+func (w *kwalletd) NetworkWallet() (string, *dbus.Error) {
+	return DefaultWallet, nil
+}
+
+// Users lists the applications that have a wallet open (D-Bus method)
+func (w *kwalletd) Users(wallet string) ([]string, *dbus.Error) {
+	w.svc.mu.Lock()
+	defer w.svc.mu.Unlock()
+	seen := map[string]bool{}
+	users := []string{}
+	for _, h := range w.svc.handles {
+		if h.wallet == wallet && !seen[h.appID] {
+			seen[h.appID] = true
+			users = append(users, h.appID)
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// FolderList lists the folders of an open wallet (D-Bus method)
+func (w *kwalletd) FolderList(sender dbus.Sender, handle int32, appID string) ([]string, *dbus.Error) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return []string{}, nil
+	}
+	folders, err := w.svc.store.folderList(requestContext(), wallet)
+	if err != nil {
+		w.logFailure("folderList", err)
+	}
+	return nonNil(folders), nil
+}
+
+// HasFolder reports whether a wallet folder exists (D-Bus method)
+func (w *kwalletd) HasFolder(sender dbus.Sender, handle int32, folder, appID string) (bool, *dbus.Error) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return false, nil
+	}
+	id, err := w.svc.store.folderID(requestContext(), wallet, folder)
+	if err != nil {
+		w.logFailure("hasFolder", err)
+	}
+	return id != "", nil
+}
+
+// CreateFolder creates a wallet folder (D-Bus method)
+func (w *kwalletd) CreateFolder(sender dbus.Sender, handle int32, folder, appID string) (bool, *dbus.Error) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return false, nil
+	}
+	if _, err := w.svc.store.createFolder(requestContext(), wallet, folder); err != nil {
+		w.logFailure("createFolder", err)
+		return false, nil
+	}
+	w.svc.emit("folderListUpdated", wallet)
+	return true, nil
+}
+
+// RemoveFolder deletes a wallet folder and its entries (D-Bus method)
+func (w *kwalletd) RemoveFolder(sender dbus.Sender, handle int32, folder, appID string) (bool, *dbus.Error) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return false, nil
+	}
+	removed, err := w.svc.store.removeFolder(requestContext(), wallet, folder)
+	if err != nil {
+		w.logFailure("removeFolder", err)
+		return false, nil
+	}
+	if removed {
+		w.svc.emit("folderListUpdated", wallet)
+	}
+	return removed, nil
+}
+
+// EntryList lists the entry keys of a folder (D-Bus method)
+func (w *kwalletd) EntryList(sender dbus.Sender, handle int32, folder, appID string) ([]string, *dbus.Error) {
+	entries, ok := w.entries("entryList", sender, handle, folder)
+	if !ok {
+		return []string{}, nil
+	}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// HasEntry reports whether an entry exists (D-Bus method)
+func (w *kwalletd) HasEntry(sender dbus.Sender, handle int32, folder, key, appID string) (bool, *dbus.Error) {
+	entries, ok := w.entries("hasEntry", sender, handle, folder)
+	return ok && entries[key] != nil, nil
+}
+
+// EntryType returns the KWallet type of an entry, 0 if unknown (D-Bus method)
+func (w *kwalletd) EntryType(sender dbus.Sender, handle int32, folder, key, appID string) (int32, *dbus.Error) {
+	item, ok := w.item("entryType", sender, handle, folder, key)
+	if !ok {
+		return int32(entryUnknown), nil
+	}
+	e, err := entryFromItem(item)
+	if err != nil {
+		w.logFailure("entryType", err)
+	}
+	return int32(e.typ), nil
+}
+
+// ReadEntry returns an entry in its serialized form (D-Bus method)
+func (w *kwalletd) ReadEntry(sender dbus.Sender, handle int32, folder, key, appID string) ([]byte, *dbus.Error) {
+	e, ok := w.read("readEntry", sender, handle, folder, key)
+	if !ok {
+		return []byte{}, nil
+	}
+	return e.bytes(), nil
+}
+
+// ReadMap returns a map entry as a serialized QMap (D-Bus method)
+func (w *kwalletd) ReadMap(sender dbus.Sender, handle int32, folder, key, appID string) ([]byte, *dbus.Error) {
+	e, ok := w.read("readMap", sender, handle, folder, key)
+	if !ok || e.typ != entryMap {
+		return []byte{}, nil
+	}
+	return e.bytes(), nil
+}
+
+// ReadPassword returns a password entry (D-Bus method)
+func (w *kwalletd) ReadPassword(sender dbus.Sender, handle int32, folder, key, appID string) (string, *dbus.Error) {
+	e, ok := w.read("readPassword", sender, handle, folder, key)
+	if !ok || e.typ != entryPassword {
+		return "", nil
+	}
+	return e.password, nil
+}
+
+// ReadMapList returns the map entries whose keys match a wildcard pattern
+// (D-Bus method)
+func (w *kwalletd) ReadMapList(sender dbus.Sender, handle int32, folder, pattern, appID string) (map[string]dbus.Variant, *dbus.Error) {
+	return w.list("readMapList", sender, handle, folder, pattern, entryMap), nil
+}
+
+// ReadPasswordList returns the password entries whose keys match a wildcard
+// pattern (D-Bus method)
+func (w *kwalletd) ReadPasswordList(sender dbus.Sender, handle int32, folder, pattern, appID string) (map[string]dbus.Variant, *dbus.Error) {
+	return w.list("readPasswordList", sender, handle, folder, pattern, entryPassword), nil
+}
+
+// EntriesList returns every entry of a folder, serialized (D-Bus method)
+func (w *kwalletd) EntriesList(sender dbus.Sender, handle int32, folder, appID string) (map[string]dbus.Variant, *dbus.Error) {
+	return w.list("entriesList", sender, handle, folder, "*", entryUnknown), nil
+}
+
+// MapList returns every map entry of a folder (D-Bus method)
+func (w *kwalletd) MapList(sender dbus.Sender, handle int32, folder, appID string) (map[string]dbus.Variant, *dbus.Error) {
+	return w.list("mapList", sender, handle, folder, "*", entryMap), nil
+}
+
+// PasswordList returns every password entry of a folder (D-Bus method)
+func (w *kwalletd) PasswordList(sender dbus.Sender, handle int32, folder, appID string) (map[string]dbus.Variant, *dbus.Error) {
+	return w.list("passwordList", sender, handle, folder, "*", entryPassword), nil
+}
+
+// WriteEntry stores a binary entry (D-Bus method). The typed variant
+// writeEntry(i, s, s, ay, i, s) can't be exported alongside it; clients use
+// writePassword and writeMap for typed entries.
+func (w *kwalletd) WriteEntry(sender dbus.Sender, handle int32, folder, key string, value []byte, appID string) (int32, *dbus.Error) {
+	return w.write("writeEntry", sender, handle, folder, key, entry{typ: entryStream, data: value}), nil
+}
+
+// WriteMap stores a map entry given as a serialized QMap (D-Bus method)
+func (w *kwalletd) WriteMap(sender dbus.Sender, handle int32, folder, key string, value []byte, appID string) (int32, *dbus.Error) {
+	fields, err := decodeQMap(value)
+	if err != nil {
+		w.logFailure("writeMap", err)
+		return -1, nil
+	}
+	return w.write("writeMap", sender, handle, folder, key, entry{typ: entryMap, fields: fields}), nil
+}
+
+// WritePassword stores a password entry (D-Bus method)
+func (w *kwalletd) WritePassword(sender dbus.Sender, handle int32, folder, key, value, appID string) (int32, *dbus.Error) {
+	return w.write("writePassword", sender, handle, folder, key, entry{typ: entryPassword, password: value}), nil
+}
+
+// RemoveEntry deletes an entry (D-Bus method)
+func (w *kwalletd) RemoveEntry(sender dbus.Sender, handle int32, folder, key, appID string) (int32, *dbus.Error) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return -1, nil
+	}
+	removed, err := w.svc.store.remove(requestContext(), wallet, folder, key)
+	if err != nil {
+		w.logFailure("removeEntry", err)
+		return -1, nil
+	}
+	if removed {
+		w.svc.emit("folderUpdated", wallet, folder)
+	}
+	return 0, nil
+}
+
+// RenameEntry changes the key of an entry (D-Bus method)
+func (w *kwalletd) RenameEntry(sender dbus.Sender, handle int32, folder, oldKey, newKey, appID string) (int32, *dbus.Error) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return -1, nil
+	}
+	renamed, err := w.svc.store.rename(requestContext(), wallet, folder, oldKey, newKey)
+	if err != nil || !renamed {
+		if err != nil {
+			w.logFailure("renameEntry", err)
+		}
+		return -1, nil
+	}
+	w.svc.emit("folderUpdated", wallet, folder)
+	return 0, nil
+}
+
+// FolderDoesNotExist reports whether a folder is missing without opening the
+// wallet (D-Bus method). While the vault is locked nothing is known to be
+// missing, so it returns false rather than asking for the master password.
+func (w *kwalletd) FolderDoesNotExist(wallet, folder string) (bool, *dbus.Error) {
+	ctx := context.Background()
+	if w.svc.store.client.IsLockedSafe(ctx) {
+		return false, nil
+	}
+	id, err := w.svc.store.folderID(ctx, wallet, folder)
+	if err != nil {
+		w.logFailure("folderDoesNotExist", err)
+		return false, nil
+	}
+	return id == "", nil
+}
+
+// KeyDoesNotExist reports whether an entry is missing without opening the
+// wallet (D-Bus method); like FolderDoesNotExist it returns false while the
+// vault is locked
+func (w *kwalletd) KeyDoesNotExist(wallet, folder, key string) (bool, *dbus.Error) {
+	ctx := context.Background()
+	if w.svc.store.client.IsLockedSafe(ctx) {
+		return false, nil
+	}
+	item, err := w.svc.store.item(ctx, wallet, folder, key)
+	if err != nil {
+		w.logFailure("keyDoesNotExist", err)
+		return false, nil
+	}
+	return item == nil, nil
+}
+
+// DisconnectApplication closes an application's handles to a wallet
+// (D-Bus method)
+func (w *kwalletd) DisconnectApplication(wallet, appID string) (bool, *dbus.Error) {
+	w.svc.mu.Lock()
+	var handles []int32
+	for handle, h := range w.svc.handles {
+		if h.wallet == wallet && h.appID == appID {
+			handles = append(handles, handle)
+		}
+	}
+	w.svc.mu.Unlock()
+
+	for _, handle := range handles {
+		w.svc.closeHandle(handle)
+	}
+	return true, nil
+}
+
+// CloseAllWallets closes every open wallet (D-Bus method)
+func (w *kwalletd) CloseAllWallets() *dbus.Error {
+	w.svc.CloseAll()
+	return nil
+}
+
+// entries returns the items of a folder in the wallet behind handle
+func (w *kwalletd) entries(op string, sender dbus.Sender, handle int32, folder string) (map[string]*bitwarden.Item, bool) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return nil, false
+	}
+	entries, err := w.svc.store.entries(requestContext(), wallet, folder)
+	if err != nil {
+		w.logFailure(op, err)
+		return nil, false
+	}
+	return entries, true
+}
+
+// item returns the vault item behind an entry of the wallet behind handle
+func (w *kwalletd) item(op string, sender dbus.Sender, handle int32, folder, key string) (*bitwarden.Item, bool) {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return nil, false
+	}
+	item, err := w.svc.store.item(requestContext(), wallet, folder, key)
+	if err != nil {
+		w.logFailure(op, err)
+	}
+	return item, item != nil
+}
+
+// read returns an entry of the wallet behind handle if sender may read it
+func (w *kwalletd) read(op string, sender dbus.Sender, handle int32, folder, key string) (entry, bool) {
+	item, ok := w.item(op, sender, handle, folder, key)
+	if !ok || !w.allowed(op, sender, item) {
+		return entry{}, false
+	}
+	e, err := entryFromItem(item)
+	if err != nil {
+		w.logFailure(op, err)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// allowed puts a read of item through the checks of the Secret Service's
+// GetSecret: access confirmation for sender and master password re-prompt
+func (w *kwalletd) allowed(op string, sender dbus.Sender, item *bitwarden.Item) bool {
+	if err := w.svc.access.Check(string(sender), access.Target{ID: item.ID, Label: item.Name}); err != nil {
+		w.logFailure(op, err)
+		return false
+	}
+	if err := w.svc.verifyReprompt(requestContext(), item); err != nil {
+		w.logFailure(op, err)
+		return false
+	}
+	return true
+}
+
+// list returns the entries of a folder whose keys match pattern, limited to
+// entries of typ unless it is entryUnknown. Passwords are returned as
+// strings and everything else in serialized form. Entries sender may not
+// read are left out.
+func (w *kwalletd) list(op string, sender dbus.Sender, handle int32, folder, pattern string, typ entryType) map[string]dbus.Variant {
+	result := map[string]dbus.Variant{}
+	entries, ok := w.entries(op, sender, handle, folder)
+	if !ok {
+		return result
+	}
+	for key, item := range entries {
+		if matched, _ := path.Match(pattern, key); !matched {
+			continue
+		}
+		e, err := entryFromItem(item)
+		if err != nil {
+			w.logFailure(op, err)
+			continue
+		}
+		if typ != entryUnknown && e.typ != typ {
+			continue
+		}
+		if !w.allowed(op, sender, item) {
+			continue
+		}
+		if typ == entryPassword {
+			result[key] = dbus.MakeVariant(e.password)
+		} else {
+			result[key] = dbus.MakeVariant(e.bytes())
+		}
+	}
+	return result
+}
+
+// write stores an entry in the wallet behind handle, returning kwalletd's
+// 0 on success and -1 on failure
+func (w *kwalletd) write(op string, sender dbus.Sender, handle int32, folder, key string, e entry) int32 {
+	wallet, ok := w.svc.wallet(handle, string(sender))
+	if !ok {
+		return -1
+	}
+	if err := w.svc.store.write(requestContext(), wallet, folder, key, e); err != nil {
+		w.logFailure(op, err)
+		return -1
+	}
+	w.svc.emit("folderUpdated", wallet, folder)
+	return 0
+}
+
+// logFailure logs a vault error that the method reports as a plain failure
+func (w *kwalletd) logFailure(op string, err error) {
+	logging.L.With("component", "kwallet").Warn("request failed", "method", op, "error", err)
+}
+
+// nonNil returns s, or an empty slice in place of nil
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package kwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+// KWallet hands maps and password entries to clients as QDataStream
+// serializations, so the few Qt types involved are encoded here.
+
+// qtNullLength marks a null QString or QByteArray in a QDataStream
+const qtNullLength = 0xFFFFFFFF
+
+// errShortData indicates a QDataStream ended in the middle of a value
+var errShortData = errors.New("truncated QDataStream data")
+
+// encodeQString appends s as a QDataStream QString: its UTF-16BE byte
+// length followed by the UTF-16BE code units
+func encodeQString(buf *bytes.Buffer, s string) {
+	units := utf16.Encode([]rune(s))
+	binary.Write(buf, binary.BigEndian, uint32(len(units)*2))
+	binary.Write(buf, binary.BigEndian, units)
+}
+
+// decodeQString reads a QDataStream QString; a null string reads as ""
+func decodeQString(r *bytes.Reader) (string, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", errShortData
+	}
+	if n == qtNullLength {
+		return "", nil
+	}
+	if n%2 != 0 || int64(n) > int64(r.Len()) {
+		return "", fmt.Errorf("invalid QString length %d", n)
+	}
+	units := make([]uint16, n/2)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return "", errShortData
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// encodeQStringValue returns s serialized on its own, as kwalletd returns
+// password entries from readEntry
+func encodeQStringValue(s string) []byte {
+	var buf bytes.Buffer
+	encodeQString(&buf, s)
+	return buf.Bytes()
+}
+
+// encodeQMap serializes a QMap<QString,QString>: the entry count followed by
+// each key and value, in key order
+func encodeQMap(m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(len(keys)))
+	for _, k := range keys {
+		encodeQString(&buf, k)
+		encodeQString(&buf, m[k])
+	}
+	return buf.Bytes()
+}
+
+// decodeQMap parses a serialized QMap<QString,QString>
+func decodeQMap(data []byte) (map[string]string, error) {
+	r := bytes.NewReader(data)
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, errShortData
+	}
+
+	// Every entry takes at least 8 bytes, which bounds the count
+	if int64(n)*8 > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid QMap size %d", n)
+	}
+
+	m := make(map[string]string, n)
+	for i := uint32(0); i < n; i++ {
+		k, err := decodeQString(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := decodeQString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
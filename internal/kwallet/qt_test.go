@@ -0,0 +1,81 @@
+package kwallet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeQStringValue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []byte
+	}{
+		{"empty", "", []byte{0, 0, 0, 0}},
+		{"ascii", "pw", []byte{0, 0, 0, 4, 0, 'p', 0, 'w'}},
+		{"non-BMP", "\U0001F511", []byte{0, 0, 0, 4, 0xD8, 0x3D, 0xDD, 0x11}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeQStringValue(tt.in)
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("encodeQStringValue(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+			decoded, err := decodeQString(bytes.NewReader(got))
+			if err != nil || decoded != tt.in {
+				t.Errorf("decodeQString() = %q, %v, want %q", decoded, err, tt.in)
+			}
+		})
+	}
+}
+
+func TestDecodeQString_Null(t *testing.T) {
+	got, err := decodeQString(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF}))
+	if err != nil || got != "" {
+		t.Errorf("decodeQString(null) = %q, %v, want empty", got, err)
+	}
+}
+
+func TestQMap_RoundTrip(t *testing.T) {
+	m := map[string]string{"login": "joe", "password": "pä55", "empty": ""}
+	got, err := decodeQMap(encodeQMap(m))
+	if err != nil {
+		t.Fatalf("decodeQMap() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("round trip = %v, want %v", got, m)
+	}
+}
+
+func TestEncodeQMap_Bytes(t *testing.T) {
+	want := []byte{
+		0, 0, 0, 1, // one entry
+		0, 0, 0, 2, 0, 'k', // key
+		0, 0, 0, 2, 0, 'v', // value
+	}
+	if got := encodeQMap(map[string]string{"k": "v"}); !bytes.Equal(got, want) {
+		t.Errorf("encodeQMap() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeQMap_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"huge count", []byte{0x7F, 0xFF, 0xFF, 0xFF}},
+		{"truncated value", []byte{0, 0, 0, 1, 0, 0, 0, 2, 0, 'k', 0, 0, 0, 2, 0}},
+		{"odd length", []byte{0, 0, 0, 1, 0, 0, 0, 1, 'k', 0, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeQMap(tt.data); err == nil {
+				t.Error("decodeQMap() succeeded, want error")
+			}
+		})
+	}
+}
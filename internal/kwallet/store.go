@@ -0,0 +1,372 @@
+package kwallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/mapping"
+)
+
+// FolderPrefix starts the name of every Bitwarden folder holding a wallet
+// folder; wallet "kdewallet" folder "Passwords" is "KWallet/kdewallet/Passwords"
+const FolderPrefix = "KWallet/"
+
+// EntryTypeFieldName is the custom field marking secure notes that hold map
+// or binary entries. Logins are password entries and need no marker.
+const EntryTypeFieldName = "kwallet-entry-type"
+
+// entryType is KWallet's EntryType
+type entryType int32
+
+// Values of entryType, as numbered by KWallet
+const (
+	entryUnknown  entryType = 0
+	entryPassword entryType = 1
+	entryStream   entryType = 2
+	entryMap      entryType = 3
+)
+
+// Values of the EntryTypeFieldName field
+const (
+	markerStream = "stream"
+	markerMap    = "map"
+)
+
+// entry is the value of a wallet entry
+type entry struct {
+	typ      entryType
+	password string            // for entryPassword
+	data     []byte            // for entryStream
+	fields   map[string]string // for entryMap
+}
+
+// entryFromItem decodes the entry stored in a vault item
+func entryFromItem(item *bitwarden.Item) (entry, error) {
+	switch item.Type {
+	case bitwarden.ItemTypeLogin:
+		e := entry{typ: entryPassword}
+		if item.Login != nil && item.Login.Password != nil {
+			e.password = *item.Login.Password
+		}
+		return e, nil
+
+	case bitwarden.ItemTypeSecureNote:
+		notes := ""
+		if item.Notes != nil {
+			notes = *item.Notes
+		}
+		switch entryMarker(item) {
+		case markerStream:
+			data, err := base64.StdEncoding.DecodeString(notes)
+			if err != nil {
+				return entry{}, fmt.Errorf("invalid binary entry %q: %w", item.Name, err)
+			}
+			return entry{typ: entryStream, data: data}, nil
+		case markerMap:
+			fields := map[string]string{}
+			if notes != "" {
+				if err := json.Unmarshal([]byte(notes), &fields); err != nil {
+					return entry{}, fmt.Errorf("invalid map entry %q: %w", item.Name, err)
+				}
+			}
+			return entry{typ: entryMap, fields: fields}, nil
+		}
+	}
+	return entry{typ: entryUnknown}, nil
+}
+
+// entryMarker returns the EntryTypeFieldName value of an item, if any
+func entryMarker(item *bitwarden.Item) string {
+	for _, f := range item.Fields {
+		if f.Name == EntryTypeFieldName {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// itemType returns the vault item type an entry is stored as
+func (e entry) itemType() bitwarden.ItemType {
+	if e.typ == entryPassword {
+		return bitwarden.ItemTypeLogin
+	}
+	return bitwarden.ItemTypeSecureNote
+}
+
+// request builds the item request storing e under key in folderID
+func (e entry) request(key, folderID string) (bitwarden.CreateItemRequest, error) {
+	req := bitwarden.CreateItemRequest{
+		FolderID: &folderID,
+		Type:     e.itemType(),
+		Name:     key,
+	}
+
+	switch e.typ {
+	case entryPassword:
+		password := e.password
+		req.Login = &bitwarden.Login{Password: &password}
+	case entryStream:
+		notes := base64.StdEncoding.EncodeToString(e.data)
+		req.Notes = &notes
+		req.SecureNote = &bitwarden.SecureNote{}
+		req.Fields = []bitwarden.Field{{Name: EntryTypeFieldName, Value: markerStream}}
+	case entryMap:
+		b, err := json.Marshal(e.fields)
+		if err != nil {
+			return req, err
+		}
+		notes := string(b)
+		req.Notes = &notes
+		req.SecureNote = &bitwarden.SecureNote{}
+		req.Fields = []bitwarden.Field{{Name: EntryTypeFieldName, Value: markerMap}}
+	default:
+		return req, fmt.Errorf("cannot store entry of type %d", e.typ)
+	}
+	return req, nil
+}
+
+// bytes returns the entry as kwalletd's readEntry returns it
+func (e entry) bytes() []byte {
+	switch e.typ {
+	case entryPassword:
+		return encodeQStringValue(e.password)
+	case entryMap:
+		return encodeQMap(e.fields)
+	default:
+		return e.data
+	}
+}
+
+// store maps wallets, folders and entries onto Bitwarden folders and items
+type store struct {
+	client *bitwarden.Client
+}
+
+// folderName returns the Bitwarden folder name of a wallet folder
+func folderName(wallet, folder string) string {
+	return FolderPrefix + wallet + "/" + folder
+}
+
+// splitFolderName splits a Bitwarden folder name into wallet and folder;
+// ok is false for folders that don't belong to a wallet
+func splitFolderName(name string) (wallet, folder string, ok bool) {
+	rest, found := strings.CutPrefix(name, FolderPrefix)
+	if !found {
+		return "", "", false
+	}
+	wallet, folder, ok = strings.Cut(rest, "/")
+	if wallet == "" || folder == "" {
+		return "", "", false
+	}
+	return wallet, folder, ok
+}
+
+// wallets returns the names of wallets that have at least one folder
+func (s *store) wallets(ctx context.Context) ([]string, error) {
+	folders, err := s.client.ListFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, f := range folders {
+		if wallet, _, ok := splitFolderName(f.Name); ok && !seen[wallet] {
+			seen[wallet] = true
+			names = append(names, wallet)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// folderList returns the folders of a wallet
+func (s *store) folderList(ctx context.Context, wallet string) ([]string, error) {
+	folders, err := s.client.ListFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range folders {
+		if w, folder, ok := splitFolderName(f.Name); ok && w == wallet {
+			names = append(names, folder)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// folderID returns the Bitwarden folder ID of a wallet folder, or "" if the
+// folder doesn't exist
+func (s *store) folderID(ctx context.Context, wallet, folder string) (string, error) {
+	folders, err := s.client.ListFolders(ctx)
+	if err != nil {
+		return "", err
+	}
+	name := folderName(wallet, folder)
+	for _, f := range folders {
+		if f.Name == name {
+			return f.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// createFolder creates a wallet folder unless it exists and returns its ID
+func (s *store) createFolder(ctx context.Context, wallet, folder string) (string, error) {
+	id, err := s.folderID(ctx, wallet, folder)
+	if err != nil || id != "" {
+		return id, err
+	}
+	created, err := s.client.CreateFolder(ctx, folderName(wallet, folder))
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// removeFolder deletes a wallet folder and its entries. It reports whether
+// the folder existed.
+func (s *store) removeFolder(ctx context.Context, wallet, folder string) (bool, error) {
+	id, err := s.folderID(ctx, wallet, folder)
+	if err != nil || id == "" {
+		return false, err
+	}
+	items, err := s.items(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range items {
+		if err := s.client.DeleteItem(ctx, item.ID); err != nil {
+			return false, err
+		}
+	}
+	return true, s.client.DeleteFolder(ctx, id)
+}
+
+// items returns the vault items in a Bitwarden folder, leaving out portal
+// secret items, which are only served to xdg-desktop-portal
+func (s *store) items(ctx context.Context, folderID string) ([]bitwarden.Item, error) {
+	all, err := s.client.ListItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var items []bitwarden.Item
+	for _, item := range all {
+		if item.FolderID != nil && *item.FolderID == folderID && mapping.PortalAppID(&item) == "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// entries returns the items of a wallet folder keyed by entry name; a
+// missing folder has no entries
+func (s *store) entries(ctx context.Context, wallet, folder string) (map[string]*bitwarden.Item, error) {
+	id, err := s.folderID(ctx, wallet, folder)
+	if err != nil || id == "" {
+		return nil, err
+	}
+	items, err := s.items(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*bitwarden.Item, len(items))
+	for i := range items {
+		byKey[items[i].Name] = &items[i]
+	}
+	return byKey, nil
+}
+
+// item returns the vault item holding an entry, or nil if there is none
+func (s *store) item(ctx context.Context, wallet, folder, key string) (*bitwarden.Item, error) {
+	entries, err := s.entries(ctx, wallet, folder)
+	if err != nil {
+		return nil, err
+	}
+	return entries[key], nil
+}
+
+// write stores an entry, creating the folder if needed. An existing entry
+// of the same kind is updated in place; one of another kind is replaced,
+// since a vault item can't change its type.
+func (s *store) write(ctx context.Context, wallet, folder, key string, e entry) error {
+	folderID, err := s.createFolder(ctx, wallet, folder)
+	if err != nil {
+		return err
+	}
+	req, err := e.request(key, folderID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.item(ctx, wallet, folder, key)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Type == req.Type {
+		update := existing.ToUpdateRequest()
+		if e.typ == entryPassword {
+			update.Login = mergeLogin(existing.Login, req.Login)
+		} else {
+			update.Notes = req.Notes
+			update.Fields = mergeMarker(existing.Fields, req.Fields)
+		}
+		_, err = s.client.UpdateItem(ctx, existing.ID, update)
+		return err
+	}
+	if existing != nil {
+		if err := s.client.DeleteItem(ctx, existing.ID); err != nil {
+			return err
+		}
+	}
+	_, err = s.client.CreateItem(ctx, req)
+	return err
+}
+
+// mergeLogin keeps an existing login's other data when only the password
+// of a password entry changes
+func mergeLogin(existing, update *bitwarden.Login) *bitwarden.Login {
+	if existing == nil || update == nil {
+		return update
+	}
+	merged := *existing
+	merged.Password = update.Password
+	return &merged
+}
+
+// mergeMarker replaces the entry type marker among an item's custom fields
+func mergeMarker(existing, marker []bitwarden.Field) []bitwarden.Field {
+	var fields []bitwarden.Field
+	for _, f := range existing {
+		if f.Name != EntryTypeFieldName {
+			fields = append(fields, f)
+		}
+	}
+	return append(fields, marker...)
+}
+
+// remove deletes an entry; it reports whether the entry existed
+func (s *store) remove(ctx context.Context, wallet, folder, key string) (bool, error) {
+	item, err := s.item(ctx, wallet, folder, key)
+	if err != nil || item == nil {
+		return false, err
+	}
+	return true, s.client.DeleteItem(ctx, item.ID)
+}
+
+// rename changes an entry's key; it reports whether the entry existed
+func (s *store) rename(ctx context.Context, wallet, folder, oldKey, newKey string) (bool, error) {
+	item, err := s.item(ctx, wallet, folder, oldKey)
+	if err != nil || item == nil {
+		return false, err
+	}
+	update := item.ToUpdateRequest()
+	update.Name = newKey
+	_, err = s.client.UpdateItem(ctx, item.ID, update)
+	return true, err
+}
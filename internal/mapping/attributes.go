@@ -19,14 +19,122 @@ const (
 	AttrSchema   = "xdg:schema"
 )
 
+// Attribute keys exposed for non-login item types
+const (
+	AttrType       = "bitwarden:type" // item type name, see Type* constants
+	AttrCardBrand  = "brand"          // card items
+	AttrCardholder = "cardholder"     // card items
+	AttrEmail      = "email"          // identity items
+	AttrCompany    = "company"        // identity items
+)
+
+// AttrSecret selects what a search result returns as its secret, see
+// SelectSecret. It is only a search attribute and never stored on items.
+const AttrSecret = "bitwarden:secret"
+
+// Item type names used as values of the bitwarden:type attribute
+const (
+	TypeLogin    = "login"
+	TypeNote     = "note"
+	TypeCard     = "card"
+	TypeIdentity = "identity"
+)
+
 // Common schemas
 const (
 	SchemaGenericSecret   = "org.freedesktop.Secret.Generic"
 	SchemaNetworkPassword = "org.gnome.keyring.NetworkPassword"
 )
 
-// ItemToAttributes converts a Bitwarden item to libsecret attributes
+// ItemTypeName returns the bitwarden:type name of an item, or an empty string
+// for item types that are not served over the Secret Service. Items without a
+// type but with login data are treated as logins.
+func ItemTypeName(item *bitwarden.Item) string {
+	switch item.Type {
+	case bitwarden.ItemTypeLogin:
+		return TypeLogin
+	case bitwarden.ItemTypeSecureNote:
+		return TypeNote
+	case bitwarden.ItemTypeCard:
+		return TypeCard
+	case bitwarden.ItemTypeIdentity:
+		return TypeIdentity
+	}
+	if item.Type == 0 && item.Login != nil {
+		return TypeLogin
+	}
+	return ""
+}
+
+// ItemTypeFromAttributes returns the item type requested by the bitwarden:type
+// attribute. Missing or unknown values default to a login item.
+func ItemTypeFromAttributes(attrs map[string]string) bitwarden.ItemType {
+	switch attrs[AttrType] {
+	case TypeNote:
+		return bitwarden.ItemTypeSecureNote
+	case TypeCard:
+		return bitwarden.ItemTypeCard
+	case TypeIdentity:
+		return bitwarden.ItemTypeIdentity
+	default:
+		return bitwarden.ItemTypeLogin
+	}
+}
+
+// ItemToAttributes converts a Bitwarden item to libsecret attributes.
+// Items whose attributes were recorded by StoreAttributes return that set,
+// with stale copies of Bitwarden fields replaced by their live values; other
+// items get attributes derived from their Bitwarden fields. Both carry the
+// vault attributes (bitwarden:id, ...) except bitwarden:folder.
 func ItemToAttributes(item *bitwarden.Item) map[string]string {
+	attrs := StoredAttributes(item)
+	if attrs == nil {
+		attrs = derivedAttributes(item)
+	} else {
+		refreshStoredAttributes(item, attrs)
+	}
+	addVaultAttributes(attrs, item)
+	return attrs
+}
+
+// derivedKey names the derived attribute that holds the live value of key
+var derivedKey = map[string]string{AttrUser: AttrUsername, AttrServer: AttrDomain}
+
+// refreshStoredAttributes replaces stored attributes that mirror a Bitwarden
+// field but no longer match it, e.g. after the username was edited in
+// Bitwarden, with the live value, or drops them if the field is now empty.
+func refreshStoredAttributes(item *bitwarden.Item, attrs map[string]string) {
+	var derived map[string]string
+	var stale []string
+	for key, value := range attrs {
+		if modeledAttribute(item, key) && !matchesLive(item, attrs, key, value, &derived) {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) > 0 && derived == nil {
+		derived = derivedAttributes(item)
+	}
+	for _, key := range stale {
+		live, ok := derived[key]
+		if k, alias := derivedKey[key]; alias {
+			live, ok = derived[k]
+		}
+		if ok {
+			attrs[key] = live
+		} else {
+			delete(attrs, key)
+		}
+	}
+}
+
+// derivedAttributes builds attributes from an item's Bitwarden fields.
+//
+// Every item carries label, xdg:schema, bitwarden:type and its text custom
+// fields. Type-specific attributes are:
+//   - login: username, service, domain, protocol, port, path
+//   - card: brand, cardholder
+//   - identity: email, company
+func derivedAttributes(item *bitwarden.Item) map[string]string {
 	attrs := make(map[string]string)
 
 	if item.Login != nil {
@@ -54,6 +162,16 @@ func ItemToAttributes(item *bitwarden.Item) map[string]string {
 		}
 	}
 
+	if item.Card != nil {
+		setIfPresent(attrs, AttrCardBrand, item.Card.Brand)
+		setIfPresent(attrs, AttrCardholder, item.Card.CardholderName)
+	}
+
+	if item.Identity != nil {
+		setIfPresent(attrs, AttrEmail, item.Identity.Email)
+		setIfPresent(attrs, AttrCompany, item.Identity.Company)
+	}
+
 	// Add item name as a searchable attribute
 	attrs["label"] = item.Name
 
@@ -62,116 +180,182 @@ func ItemToAttributes(item *bitwarden.Item) map[string]string {
 
 	// Add custom fields as attributes
 	for _, field := range item.Fields {
-		if field.Type == 0 { // text field
+		if field.Type == 0 && !strings.HasPrefix(field.Name, StoredAttributePrefix) { // text field
 			attrs[field.Name] = field.Value
 		}
 	}
 
+	// The type is set last so a custom field cannot shadow it
+	if typeName := ItemTypeName(item); typeName != "" {
+		attrs[AttrType] = typeName
+	}
+
 	return attrs
 }
 
-// MatchesAttributes checks if a Bitwarden item matches the given attributes
+// setIfPresent sets attrs[key] when value is non-nil and non-empty
+func setIfPresent(attrs map[string]string, key string, value *string) {
+	if value != nil && *value != "" {
+		attrs[key] = *value
+	}
+}
+
+// MatchesAttributes checks if a Bitwarden item matches the given attributes.
+// An attribute stored by StoreAttributes must equal the stored value, which
+// overrides the item's derived attributes, unless it mirrors a Bitwarden field
+// (see modeledAttribute). Other attributes match the derived ones: login items match URLs the way Bitwarden clients do (see MatchesURL),
+// other supported item types compare exactly. xdg:schema is only enforced for
+// items with a stored schema. bitwarden:secret matches items that have the selected secret;
+// bitwarden:secret=ssh-key also matches SSH key items, which are otherwise
+// not served. The vault attributes match exactly or as globs;
+// bitwarden:folder is skipped and must be checked by callers that know
+// folder names.
 func MatchesAttributes(item *bitwarden.Item, attrs map[string]string) bool {
-	// Only match login items for now
-	if item.Login == nil {
+	typeName := ItemTypeName(item)
+	if typeName == "" && !(item.Type == bitwarden.ItemTypeSSHKey && attrs[AttrSecret] == SecretSSHKey) {
+		return false
+	}
+	if typeName == TypeLogin && item.Login == nil {
 		return false
 	}
 
-	for key, value := range attrs {
-		switch key {
-		case AttrSchema:
-			// Skip schema matching for now - we handle all schemas
-			continue
-
-		case AttrService, AttrDomain, AttrServer:
-			// Match against URIs
-			if !matchesURI(item, value) {
-				return false
-			}
-
-		case AttrProtocol:
-			// Match protocol against URI scheme
-			if len(item.Login.URIs) == 0 {
-				return false
-			}
-			uri := item.Login.URIs[0].URI
-			if !strings.HasPrefix(strings.ToLower(uri), strings.ToLower(value)+"://") {
-				return false
-			}
-
-		case AttrPort:
-			// Match port against URI
-			if len(item.Login.URIs) == 0 {
-				return false
-			}
-			uri := item.Login.URIs[0].URI
-			if !matchesPort(uri, value) {
-				return false
-			}
+	stored := StoredAttributes(item)
+	var derived map[string]string // computed lazily by matchesLive
 
-		case AttrPath:
-			// Match path against URI
-			if len(item.Login.URIs) == 0 {
+	for key, value := range attrs {
+		if key == AttrSecret {
+			if !HasSecret(item, value) {
 				return false
 			}
-			uri := item.Login.URIs[0].URI
-			if !matchesPath(uri, value) {
+			continue
+		}
+		if key == AttrFolder {
+			continue
+		}
+		if IsVaultAttribute(key) {
+			if !matchesVaultAttribute(item, key, value) {
 				return false
 			}
+			continue
+		}
 
-		case AttrUsername, AttrUser:
-			// Match against username
-			if item.Login.Username == nil || *item.Login.Username != value {
+		// The recorded attribute set is authoritative for the keys it has,
+		// except those that mirror a Bitwarden field, which may have been
+		// edited since
+		if v, ok := stored[key]; ok && !modeledAttribute(item, key) {
+			if v != value {
 				return false
 			}
+			continue
+		}
 
-		case "label":
-			// Match against item name
-			if !strings.EqualFold(item.Name, value) {
-				return false
-			}
+		// Items without a stored schema accept every schema
+		if key == AttrSchema {
+			continue
+		}
 
-		default:
-			// Check custom fields
-			if !matchesField(item, key, value) {
-				return false
-			}
+		if !matchesLive(item, attrs, key, value, &derived) {
+			return false
 		}
 	}
 
 	return true
 }
 
-// matchesURI checks if any of the item's URIs match the given value
-func matchesURI(item *bitwarden.Item, value string) bool {
-	if item.Login == nil {
-		return false
+// modeledAttribute reports whether key mirrors a Bitwarden field of the item,
+// such as a login's username or URI, so the live field takes precedence over
+// a copy stored by StoreAttributes.
+func modeledAttribute(item *bitwarden.Item, key string) bool {
+	switch key {
+	case "label":
+		return true
+	case AttrUsername, AttrUser, AttrService, AttrDomain, AttrServer, AttrProtocol, AttrPort, AttrPath:
+		return ItemTypeName(item) == TypeLogin
+	case AttrCardBrand, AttrCardholder:
+		return item.Type == bitwarden.ItemTypeCard
+	case AttrEmail, AttrCompany:
+		return item.Type == bitwarden.ItemTypeIdentity
 	}
+	return false
+}
 
-	valueLower := strings.ToLower(value)
-	valueDomain := extractDomain(value)
-
-	for _, uri := range item.Login.URIs {
-		uriLower := strings.ToLower(uri.URI)
-		uriDomain := extractDomain(uri.URI)
+// matchesLive matches a single attribute against the item's Bitwarden fields.
+// derived caches derivedAttributes across calls for non-login items.
+func matchesLive(item *bitwarden.Item, attrs map[string]string, key, value string, derived *map[string]string) bool {
+	if ItemTypeName(item) == TypeLogin {
+		return matchesLoginAttribute(item, attrs, key, value)
+	}
+	if key == "label" {
+		return strings.EqualFold(item.Name, value)
+	}
+	if *derived == nil {
+		*derived = derivedAttributes(item)
+	}
+	got, ok := (*derived)[key]
+	return ok && got == value
+}
 
-		// Exact match
-		if uriLower == valueLower {
-			return true
+// matchesLoginAttribute matches a single attribute against a login item.
+// service, domain and server match when any URI of the item matches the URL
+// they describe (see queryURL) under its match detection type; protocol, port
+// and path match when any URI has that component.
+func matchesLoginAttribute(item *bitwarden.Item, attrs map[string]string, key, value string) bool {
+	switch key {
+	case AttrType:
+		return value == TypeLogin
+
+	case AttrService, AttrDomain, AttrServer:
+		return MatchesURL(item, queryURL(attrs, key))
+
+	case AttrProtocol:
+		// Match protocol against URI schemes
+		for _, uri := range componentURIs(item) {
+			if strings.HasPrefix(strings.ToLower(uri), strings.ToLower(value)+"://") {
+				return true
+			}
 		}
+		return false
 
-		// Domain match
-		if valueDomain != "" && uriDomain == valueDomain {
-			return true
+	case AttrPort:
+		for _, uri := range componentURIs(item) {
+			if matchesPort(uri, value) {
+				return true
+			}
 		}
+		return false
 
-		// Contains match (for partial URLs)
-		if strings.Contains(uriLower, valueLower) || strings.Contains(valueLower, uriLower) {
-			return true
+	case AttrPath:
+		for _, uri := range componentURIs(item) {
+			if matchesPath(uri, value) {
+				return true
+			}
 		}
+		return false
+
+	case AttrUsername, AttrUser:
+		// Match against username
+		return item.Login.Username != nil && *item.Login.Username == value
+
+	case "label":
+		// Match against item name
+		return strings.EqualFold(item.Name, value)
+
+	default:
+		// Check custom fields
+		return matchesField(item, key, value)
 	}
+}
 
-	return false
+// queryURL builds the URL a service, domain or server attribute stands for,
+// completed with the protocol, port and path attributes of the same search.
+func queryURL(attrs map[string]string, key string) string {
+	query := map[string]string{AttrService: attrs[key]}
+	for _, k := range []string{AttrProtocol, AttrPort, AttrPath} {
+		if v, ok := attrs[k]; ok {
+			query[k] = v
+		}
+	}
+	return BuildURIFromAttributes(query)
 }
 
 // matchesField checks if an item has a custom field matching the given key/value
@@ -393,6 +577,15 @@ func GetUsername(attrs map[string]string) string {
 var ReservedAttributes = map[string]bool{
 	"label":    true,
 	AttrSchema: true,
+	AttrType:   true,
+	AttrSecret: true,
+
+	AttrID:           true,
+	AttrName:         true,
+	AttrFolder:       true,
+	AttrOrganization: true,
+	AttrFavorite:     true,
+
 	"created":  true,
 	"modified": true,
 	"locked":   true,
@@ -403,6 +596,17 @@ var ReservedAttributes = map[string]bool{
 // unknown attributes as custom fields.
 // Note: This merges attributes (adds/updates) rather than replacing.
 func UpdateItemFromAttributes(item *bitwarden.Item, attrs map[string]string) {
+	applyAttributes(item, attrs, true)
+}
+
+// applyAttributes maps well-known attributes onto the item's Bitwarden fields.
+// Unknown attributes become custom fields when unknownAsFields is set.
+func applyAttributes(item *bitwarden.Item, attrs map[string]string, unknownAsFields bool) {
+	if typeName := ItemTypeName(item); typeName != "" && typeName != TypeLogin {
+		updateNonLoginFromAttributes(item, attrs, unknownAsFields)
+		return
+	}
+
 	// Track which attributes we've handled to avoid duplicate URI processing
 	handledURI := false
 
@@ -447,7 +651,7 @@ func UpdateItemFromAttributes(item *bitwarden.Item, attrs map[string]string) {
 			continue
 
 		default:
-			if ReservedAttributes[key] {
+			if ReservedAttributes[key] || !unknownAsFields {
 				continue // Skip system attributes
 			}
 			updateOrAddField(item, key, value)
@@ -455,6 +659,44 @@ func UpdateItemFromAttributes(item *bitwarden.Item, attrs map[string]string) {
 	}
 }
 
+// updateNonLoginFromAttributes applies attributes to a note, card or identity.
+// Type-specific attributes update the matching Bitwarden field; everything
+// else, including login attributes such as service, becomes a custom field.
+func updateNonLoginFromAttributes(item *bitwarden.Item, attrs map[string]string, unknownAsFields bool) {
+	for key, value := range attrs {
+		v := value
+		switch {
+		case ReservedAttributes[key]:
+			continue
+
+		case item.Type == bitwarden.ItemTypeCard && (key == AttrCardBrand || key == AttrCardholder):
+			if item.Card == nil {
+				item.Card = &bitwarden.Card{}
+			}
+			if key == AttrCardBrand {
+				item.Card.Brand = &v
+			} else {
+				item.Card.CardholderName = &v
+			}
+
+		case item.Type == bitwarden.ItemTypeIdentity && (key == AttrEmail || key == AttrCompany):
+			if item.Identity == nil {
+				item.Identity = &bitwarden.Identity{}
+			}
+			if key == AttrEmail {
+				item.Identity.Email = &v
+			} else {
+				item.Identity.Company = &v
+			}
+
+		default:
+			if unknownAsFields {
+				updateOrAddField(item, key, value)
+			}
+		}
+	}
+}
+
 // updateOrAddField updates an existing custom field or adds a new one
 func updateOrAddField(item *bitwarden.Item, name, value string) {
 	// Find existing field with same name
@@ -424,3 +424,124 @@ func TestBuildURIFromAttributes_ServiceWithComponents(t *testing.T) {
 		})
 	}
 }
+
+func TestItemToAttributes_NonLoginTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		item *bitwarden.Item
+		want map[string]string
+	}{
+		{
+			name: "secure note",
+			item: &bitwarden.Item{
+				Type:   bitwarden.ItemTypeSecureNote,
+				Name:   "API token",
+				Fields: []bitwarden.Field{{Name: "token", Value: "github", Type: 0}},
+			},
+			want: map[string]string{AttrType: TypeNote, "label": "API token", "token": "github"},
+		},
+		{
+			name: "card",
+			item: &bitwarden.Item{
+				Type: bitwarden.ItemTypeCard,
+				Name: "Visa",
+				Card: &bitwarden.Card{Brand: strPtr("Visa"), CardholderName: strPtr("Jane Doe"), Number: strPtr("4111")},
+			},
+			want: map[string]string{AttrType: TypeCard, AttrCardBrand: "Visa", AttrCardholder: "Jane Doe"},
+		},
+		{
+			name: "identity",
+			item: &bitwarden.Item{
+				Type:     bitwarden.ItemTypeIdentity,
+				Name:     "Work",
+				Identity: &bitwarden.Identity{Email: strPtr("jane@example.com"), Company: strPtr("ACME")},
+			},
+			want: map[string]string{AttrType: TypeIdentity, AttrEmail: "jane@example.com", AttrCompany: "ACME"},
+		},
+		{
+			name: "custom field cannot shadow type",
+			item: &bitwarden.Item{
+				Type:   bitwarden.ItemTypeSecureNote,
+				Fields: []bitwarden.Field{{Name: AttrType, Value: TypeLogin, Type: 0}},
+			},
+			want: map[string]string{AttrType: TypeNote},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := ItemToAttributes(tt.item)
+			for k, v := range tt.want {
+				if attrs[k] != v {
+					t.Errorf("attrs[%q] = %q, want %q", k, attrs[k], v)
+				}
+			}
+			if _, ok := attrs["number"]; ok {
+				t.Error("card number must not be exposed as an attribute")
+			}
+		})
+	}
+}
+
+func TestMatchesAttributes_NonLoginTypes(t *testing.T) {
+	note := &bitwarden.Item{
+		Type:   bitwarden.ItemTypeSecureNote,
+		Name:   "API token",
+		Fields: []bitwarden.Field{{Name: "token", Value: "github", Type: 0}},
+	}
+	card := &bitwarden.Item{
+		Type: bitwarden.ItemTypeCard,
+		Card: &bitwarden.Card{Brand: strPtr("Visa")},
+	}
+	login := &bitwarden.Item{
+		Type:  bitwarden.ItemTypeLogin,
+		Login: &bitwarden.Login{Username: strPtr("joe")},
+	}
+	sshKey := &bitwarden.Item{Type: bitwarden.ItemTypeSSHKey, Name: "key"}
+
+	tests := []struct {
+		name  string
+		item  *bitwarden.Item
+		attrs map[string]string
+		want  bool
+	}{
+		{"note by custom field", note, map[string]string{"token": "github"}, true},
+		{"note by type and field", note, map[string]string{AttrType: TypeNote, "token": "github"}, true},
+		{"note by label case-insensitive", note, map[string]string{"label": "api TOKEN"}, true},
+		{"note wrong type", note, map[string]string{AttrType: TypeCard}, false},
+		{"note never matches login attrs", note, map[string]string{"username": "joe"}, false},
+		{"card by brand", card, map[string]string{AttrCardBrand: "Visa"}, true},
+		{"card wrong brand", card, map[string]string{AttrCardBrand: "Amex"}, false},
+		{"login by type", login, map[string]string{AttrType: TypeLogin, "username": "joe"}, true},
+		{"login wrong type", login, map[string]string{AttrType: TypeNote}, false},
+		{"ssh keys are not served", sshKey, map[string]string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesAttributes(tt.item, tt.attrs); got != tt.want {
+				t.Errorf("MatchesAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateItemFromAttributes_NonLoginTypes(t *testing.T) {
+	card := &bitwarden.Item{Type: bitwarden.ItemTypeCard}
+	UpdateItemFromAttributes(card, map[string]string{AttrCardBrand: "Visa", AttrType: TypeCard, "service": "bank"})
+	if card.Card == nil || card.Card.Brand == nil || *card.Card.Brand != "Visa" {
+		t.Errorf("card brand not set: %+v", card.Card)
+	}
+	if card.Login != nil {
+		t.Error("login attributes must not create login data on a card")
+	}
+	if len(card.Fields) != 1 || card.Fields[0].Name != "service" {
+		t.Errorf("expected service stored as custom field, got %+v", card.Fields)
+	}
+
+	identity := &bitwarden.Item{Type: bitwarden.ItemTypeIdentity}
+	UpdateItemFromAttributes(identity, map[string]string{AttrEmail: "jane@example.com"})
+	if identity.Identity == nil || identity.Identity.Email == nil || *identity.Identity.Email != "jane@example.com" {
+		t.Errorf("identity email not set: %+v", identity.Identity)
+	}
+}
@@ -0,0 +1,21 @@
+package mapping
+
+import "github.com/joe/bitwarden-keyring/internal/bitwarden"
+
+// PortalAppIDFieldName is the custom field identifying the Flatpak app a
+// portal secret item belongs to. Such items are only handed to
+// xdg-desktop-portal and are hidden from every other interface.
+const PortalAppIDFieldName = "flatpak-app-id"
+
+// PortalAppID returns the app ID of a portal secret item, or "" for other items
+func PortalAppID(item *bitwarden.Item) string {
+	if item.Type != bitwarden.ItemTypeSecureNote {
+		return ""
+	}
+	for _, f := range item.Fields {
+		if f.Name == PortalAppIDFieldName {
+			return f.Value
+		}
+	}
+	return ""
+}
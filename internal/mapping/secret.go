@@ -0,0 +1,125 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+	"github.com/joe/bitwarden-keyring/internal/totp"
+)
+
+// Content types of item secrets
+const (
+	ContentTypeText = "text/plain"
+	ContentTypeJSON = "application/json"
+)
+
+// ItemSecret returns the secret payload of an item and its content type:
+//   - login: the password (text/plain)
+//   - note: the note body (text/plain)
+//   - card: the card number (text/plain)
+//   - identity: the identity fields as a JSON object (application/json)
+//
+// An item with a SecretFieldName field returns the secret it selects instead.
+func ItemSecret(item *bitwarden.Item) ([]byte, string, error) {
+	if selector := SecretSelector(item); selector != "" {
+		return SelectSecret(item, selector, time.Now())
+	}
+
+	switch ItemTypeName(item) {
+	case TypeLogin:
+		if item.Login == nil || item.Login.Password == nil {
+			return nil, "", fmt.Errorf("item has no password")
+		}
+		return []byte(*item.Login.Password), ContentTypeText, nil
+
+	case TypeNote:
+		if item.Notes == nil {
+			return []byte{}, ContentTypeText, nil
+		}
+		return []byte(*item.Notes), ContentTypeText, nil
+
+	case TypeCard:
+		if item.Card == nil || item.Card.Number == nil {
+			return nil, "", fmt.Errorf("item has no card number")
+		}
+		return []byte(*item.Card.Number), ContentTypeText, nil
+
+	case TypeIdentity:
+		identity := item.Identity
+		if identity == nil {
+			identity = &bitwarden.Identity{}
+		}
+		data, err := json.Marshal(identity)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode identity: %w", err)
+		}
+		return data, ContentTypeJSON, nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported item type: %d", item.Type)
+	}
+}
+
+// SetItemSecret stores a secret payload on an item. It is the inverse of
+// ItemSecret; identity payloads must be a JSON object using Bitwarden's
+// identity field names and replace the whole identity.
+func SetItemSecret(item *bitwarden.Item, value []byte) error {
+	secret := string(value)
+
+	if selector := SecretSelector(item); selector != "" {
+		return setSelectedSecret(item, selector, secret)
+	}
+
+	switch ItemTypeName(item) {
+	case TypeLogin:
+		if item.Login == nil {
+			return fmt.Errorf("item has no login credentials")
+		}
+		item.Login.Password = &secret
+
+	case TypeNote:
+		if item.SecureNote == nil {
+			item.SecureNote = &bitwarden.SecureNote{}
+		}
+		item.Notes = &secret
+
+	case TypeCard:
+		if item.Card == nil {
+			item.Card = &bitwarden.Card{}
+		}
+		item.Card.Number = &secret
+
+	case TypeIdentity:
+		var identity bitwarden.Identity
+		if err := json.Unmarshal(value, &identity); err != nil {
+			return fmt.Errorf("identity secret must be a JSON object: %w", err)
+		}
+		item.Identity = &identity
+
+	default:
+		return fmt.Errorf("unsupported item type: %d", item.Type)
+	}
+
+	return nil
+}
+
+// HasTOTP reports whether an item is a login with an authenticator key
+func HasTOTP(item *bitwarden.Item) bool {
+	return ItemTypeName(item) == TypeLogin && item.Login != nil &&
+		item.Login.TOTP != nil && *item.Login.TOTP != ""
+}
+
+// ItemTOTP returns the item's TOTP code at now and how long it stays valid
+func ItemTOTP(item *bitwarden.Item, now time.Time) (string, time.Duration, error) {
+	if !HasTOTP(item) {
+		return "", 0, fmt.Errorf("item has no TOTP key")
+	}
+	key, err := totp.Parse(*item.Login.TOTP)
+	if err != nil {
+		return "", 0, err
+	}
+	code, remaining := key.Code(now)
+	return code, remaining, nil
+}
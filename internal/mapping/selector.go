@@ -0,0 +1,117 @@
+package mapping
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+// SecretFieldName is the custom field choosing what an item returns as its
+// secret instead of the default for its type. Its value is a selector as
+// accepted by SelectSecret.
+const SecretFieldName = "keyring:secret-field"
+
+// Secret selectors naming item data. Any other selector names a custom field,
+// so a custom field called e.g. "notes" can't be selected.
+const (
+	SecretPassword   = "password"    // login password
+	SecretNotes      = "notes"       // notes of any item
+	SecretCardNumber = "card-number" // card number
+	SecretSSHKey     = "ssh-key"     // private key of an SSH key item
+	SecretTOTP       = "totp"        // current code of a login's TOTP key
+)
+
+// SecretSelector returns the selector an item's SecretFieldName field sets,
+// or "" if the item uses the default secret of its type
+func SecretSelector(item *bitwarden.Item) string {
+	for _, f := range item.Fields {
+		if f.Name == SecretFieldName {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// SelectSecret returns the item data named by selector as a text/plain
+// secret; TOTP codes are computed for now
+func SelectSecret(item *bitwarden.Item, selector string, now time.Time) ([]byte, string, error) {
+	switch selector {
+	case SecretPassword:
+		if item.Login == nil || item.Login.Password == nil {
+			return nil, "", fmt.Errorf("item has no password")
+		}
+		return []byte(*item.Login.Password), ContentTypeText, nil
+
+	case SecretNotes:
+		if item.Notes == nil {
+			return nil, "", fmt.Errorf("item has no notes")
+		}
+		return []byte(*item.Notes), ContentTypeText, nil
+
+	case SecretCardNumber:
+		if item.Card == nil || item.Card.Number == nil {
+			return nil, "", fmt.Errorf("item has no card number")
+		}
+		return []byte(*item.Card.Number), ContentTypeText, nil
+
+	case SecretSSHKey:
+		if item.SSHKey == nil || item.SSHKey.PrivateKey == "" {
+			return nil, "", fmt.Errorf("item has no SSH private key")
+		}
+		return []byte(item.SSHKey.PrivateKey), ContentTypeText, nil
+
+	case SecretTOTP:
+		code, _, err := ItemTOTP(item, now)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(code), ContentTypeText, nil
+
+	case "":
+		return nil, "", fmt.Errorf("empty secret selector")
+	}
+
+	for _, f := range item.Fields {
+		if f.Name == selector {
+			return []byte(f.Value), ContentTypeText, nil
+		}
+	}
+	return nil, "", fmt.Errorf("item has no field %q", selector)
+}
+
+// HasSecret reports whether SelectSecret can return the selected secret
+func HasSecret(item *bitwarden.Item, selector string) bool {
+	_, _, err := SelectSecret(item, selector, time.Now())
+	return err == nil
+}
+
+// setSelectedSecret stores value in the item data named by selector. TOTP
+// codes and SSH keys can't be written this way.
+func setSelectedSecret(item *bitwarden.Item, selector, value string) error {
+	switch selector {
+	case SecretPassword:
+		if item.Login == nil {
+			return fmt.Errorf("item has no login credentials")
+		}
+		item.Login.Password = &value
+	case SecretNotes:
+		item.Notes = &value
+	case SecretCardNumber:
+		if item.Card == nil {
+			item.Card = &bitwarden.Card{}
+		}
+		item.Card.Number = &value
+	case SecretSSHKey, SecretTOTP, "":
+		return fmt.Errorf("secret %q cannot be set", selector)
+	default:
+		for i := range item.Fields {
+			if item.Fields[i].Name == selector {
+				item.Fields[i].Value = value
+				return nil
+			}
+		}
+		item.Fields = append(item.Fields, bitwarden.Field{Name: selector, Value: value, Type: 1}) // hidden
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package mapping
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+// StoredAttributePrefix prefixes the names of the text custom fields that hold
+// the libsecret attributes of an item, e.g. "libsecret:xdg:schema".
+const StoredAttributePrefix = "libsecret:"
+
+// StoredAttributes returns the attributes recorded on an item by
+// StoreAttributes, or nil if the item has none.
+func StoredAttributes(item *bitwarden.Item) map[string]string {
+	var attrs map[string]string
+	for _, field := range item.Fields {
+		if field.Type != 0 || !strings.HasPrefix(field.Name, StoredAttributePrefix) {
+			continue
+		}
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[strings.TrimPrefix(field.Name, StoredAttributePrefix)] = field.Value
+	}
+	return attrs
+}
+
+// StoreAttributes records attrs on an item so that they round-trip exactly.
+// The whole set is written as prefixed text custom fields, replacing any set
+// stored before, and well-known attributes also update the matching Bitwarden
+// fields (login username and URI, card brand, identity email, ...) so the
+// item stays useful in Bitwarden itself. Vault attributes such as bitwarden:id
+// describe the item and are never recorded.
+func StoreAttributes(item *bitwarden.Item, attrs map[string]string) {
+	applyAttributes(item, attrs, false)
+
+	fields := make([]bitwarden.Field, 0, len(item.Fields)+len(attrs))
+	for _, field := range item.Fields {
+		if !strings.HasPrefix(field.Name, StoredAttributePrefix) {
+			fields = append(fields, field)
+		}
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		if IsVaultAttribute(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fields = append(fields, bitwarden.Field{
+			Name:  StoredAttributePrefix + key,
+			Value: attrs[key],
+			Type:  0, // text
+		})
+	}
+
+	if len(fields) == 0 {
+		fields = nil
+	}
+	item.Fields = fields
+}
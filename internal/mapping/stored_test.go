@@ -0,0 +1,157 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+func TestStoreAttributes_RoundTrip(t *testing.T) {
+	attrs := map[string]string{
+		AttrSchema:                  "org.gnome.Evolution.Data.Source",
+		"e-source-uid":              "1234abcd",
+		"eds-kind":                  "Collection",
+		"application":               "evolution",
+		AttrUsername:                "joe",
+		AttrServer:                  "imap.example.com",
+		"org.gnome.Evolution.extra": "value with spaces",
+	}
+
+	item := &bitwarden.Item{ID: "id-1", Name: "Mail", Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{}}
+	StoreAttributes(item, attrs)
+
+	// The stored set comes back alongside the vault attributes
+	want := map[string]string{AttrID: "id-1", AttrName: "Mail", AttrFavorite: "false"}
+	for k, v := range attrs {
+		want[k] = v
+	}
+	if got := ItemToAttributes(item); !reflect.DeepEqual(got, want) {
+		t.Errorf("ItemToAttributes() = %v, want exactly %v", got, want)
+	}
+
+	// Well-known attributes still populate the Bitwarden login
+	if item.Login.Username == nil || *item.Login.Username != "joe" {
+		t.Errorf("username = %v, want joe", item.Login.Username)
+	}
+	if len(item.Login.URIs) != 1 || item.Login.URIs[0].URI != "https://imap.example.com" {
+		t.Errorf("URIs = %+v, want https://imap.example.com", item.Login.URIs)
+	}
+
+	// Every attribute is a prefixed text field; nothing is stored unprefixed
+	for _, f := range item.Fields {
+		if f.Type != 0 || len(f.Name) <= len(StoredAttributePrefix) || f.Name[:len(StoredAttributePrefix)] != StoredAttributePrefix {
+			t.Errorf("unexpected field %q (type %d)", f.Name, f.Type)
+		}
+	}
+}
+
+func TestStoreAttributes_ReplacesPreviousSet(t *testing.T) {
+	item := &bitwarden.Item{
+		Type:   bitwarden.ItemTypeLogin,
+		Login:  &bitwarden.Login{},
+		Fields: []bitwarden.Field{{Name: "kept", Value: "user field", Type: 0}},
+	}
+
+	StoreAttributes(item, map[string]string{"a": "1", "b": "2"})
+	StoreAttributes(item, map[string]string{"b": "3"})
+
+	want := map[string]string{"b": "3"}
+	if got := StoredAttributes(item); !reflect.DeepEqual(got, want) {
+		t.Errorf("StoredAttributes() = %v, want %v", got, want)
+	}
+	if item.Fields[0].Name != "kept" {
+		t.Errorf("non-attribute custom fields must be preserved, got %+v", item.Fields)
+	}
+}
+
+func TestStoredAttributes_NoneForLegacyItems(t *testing.T) {
+	item := &bitwarden.Item{
+		Type:   bitwarden.ItemTypeLogin,
+		Login:  &bitwarden.Login{Username: strPtr("joe")},
+		Fields: []bitwarden.Field{{Name: "custom", Value: "x", Type: 0}},
+	}
+	if got := StoredAttributes(item); got != nil {
+		t.Errorf("StoredAttributes() = %v, want nil", got)
+	}
+	attrs := ItemToAttributes(item)
+	if attrs[AttrUsername] != "joe" || attrs["custom"] != "x" {
+		t.Errorf("legacy derivation broken: %v", attrs)
+	}
+}
+
+func TestMatchesAttributes_StoredAttributes(t *testing.T) {
+	stored := &bitwarden.Item{
+		Type:  bitwarden.ItemTypeLogin,
+		Name:  "Mail",
+		Login: &bitwarden.Login{},
+	}
+	StoreAttributes(stored, map[string]string{
+		AttrSchema:     "org.gnome.keyring.NetworkPassword",
+		AttrServer:     "imap.example.com",
+		AttrUser:       "joe",
+		"e-source-uid": "1234",
+	})
+
+	// Username edited in Bitwarden after the attributes were stored
+	edited := &bitwarden.Item{Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{}}
+	StoreAttributes(edited, map[string]string{AttrUser: "joe"})
+	edited.Login.Username = strPtr("jane")
+
+	legacy := &bitwarden.Item{
+		Type:  bitwarden.ItemTypeLogin,
+		Login: &bitwarden.Login{Username: strPtr("joe"), URIs: []bitwarden.URI{{URI: "https://imap.example.com"}}},
+	}
+
+	tests := []struct {
+		name  string
+		item  *bitwarden.Item
+		attrs map[string]string
+		want  bool
+	}{
+		{"stored exact", stored, map[string]string{"e-source-uid": "1234", AttrUser: "joe"}, true},
+		{"stored wrong value", stored, map[string]string{"e-source-uid": "9999"}, false},
+		{"stored schema matches", stored, map[string]string{AttrSchema: "org.gnome.keyring.NetworkPassword"}, true},
+		{"stored schema mismatch", stored, map[string]string{AttrSchema: SchemaGenericSecret, AttrUser: "joe"}, false},
+		{"stored falls back to derived domain", stored, map[string]string{AttrDomain: "imap.example.com"}, true},
+		{"stored falls back to label", stored, map[string]string{"label": "mail"}, true},
+		{"live username overrides stored copy", edited, map[string]string{AttrUser: "jane"}, true},
+		{"stale stored username", edited, map[string]string{AttrUser: "joe"}, false},
+		{"legacy ignores schema", legacy, map[string]string{AttrSchema: "anything", AttrUsername: "joe"}, true},
+		{"legacy does not see stored keys", legacy, map[string]string{"e-source-uid": "1234"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesAttributes(tt.item, tt.attrs); got != tt.want {
+				t.Errorf("MatchesAttributes(%v) = %v, want %v", tt.attrs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemToAttributes_EditedLogin(t *testing.T) {
+	item := &bitwarden.Item{ID: "id-1", Name: "Mail", Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{}}
+	StoreAttributes(item, map[string]string{
+		AttrUsername:   "joe",
+		AttrServer:     "imap.example.com",
+		AttrPort:       "993",
+		"e-source-uid": "1234",
+	})
+
+	// Edited in Bitwarden after the attributes were stored
+	item.Login.Username = strPtr("jane")
+	item.Login.URIs = []bitwarden.URI{{URI: "https://mail.example.org"}}
+
+	want := map[string]string{
+		AttrUsername:   "jane",
+		AttrServer:     "mail.example.org",
+		"e-source-uid": "1234",
+		AttrID:         "id-1",
+		AttrName:       "Mail",
+		AttrFavorite:   "false",
+	}
+	if got := ItemToAttributes(item); !reflect.DeepEqual(got, want) {
+		t.Errorf("ItemToAttributes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package mapping
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+// MatchesURL reports whether any of a login item's URIs matches rawURL under
+// its match detection type, the way Bitwarden clients decide which logins to
+// offer for a page.
+func MatchesURL(item *bitwarden.Item, rawURL string) bool {
+	if item.Login == nil || rawURL == "" {
+		return false
+	}
+	for _, u := range item.Login.URIs {
+		if URIMatches(u, rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// URIMatches reports whether rawURL is matched by a single item URI:
+//   - domain (the default): same registrable domain, per the public suffix list
+//   - host: same host and port
+//   - starts with: rawURL starts with the URI
+//   - exact: rawURL equals the URI
+//   - regular expression: the URI matches rawURL, ignoring case
+//   - never: no match
+func URIMatches(u bitwarden.URI, rawURL string) bool {
+	if u.URI == "" {
+		return false
+	}
+
+	switch uriMatchType(u) {
+	case bitwarden.URIMatchDomain:
+		domain := registrableDomain(hostname(rawURL))
+		return domain != "" && domain == registrableDomain(hostname(u.URI))
+
+	case bitwarden.URIMatchHost:
+		host := hostWithPort(rawURL)
+		return host != "" && host == hostWithPort(u.URI)
+
+	case bitwarden.URIMatchStartsWith:
+		return strings.HasPrefix(rawURL, u.URI)
+
+	case bitwarden.URIMatchExact:
+		return rawURL == u.URI
+
+	case bitwarden.URIMatchRegularExpression:
+		re, err := regexp.Compile("(?i)" + u.URI)
+		return err == nil && re.MatchString(rawURL)
+
+	default: // never, or a type this version doesn't know
+		return false
+	}
+}
+
+// uriMatchType returns the match detection type of a URI
+func uriMatchType(u bitwarden.URI) int {
+	if u.Match == nil {
+		return bitwarden.URIMatchDomain
+	}
+	return *u.Match
+}
+
+// componentURIs returns the item's URIs that describe a location, skipping
+// regular expressions and URIs set to never match. The protocol, port and
+// path attributes are matched against these.
+func componentURIs(item *bitwarden.Item) []string {
+	var uris []string
+	for _, u := range item.Login.URIs {
+		switch uriMatchType(u) {
+		case bitwarden.URIMatchRegularExpression, bitwarden.URIMatchNever:
+			continue
+		}
+		uris = append(uris, u.URI)
+	}
+	return uris
+}
+
+// parseURL parses a URL, accepting bare hostnames as Bitwarden clients do
+func parseURL(raw string) *url.URL {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	return u
+}
+
+// hostname returns the lowercased host of a URL without its port
+func hostname(raw string) string {
+	u := parseURL(raw)
+	if u == nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// hostWithPort returns the lowercased host of a URL with its port, leaving
+// out the scheme's default port
+func hostWithPort(raw string) string {
+	u := parseURL(raw)
+	if u == nil {
+		return ""
+	}
+	port := u.Port()
+	if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		port = ""
+	}
+	host := strings.ToLower(u.Hostname())
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// registrableDomain returns the domain a host is registered under, such as
+// example.co.uk for www.example.co.uk. IP addresses, single-label hosts and
+// hosts that are themselves public suffixes are returned unchanged.
+func registrableDomain(host string) string {
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+	host = strings.TrimSuffix(host, ".")
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
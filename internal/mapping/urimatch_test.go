@@ -0,0 +1,84 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+func matchURI(uri string, match int) bitwarden.URI {
+	return bitwarden.URI{URI: uri, Match: &match}
+}
+
+func TestURIMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  bitwarden.URI
+		url  string
+		want bool
+	}{
+		{"default is base domain", bitwarden.URI{URI: "https://example.com"}, "https://login.example.com/x", true},
+		{"domain ignores scheme and port", matchURI("http://example.com:8080", bitwarden.URIMatchDomain), "https://www.example.com", true},
+		{"domain other site", bitwarden.URI{URI: "https://example.com"}, "https://example.org", false},
+		{"domain public suffix aware", bitwarden.URI{URI: "https://a.co.uk"}, "https://b.co.uk", false},
+		{"domain below public suffix", bitwarden.URI{URI: "https://www.bank.co.uk"}, "https://login.bank.co.uk", true},
+		{"domain bare host", bitwarden.URI{URI: "mail.example.com"}, "https://example.com", true},
+		{"domain ip address", bitwarden.URI{URI: "https://10.0.0.1:8443"}, "https://10.0.0.1", true},
+		{"domain single label", bitwarden.URI{URI: "http://nas"}, "https://nas/login", true},
+		{"host", matchURI("https://login.example.com", bitwarden.URIMatchHost), "https://login.example.com/a", true},
+		{"host other subdomain", matchURI("https://login.example.com", bitwarden.URIMatchHost), "https://www.example.com", false},
+		{"host compares port", matchURI("https://example.com:8443", bitwarden.URIMatchHost), "https://example.com", false},
+		{"host default port", matchURI("https://example.com:443", bitwarden.URIMatchHost), "https://example.com", true},
+		{"starts with", matchURI("https://example.com/app", bitwarden.URIMatchStartsWith), "https://example.com/app/login", true},
+		{"starts with other path", matchURI("https://example.com/app", bitwarden.URIMatchStartsWith), "https://example.com/", false},
+		{"exact", matchURI("https://example.com/login", bitwarden.URIMatchExact), "https://example.com/login", true},
+		{"exact differs", matchURI("https://example.com/login", bitwarden.URIMatchExact), "https://example.com/login?next=/", false},
+		{"regex ignores case", matchURI(`^https://.*\.EXAMPLE\.com/`, bitwarden.URIMatchRegularExpression), "https://a.example.com/", true},
+		{"regex no match", matchURI(`^https://a\.`, bitwarden.URIMatchRegularExpression), "https://b.example.com", false},
+		{"regex invalid", matchURI(`(`, bitwarden.URIMatchRegularExpression), "(", false},
+		{"never", matchURI("https://example.com", bitwarden.URIMatchNever), "https://example.com", false},
+		{"empty uri", bitwarden.URI{}, "https://example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URIMatches(tt.uri, tt.url); got != tt.want {
+				t.Errorf("URIMatches(%q, %q) = %v, want %v", tt.uri.URI, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesAttributes_AllURIs(t *testing.T) {
+	item := &bitwarden.Item{
+		Type: bitwarden.ItemTypeLogin,
+		Login: &bitwarden.Login{URIs: []bitwarden.URI{
+			matchURI("https://old.example.org", bitwarden.URIMatchNever),
+			{URI: "https://example.com"},
+			matchURI("http://intranet.corp:8080/wiki", bitwarden.URIMatchExact),
+		}},
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  bool
+	}{
+		{"first matching uri", map[string]string{AttrService: "www.example.com"}, true},
+		{"never uri ignored", map[string]string{AttrDomain: "old.example.org"}, false},
+		{"exact uri with components", map[string]string{AttrServer: "intranet.corp", AttrProtocol: "http", AttrPort: "8080", AttrPath: "/wiki"}, true},
+		{"exact uri needs the full url", map[string]string{AttrServer: "intranet.corp", AttrProtocol: "http", AttrPort: "8080"}, false},
+		{"protocol of any uri", map[string]string{AttrProtocol: "http"}, true},
+		{"port of any uri", map[string]string{AttrPort: "8080"}, true},
+		{"path of any uri", map[string]string{AttrPath: "/wiki"}, true},
+		{"no longer a substring match", map[string]string{AttrService: "example"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesAttributes(item, tt.attrs); got != tt.want {
+				t.Errorf("MatchesAttributes(%v) = %v, want %v", tt.attrs, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package mapping
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+// Attributes identifying an item in the vault. Every item carries them, they
+// are never stored on items, and in searches their values match exactly or as
+// MatchPattern globs.
+const (
+	AttrID           = "bitwarden:id"
+	AttrName         = "bitwarden:name"         // item name, case-sensitive unlike label
+	AttrFolder       = "bitwarden:folder"       // folder name, empty outside folders
+	AttrOrganization = "bitwarden:organization" // organization ID, empty for personal items
+	AttrFavorite     = "bitwarden:favorite"     // "true" or "false"
+)
+
+// vaultAttributes lists the attributes above
+var vaultAttributes = map[string]bool{
+	AttrID:           true,
+	AttrName:         true,
+	AttrFolder:       true,
+	AttrOrganization: true,
+	AttrFavorite:     true,
+}
+
+// IsVaultAttribute reports whether key is one of the bitwarden:id family
+func IsVaultAttribute(key string) bool {
+	return vaultAttributes[key]
+}
+
+// MatchPattern reports whether value equals pattern or matches it as a glob
+// in path.Match syntax, where * and ? don't match "/". Malformed patterns
+// only match exactly.
+func MatchPattern(value, pattern string) bool {
+	if value == pattern {
+		return true
+	}
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
+
+// addVaultAttributes adds the vault attributes known from the item itself.
+// bitwarden:folder is left to callers, as items only carry the folder ID.
+func addVaultAttributes(attrs map[string]string, item *bitwarden.Item) {
+	attrs[AttrID] = item.ID
+	attrs[AttrName] = item.Name
+	if item.OrganizationID != nil && *item.OrganizationID != "" {
+		attrs[AttrOrganization] = *item.OrganizationID
+	} else {
+		delete(attrs, AttrOrganization)
+	}
+	attrs[AttrFavorite] = strconv.FormatBool(item.Favorite)
+}
+
+// matchesVaultAttribute matches a vault attribute other than bitwarden:folder
+// against an item
+func matchesVaultAttribute(item *bitwarden.Item, key, value string) bool {
+	var got string
+	switch key {
+	case AttrID:
+		got = item.ID
+	case AttrName:
+		got = item.Name
+	case AttrOrganization:
+		if item.OrganizationID != nil {
+			got = *item.OrganizationID
+		}
+	case AttrFavorite:
+		got = strconv.FormatBool(item.Favorite)
+	}
+	return MatchPattern(got, value)
+}
@@ -0,0 +1,108 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/joe/bitwarden-keyring/internal/bitwarden"
+)
+
+func TestItemToAttributes_VaultAttributes(t *testing.T) {
+	org := "org-1"
+	item := &bitwarden.Item{
+		ID:             "0b6f0f5e",
+		Name:           "GitHub",
+		Type:           bitwarden.ItemTypeLogin,
+		OrganizationID: &org,
+		Favorite:       true,
+		Login:          &bitwarden.Login{},
+	}
+
+	attrs := ItemToAttributes(item)
+	want := map[string]string{
+		AttrID:           "0b6f0f5e",
+		AttrName:         "GitHub",
+		AttrOrganization: "org-1",
+		AttrFavorite:     "true",
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("attrs[%q] = %q, want %q", k, attrs[k], v)
+		}
+	}
+	if _, ok := attrs[AttrFolder]; ok {
+		t.Error("bitwarden:folder is set by callers, not ItemToAttributes")
+	}
+
+	// Personal items have no organization attribute
+	item.OrganizationID = nil
+	if _, ok := ItemToAttributes(item)[AttrOrganization]; ok {
+		t.Error("personal item has bitwarden:organization")
+	}
+}
+
+func TestMatchesAttributes_VaultAttributes(t *testing.T) {
+	org := "org-1"
+	item := &bitwarden.Item{
+		ID:             "0b6f0f5e",
+		Name:           "GitHub",
+		Type:           bitwarden.ItemTypeSecureNote,
+		OrganizationID: &org,
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  bool
+	}{
+		{"id exact", map[string]string{AttrID: "0b6f0f5e"}, true},
+		{"id glob", map[string]string{AttrID: "0b6f*"}, true},
+		{"id mismatch", map[string]string{AttrID: "ffff"}, false},
+		{"name is case-sensitive", map[string]string{AttrName: "github"}, false},
+		{"name glob", map[string]string{AttrName: "Git?ub"}, true},
+		{"organization", map[string]string{AttrOrganization: "org-*"}, true},
+		{"favorite", map[string]string{AttrFavorite: "true"}, false},
+		{"not favorite", map[string]string{AttrFavorite: "false"}, true},
+		{"folder left to callers", map[string]string{AttrFolder: "Work"}, true},
+		{"combined with type", map[string]string{AttrID: "0b6f0f5e", AttrType: TypeCard}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesAttributes(item, tt.attrs); got != tt.want {
+				t.Errorf("MatchesAttributes(%v) = %v, want %v", tt.attrs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreAttributes_SkipsVaultAttributes(t *testing.T) {
+	item := &bitwarden.Item{ID: "id-1", Name: "Mail", Type: bitwarden.ItemTypeLogin, Login: &bitwarden.Login{}}
+	StoreAttributes(item, map[string]string{AttrID: "other", AttrFolder: "Work", "application": "mail"})
+
+	stored := StoredAttributes(item)
+	if len(stored) != 1 || stored["application"] != "mail" {
+		t.Errorf("StoredAttributes() = %v, want only application", stored)
+	}
+	if got := ItemToAttributes(item)[AttrID]; got != "id-1" {
+		t.Errorf("bitwarden:id = %q, want the item's ID", got)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		value, pattern string
+		want           bool
+	}{
+		{"Work", "Work", true},
+		{"Work", "W*", true},
+		{"Work/Servers", "Work/*", true},
+		{"Work/Servers", "W*", false},
+		{"[odd]", "[odd]", true},
+		{"x", "[", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.value, tt.pattern); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.value, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -303,41 +303,91 @@ func (s *PasswordSession) close() error {
 
 // RequestPasswordWithSession sends a password request to the Noctalia agent and returns
 // a session that can be used to send unlock results and handle retries.
+// parentWindow, if set, names the window the dialog belongs to (see KeyringRequest).
 // The caller must close the session when done.
-func (c *Client) RequestPasswordWithSession(ctx context.Context, title, message string) (string, *PasswordSession, error) {
-	// Validate socket security before attempting connection
-	if err := c.ValidateSocket(); err != nil {
+func (c *Client) RequestPasswordWithSession(ctx context.Context, title, message, parentWindow string) (string, *PasswordSession, error) {
+	resp, session, err := c.exchange(ctx, KeyringRequest{
+		Type:         MessageTypeRequest,
+		Title:        title,
+		Message:      message,
+		Description:  "",
+		PasswordNew:  false,
+		ConfirmOnly:  false,
+		ParentWindow: parentWindow,
+	})
+	if err != nil {
 		return "", nil, err
 	}
 
-	// Generate unique cookie for this request
-	cookie, err := generateCookie()
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to generate cookie: %w", err)
+	// Handle result
+	switch resp.Result {
+	case ResultOK:
+		return resp.Password, session, nil
+	case ResultCancelled:
+		session.Close()
+		return "", nil, ErrCancelled
+	case ResultConfirmed:
+		session.Close()
+		return "", nil, ErrConfirmOnly
+	default:
+		session.Close()
+		return "", nil, fmt.Errorf("%w: unknown result: %s", ErrProtocolError, resp.Result)
 	}
+}
 
-	// Create request
-	req := KeyringRequest{
+// RequestConfirmation sends a confirm-only request to the Noctalia agent.
+// It returns nil if the user confirmed and ErrCancelled if they declined or
+// closed the dialog.
+func (c *Client) RequestConfirmation(ctx context.Context, title, message, description string) error {
+	resp, session, err := c.exchange(ctx, KeyringRequest{
 		Type:        MessageTypeRequest,
-		Cookie:      cookie,
 		Title:       title,
 		Message:     message,
-		Description: "",
-		PasswordNew: false,
-		ConfirmOnly: false,
+		Description: description,
+		ConfirmOnly: true,
+	})
+	if err != nil {
+		return err
 	}
+	session.Close()
+
+	switch resp.Result {
+	case ResultConfirmed, ResultOK:
+		return nil
+	case ResultCancelled:
+		return ErrCancelled
+	default:
+		return fmt.Errorf("%w: unknown result: %s", ErrProtocolError, resp.Result)
+	}
+}
+
+// exchange sends req (with a fresh cookie) to the Noctalia agent and reads the
+// matching response. The returned session keeps the connection open; the
+// caller must close it.
+func (c *Client) exchange(ctx context.Context, req KeyringRequest) (*KeyringResponse, *PasswordSession, error) {
+	// Validate socket security before attempting connection
+	if err := c.ValidateSocket(); err != nil {
+		return nil, nil, err
+	}
+
+	// Generate unique cookie for this request
+	cookie, err := generateCookie()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate cookie: %w", err)
+	}
+	req.Cookie = cookie
 
 	// Connect to socket with timeout
 	dialer := net.Dialer{Timeout: connectTimeout}
 	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			return "", nil, ErrCancelled
+			return nil, nil, ErrCancelled
 		}
 		if errors.Is(err, context.DeadlineExceeded) {
-			return "", nil, ErrTimeout
+			return nil, nil, ErrTimeout
 		}
-		return "", nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+		return nil, nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 
 	// Set read deadline based on timeout
@@ -347,20 +397,20 @@ func (c *Client) RequestPasswordWithSession(ctx context.Context, title, message
 	}
 	if err := conn.SetReadDeadline(deadline); err != nil {
 		conn.Close()
-		return "", nil, fmt.Errorf("failed to set read deadline: %w", err)
+		return nil, nil, fmt.Errorf("failed to set read deadline: %w", err)
 	}
 
 	// Send request as newline-delimited JSON
 	reqBytes, err := json.Marshal(req)
 	if err != nil {
 		conn.Close()
-		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 	reqBytes = append(reqBytes, '\n')
 
 	if _, err := conn.Write(reqBytes); err != nil {
 		conn.Close()
-		return "", nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	// Wait for response with context cancellation support
@@ -369,38 +419,38 @@ func (c *Client) RequestPasswordWithSession(ctx context.Context, title, message
 	if err != nil {
 		conn.Close()
 		if errors.Is(err, context.Canceled) {
-			return "", nil, ErrCancelled
+			return nil, nil, ErrCancelled
 		}
 		if errors.Is(err, context.DeadlineExceeded) {
-			return "", nil, ErrTimeout
+			return nil, nil, ErrTimeout
 		}
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return "", nil, ErrTimeout
+			return nil, nil, ErrTimeout
 		}
 		// Connection closed without response = user cancelled (closed the window)
 		if errors.Is(err, io.EOF) {
-			return "", nil, ErrCancelled
+			return nil, nil, ErrCancelled
 		}
-		return "", nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Parse response
 	var resp KeyringResponse
 	if err := json.Unmarshal(respLine, &resp); err != nil {
 		conn.Close()
-		return "", nil, fmt.Errorf("%w: invalid JSON response: %v", ErrProtocolError, err)
+		return nil, nil, fmt.Errorf("%w: invalid JSON response: %v", ErrProtocolError, err)
 	}
 
 	// Validate response type
 	if resp.Type != MessageTypeResponse {
 		conn.Close()
-		return "", nil, fmt.Errorf("%w: unexpected response type: %s", ErrProtocolError, resp.Type)
+		return nil, nil, fmt.Errorf("%w: unexpected response type: %s", ErrProtocolError, resp.Type)
 	}
 
 	// Validate cookie matches
 	if resp.ID != cookie {
 		conn.Close()
-		return "", nil, fmt.Errorf("%w: expected %s, got %s", ErrCookieMismatch, cookie, resp.ID)
+		return nil, nil, fmt.Errorf("%w: expected %s, got %s", ErrCookieMismatch, cookie, resp.ID)
 	}
 
 	// Create session to keep connection open
@@ -408,21 +458,7 @@ func (c *Client) RequestPasswordWithSession(ctx context.Context, title, message
 		conn:   conn,
 		cookie: cookie,
 	}
-
-	// Handle result
-	switch resp.Result {
-	case ResultOK:
-		return resp.Password, session, nil
-	case ResultCancelled:
-		session.Close()
-		return "", nil, ErrCancelled
-	case ResultConfirmed:
-		session.Close()
-		return "", nil, ErrConfirmOnly
-	default:
-		session.Close()
-		return "", nil, fmt.Errorf("%w: unknown result: %s", ErrProtocolError, resp.Result)
-	}
+	return &resp, session, nil
 }
 
 // readWithContext reads from the connection with context cancellation support.
@@ -470,7 +506,7 @@ func generateCookie() (string, error) {
 // RequestPassword is a convenience wrapper that requests a password without session support.
 // For retry support, use RequestPasswordWithSession instead.
 func (c *Client) RequestPassword(ctx context.Context, title, message string) (string, error) {
-	password, session, err := c.RequestPasswordWithSession(ctx, title, message)
+	password, session, err := c.RequestPasswordWithSession(ctx, title, message, "")
 	if err != nil {
 		return "", err
 	}
@@ -484,6 +484,38 @@ func TestClient_RequestPassword_Success(t *testing.T) {
 	}
 }
 
+func TestClient_RequestPasswordWithSession_ParentWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+	socketPath := filepath.Join(tmpDir, "test.sock")
+
+	server := NewMockServer(t, socketPath)
+	defer server.Close()
+
+	client := NewClient(
+		WithSocketPath(socketPath),
+		WithTimeout(5*time.Second),
+	)
+
+	go func() {
+		req := server.GetRequest(t, 2*time.Second)
+		if req.ParentWindow != "wayland:abc" {
+			t.Errorf("Expected parent window 'wayland:abc', got %q", req.ParentWindow)
+		}
+		server.RespondWith(KeyringResponse{
+			Type:     "keyring_response",
+			ID:       req.Cookie,
+			Result:   ResultOK,
+			Password: "secret123",
+		})
+	}()
+
+	_, session, err := client.RequestPasswordWithSession(context.Background(), "Test", "Enter password", "wayland:abc")
+	if err != nil {
+		t.Fatalf("RequestPasswordWithSession() error = %v", err)
+	}
+	session.Close()
+}
+
 func TestClient_RequestPassword_Cancelled(t *testing.T) {
 	tmpDir := t.TempDir()
 	socketPath := filepath.Join(tmpDir, "test.sock")
@@ -514,6 +546,48 @@ func TestClient_RequestPassword_Cancelled(t *testing.T) {
 	}
 }
 
+func TestClient_RequestConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		wantErr error
+	}{
+		{"confirmed", ResultConfirmed, nil},
+		{"cancelled", ResultCancelled, ErrCancelled},
+		{"unknown result", "maybe", ErrProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := filepath.Join(t.TempDir(), "test.sock")
+			server := NewMockServer(t, socketPath)
+			defer server.Close()
+
+			client := NewClient(WithSocketPath(socketPath), WithTimeout(5*time.Second))
+
+			go func() {
+				req := server.GetRequest(t, 2*time.Second)
+				if !req.ConfirmOnly {
+					t.Error("Expected confirm_only request")
+				}
+				if req.Description != "details" {
+					t.Errorf("Expected description 'details', got %q", req.Description)
+				}
+				server.RespondWith(KeyringResponse{
+					Type:   "keyring_response",
+					ID:     req.Cookie,
+					Result: tt.result,
+				})
+			}()
+
+			err := client.RequestConfirmation(context.Background(), "Test", "Allow?", "details")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RequestConfirmation() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestClient_RequestPassword_Timeout(t *testing.T) {
 	tmpDir := t.TempDir()
 	socketPath := filepath.Join(tmpDir, "test.sock")
@@ -764,7 +838,7 @@ func TestClient_FullRetryCycle(t *testing.T) {
 	ctx := context.Background()
 
 	// Step 1: Request password with session
-	password, session, err := client.RequestPasswordWithSession(ctx, "Test Title", "Enter password")
+	password, session, err := client.RequestPasswordWithSession(ctx, "Test Title", "Enter password", "")
 	if err != nil {
 		t.Fatalf("RequestPasswordWithSession() error = %v", err)
 	}
@@ -835,7 +909,7 @@ func TestClient_WaitForRetry_Timeout(t *testing.T) {
 	ctx := context.Background()
 
 	// Get initial password
-	password, session, err := client.RequestPasswordWithSession(ctx, "Test", "Enter password")
+	password, session, err := client.RequestPasswordWithSession(ctx, "Test", "Enter password", "")
 	if err != nil {
 		t.Fatalf("RequestPasswordWithSession() error = %v", err)
 	}
@@ -899,7 +973,7 @@ func TestClient_CookieMismatch(t *testing.T) {
 	ctx := context.Background()
 
 	// Get initial password
-	password, session, err := client.RequestPasswordWithSession(ctx, "Test", "Enter password")
+	password, session, err := client.RequestPasswordWithSession(ctx, "Test", "Enter password", "")
 	if err != nil {
 		t.Fatalf("RequestPasswordWithSession() error = %v", err)
 	}
@@ -1002,7 +1076,7 @@ func TestClient_WaitForRetry_ContextCancelled(t *testing.T) {
 	ctx := context.Background()
 
 	// Get initial password
-	password, session, err := client.RequestPasswordWithSession(ctx, "Test", "Enter password")
+	password, session, err := client.RequestPasswordWithSession(ctx, "Test", "Enter password", "")
 	if err != nil {
 		t.Fatalf("RequestPasswordWithSession() error = %v", err)
 	}
@@ -14,6 +14,10 @@ type KeyringRequest struct {
 	Description string `json:"description"`  // Optional description
 	PasswordNew bool   `json:"password_new"` // Whether this is for a new password
 	ConfirmOnly bool   `json:"confirm_only"` // Whether to just confirm (no password input)
+
+	// ParentWindow is the requesting application's window as "x11:<xid>" or
+	// "wayland:<handle>", omitted if unknown
+	ParentWindow string `json:"parent_window,omitempty"`
 }
 
 // KeyringResponse is received from the Noctalia Quickshell plugin after the user
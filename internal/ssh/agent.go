@@ -25,6 +25,7 @@ type Server struct {
 	wg         sync.WaitGroup
 	debug      bool
 	started    bool                  // tracks if server is running
+	onActivity func()                // called for every client connection
 	conns      map[net.Conn]struct{} // active connections
 	connsMu    sync.Mutex            // protects conns map
 }
@@ -37,6 +38,18 @@ func NewServer(socketPath string, client *bitwarden.Client) *Server {
 	}
 }
 
+// SetConfirmer sets how the user is asked before a key that requires
+// confirmation signs anything.
+func (s *Server) SetConfirmer(c Confirmer) {
+	s.keyring.SetConfirmer(c)
+}
+
+// SetActivityHook sets a function called whenever a client connects,
+// before the connection is served. It must be called before Start.
+func (s *Server) SetActivityHook(fn func()) {
+	s.onActivity = fn
+}
+
 // SetDebug enables or disables debug logging.
 func (s *Server) SetDebug(debug bool) {
 	s.debug = debug
@@ -210,6 +223,9 @@ func (s *Server) handleConnection(conn net.Conn) {
 	if s.debug {
 		logging.L.With("component", "ssh-agent").Info("new connection", "remote", conn.RemoteAddr())
 	}
+	if s.onActivity != nil {
+		s.onActivity()
+	}
 
 	// ServeAgent serves the agent protocol on the connection
 	if err := agent.ServeAgent(s.keyring, conn); err != nil {
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"sync"
+	"time"
 
 	cryptossh "golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -15,11 +16,19 @@ import (
 )
 
 // Keyring implements the agent.Agent interface using Bitwarden as the key store.
+//
+// Keys added with a lifetime (ssh-add -t) are held in memory only and are
+// never written to the vault. ssh-add constraints given for a key that is
+// already in the vault apply until the agent exits.
 type Keyring struct {
-	client BitwardenClient
-	mu     sync.RWMutex
-	keys   []*SSHKeyItem // cached keys
-	debug  bool          // enable debug logging
+	client      BitwardenClient
+	confirmer   Confirmer
+	mu          sync.RWMutex
+	keys        []*SSHKeyItem              // cached keys
+	temporary   []*SSHKeyItem              // keys added with a lifetime
+	constraints map[string]*keyConstraints // ssh-add constraints by fingerprint
+	debug       bool                       // enable debug logging
+	now         func() time.Time           // clock, replaceable in tests
 }
 
 // NewKeyring creates a new Keyring backed by the given Bitwarden client.
@@ -34,6 +43,120 @@ func (k *Keyring) SetDebug(debug bool) {
 	k.debug = debug
 }
 
+// SetConfirmer sets how the user is asked before a key that requires
+// confirmation is used. Without one, such keys cannot sign.
+func (k *Keyring) SetConfirmer(c Confirmer) {
+	k.confirmer = c
+}
+
+// clock returns the current time
+func (k *Keyring) clock() time.Time {
+	if k.now != nil {
+		return k.now()
+	}
+	return time.Now()
+}
+
+// activeKeys returns the vault and temporary keys that have not expired.
+// Callers must hold mu.
+func (k *Keyring) activeKeys() []*SSHKeyItem {
+	now := k.clock()
+	active := make([]*SSHKeyItem, 0, len(k.keys)+len(k.temporary))
+	for _, keys := range [][]*SSHKeyItem{k.keys, k.temporary} {
+		for _, key := range keys {
+			if key.Signer == nil {
+				continue
+			}
+			c := k.constraints[cryptossh.FingerprintSHA256(key.Signer.PublicKey())]
+			if c != nil && !c.expires.IsZero() && !now.Before(c.expires) {
+				continue
+			}
+			active = append(active, key)
+		}
+	}
+	return active
+}
+
+// findActive looks up an active key and whether it must be confirmed
+func (k *Keyring) findActive(pubKey cryptossh.PublicKey) (*SSHKeyItem, bool, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, found := FindSSHKeyByPublicKey(k.activeKeys(), pubKey)
+	if !found {
+		return nil, false, false
+	}
+	c := k.constraints[cryptossh.FingerprintSHA256(pubKey)]
+	return key, key.Confirm || (c != nil && c.confirm), true
+}
+
+// setConstraints replaces the in-agent constraints of a key, dropping them
+// if the key was added without any. Callers must hold mu.
+func (k *Keyring) setConstraints(fingerprint string, c *keyConstraints) {
+	if old := k.constraints[fingerprint]; old != nil && old.timer != nil {
+		old.timer.Stop()
+	}
+	if c == nil {
+		delete(k.constraints, fingerprint)
+		return
+	}
+	if k.constraints == nil {
+		k.constraints = make(map[string]*keyConstraints)
+	}
+	k.constraints[fingerprint] = c
+}
+
+// expire drops a temporary key once its lifetime has passed so the private
+// key does not linger in memory. Expired vault keys stay hidden through
+// their constraints.
+func (k *Keyring) expire(fingerprint string, c *keyConstraints) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.constraints[fingerprint] != c {
+		return // re-added since
+	}
+	if removeKey(&k.temporary, fingerprint) {
+		delete(k.constraints, fingerprint)
+		if k.debug {
+			logging.L.With("component", "ssh-agent").Info("temporary key expired", "fingerprint", fingerprint)
+		}
+	}
+}
+
+// removeKey deletes the key with the given fingerprint from keys
+func removeKey(keys *[]*SSHKeyItem, fingerprint string) bool {
+	for i, key := range *keys {
+		if key.Signer != nil && cryptossh.FingerprintSHA256(key.Signer.PublicKey()) == fingerprint {
+			*keys = append((*keys)[:i], (*keys)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// confirmUse asks the user before key signs anything
+func (k *Keyring) confirmUse(key *SSHKeyItem) error {
+	log := logging.L.With("component", "ssh-agent")
+	fingerprint := cryptossh.FingerprintSHA256(key.Signer.PublicKey())
+
+	if k.confirmer == nil {
+		log.Warn("key requires confirmation but no prompt is available", "fingerprint", fingerprint)
+		return ErrConfirmationDenied
+	}
+
+	ok, err := k.confirmer.PromptForConfirmation(fmt.Sprintf("Allow use of SSH key %q (%s)?", key.Item.Name, fingerprint))
+	if err != nil {
+		log.Info("key confirmation not answered, refusing", "fingerprint", fingerprint, "error", err)
+		return ErrConfirmationDenied
+	}
+	if !ok {
+		log.Info("key use declined", "fingerprint", fingerprint)
+		return ErrConfirmationDenied
+	}
+	return nil
+}
+
 // refreshKeys reloads the SSH keys from Bitwarden.
 func (k *Keyring) refreshKeys(ctx context.Context) error {
 	result, err := ListSSHKeys(ctx, k.client)
@@ -57,7 +180,7 @@ func (k *Keyring) refreshKeys(ctx context.Context) error {
 
 // List returns the identities known to the agent.
 func (k *Keyring) List() ([]*agent.Key, error) {
-	ctx := context.Background()
+	ctx := requestContext()
 
 	// Refresh keys from Bitwarden (client handles auto-unlock)
 	if err := k.refreshKeys(ctx); err != nil {
@@ -68,10 +191,7 @@ func (k *Keyring) List() ([]*agent.Key, error) {
 	defer k.mu.RUnlock()
 
 	var agentKeys []*agent.Key
-	for _, key := range k.keys {
-		if key.Signer == nil {
-			continue
-		}
+	for _, key := range k.activeKeys() {
 		pubKey := key.Signer.PublicKey()
 		agentKeys = append(agentKeys, &agent.Key{
 			Format:  pubKey.Type(),
@@ -91,7 +211,7 @@ func (k *Keyring) Sign(key cryptossh.PublicKey, data []byte) (*cryptossh.Signatu
 
 // SignWithFlags signs data with the specified flags.
 func (k *Keyring) SignWithFlags(key cryptossh.PublicKey, data []byte, flags agent.SignatureFlags) (*cryptossh.Signature, error) {
-	ctx := context.Background()
+	ctx := requestContext()
 
 	// Refresh keys if cache is empty (client handles auto-unlock)
 	k.mu.RLock()
@@ -104,23 +224,30 @@ func (k *Keyring) SignWithFlags(key cryptossh.PublicKey, data []byte, flags agen
 		}
 	}
 
-	k.mu.RLock()
-	sshKey, found := FindSSHKeyByPublicKey(k.keys, key)
-	k.mu.RUnlock()
+	sshKey, confirm, found := k.findActive(key)
 
 	// If not found, try refreshing once and retry
 	if !found {
 		if err := k.refreshKeys(ctx); err != nil {
 			return nil, fmt.Errorf("failed to refresh keys: %w", err)
 		}
-		k.mu.RLock()
-		sshKey, found = FindSSHKeyByPublicKey(k.keys, key)
-		k.mu.RUnlock()
+		sshKey, confirm, found = k.findActive(key)
 		if !found {
 			return nil, ErrKeyNotFound
 		}
 	}
 
+	if confirm {
+		if err := k.confirmUse(sshKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keys protected by master password re-prompt need a fresh password entry
+	if err := k.client.VerifyReprompt(ctx, sshKey.Item); err != nil {
+		return nil, fmt.Errorf("master password re-prompt failed: %w", err)
+	}
+
 	// Handle signature algorithm based on flags
 	var algo string
 	switch {
@@ -143,14 +270,17 @@ func (k *Keyring) SignWithFlags(key cryptossh.PublicKey, data []byte, flags agen
 }
 
 // Add adds a key to the agent by creating an SSH key item in Bitwarden.
-// Note: The LifetimeSecs and ConfirmBeforeUse fields are ignored as Bitwarden
-// does not support these options.
+//
+// A key with LifetimeSecs is kept in memory only and dropped when it expires.
+// A key with ConfirmBeforeUse is stored with ConfirmFieldName set so the
+// vault remembers it. Adding a key that is already in the vault replaces its
+// in-agent constraints instead.
 func (k *Keyring) Add(key agent.AddedKey) error {
 	if key.PrivateKey == nil {
 		return fmt.Errorf("private key is required")
 	}
 
-	ctx := context.Background()
+	ctx := requestContext()
 
 	// Create a signer from the private key to get the public key
 	signer, err := cryptossh.NewSignerFromKey(key.PrivateKey)
@@ -166,18 +296,51 @@ func (k *Keyring) Add(key agent.AddedKey) error {
 		return fmt.Errorf("failed to refresh keys: %w", err)
 	}
 
-	k.mu.RLock()
-	_, found := FindSSHKeyByPublicKey(k.keys, signer.PublicKey())
-	k.mu.RUnlock()
+	var constraints *keyConstraints
+	if key.ConfirmBeforeUse || key.LifetimeSecs > 0 {
+		constraints = &keyConstraints{confirm: key.ConfirmBeforeUse}
+	}
+	if key.LifetimeSecs > 0 {
+		constraints.expires = k.clock().Add(time.Duration(key.LifetimeSecs) * time.Second)
+	}
 
+	// Determine the item name
+	name := key.Comment
+	if name == "" {
+		name = fingerprint
+	}
+
+	k.mu.Lock()
+	_, found := FindSSHKeyByPublicKey(k.keys, signer.PublicKey())
 	if found {
-		// Key already exists, return success (idempotent)
+		// Key already exists, only its constraints change (idempotent)
+		k.setConstraints(fingerprint, constraints)
+		k.mu.Unlock()
 		if k.debug {
 			logging.L.With("component", "ssh-agent").Info("key already exists, skipping", "fingerprint", fingerprint)
 		}
 		return nil
 	}
 
+	removeKey(&k.temporary, fingerprint)
+	k.setConstraints(fingerprint, nil)
+	if key.LifetimeSecs > 0 {
+		k.temporary = append(k.temporary, &SSHKeyItem{
+			Item:   &bitwarden.Item{Type: bitwarden.ItemTypeSSHKey, Name: name},
+			Signer: signer,
+		})
+		constraints.timer = time.AfterFunc(time.Duration(key.LifetimeSecs)*time.Second, func() {
+			k.expire(fingerprint, constraints)
+		})
+		k.setConstraints(fingerprint, constraints)
+		k.mu.Unlock()
+		if k.debug {
+			logging.L.With("component", "ssh-agent").Info("added temporary key", "fingerprint", fingerprint, "lifetime_secs", key.LifetimeSecs)
+		}
+		return nil
+	}
+	k.mu.Unlock()
+
 	// Marshal the private key to OpenSSH format
 	privateKeyPEM, err := marshalPrivateKeyOpenSSH(key.PrivateKey, key.Comment)
 	if err != nil {
@@ -187,12 +350,6 @@ func (k *Keyring) Add(key agent.AddedKey) error {
 	// Format the public key
 	publicKeyStr := formatAuthorizedKey(signer.PublicKey(), key.Comment)
 
-	// Determine the item name
-	name := key.Comment
-	if name == "" {
-		name = fingerprint
-	}
-
 	// Create the Bitwarden item (client handles auto-unlock)
 	req := bitwarden.CreateItemRequest{
 		Type: bitwarden.ItemTypeSSHKey,
@@ -203,6 +360,9 @@ func (k *Keyring) Add(key agent.AddedKey) error {
 			KeyFingerprint: fingerprint,
 		},
 	}
+	if key.ConfirmBeforeUse {
+		req.Fields = []bitwarden.Field{{Name: ConfirmFieldName, Value: "true", Type: 2}}
+	}
 
 	createdItem, err := k.client.CreateItem(ctx, req)
 	if err != nil {
@@ -212,8 +372,9 @@ func (k *Keyring) Add(key agent.AddedKey) error {
 	// Add to cache
 	k.mu.Lock()
 	k.keys = append(k.keys, &SSHKeyItem{
-		Item:   createdItem,
-		Signer: signer,
+		Item:    createdItem,
+		Signer:  signer,
+		Confirm: key.ConfirmBeforeUse,
 	})
 	k.mu.Unlock()
 
@@ -224,13 +385,26 @@ func (k *Keyring) Add(key agent.AddedKey) error {
 	return nil
 }
 
-// Remove removes a key from the agent by deleting the SSH key item from Bitwarden.
+// Remove removes a key from the agent by deleting the SSH key item from
+// Bitwarden. Temporary keys are only dropped from memory.
 func (k *Keyring) Remove(key cryptossh.PublicKey) error {
 	if key == nil {
 		return fmt.Errorf("public key is required")
 	}
 
-	ctx := context.Background()
+	ctx := requestContext()
+	fingerprint := cryptossh.FingerprintSHA256(key)
+
+	k.mu.Lock()
+	if removeKey(&k.temporary, fingerprint) {
+		k.setConstraints(fingerprint, nil)
+		k.mu.Unlock()
+		if k.debug {
+			logging.L.With("component", "ssh-agent").Info("removed temporary key", "fingerprint", fingerprint)
+		}
+		return nil
+	}
+	k.mu.Unlock()
 
 	// Refresh keys from Bitwarden (client handles auto-unlock)
 	if err := k.refreshKeys(ctx); err != nil {
@@ -264,10 +438,11 @@ func (k *Keyring) Remove(key cryptossh.PublicKey) error {
 		}
 	}
 	k.keys = newKeys
+	k.setConstraints(fingerprint, nil)
 	k.mu.Unlock()
 
 	if k.debug {
-		logging.L.With("component", "ssh-agent").Info("removed key", "fingerprint", cryptossh.FingerprintSHA256(key), "item_id", sshKey.Item.ID)
+		logging.L.With("component", "ssh-agent").Info("removed key", "fingerprint", fingerprint, "item_id", sshKey.Item.ID)
 	}
 
 	return nil
@@ -285,22 +460,33 @@ func (k *Keyring) RemoveAll() error {
 	return ErrRemoveAllNotSupported
 }
 
-// Lock locks the Bitwarden vault, clearing the key cache.
+// Lock locks the Bitwarden vault, clearing the key cache and forgetting
+// temporary keys.
 //
 // Note: The passphrase parameter is ignored. This agent implements vault-level
 // locking rather than passphrase-based agent locking as used by ssh-agent.
 // When the Bitwarden vault is locked, the agent cannot access any keys.
 func (k *Keyring) Lock(passphrase []byte) error {
-	ctx := context.Background()
+	ctx := requestContext()
 	if err := k.client.Lock(ctx); err != nil {
 		return fmt.Errorf("failed to lock vault: %w", err)
 	}
 
+	k.ClearKeys()
+	return nil
+}
+
+// ClearKeys drops the cached vault keys and forgets temporary keys, as
+// when the vault was locked elsewhere.
+func (k *Keyring) ClearKeys() {
 	k.mu.Lock()
-	k.keys = nil
-	k.mu.Unlock()
+	defer k.mu.Unlock()
 
-	return nil
+	k.keys = nil
+	for _, key := range k.temporary {
+		k.setConstraints(cryptossh.FingerprintSHA256(key.Signer.PublicKey()), nil)
+	}
+	k.temporary = nil
 }
 
 // Unlock unlocks the Bitwarden vault and refreshes the key cache.
@@ -310,14 +496,14 @@ func (k *Keyring) Lock(passphrase []byte) error {
 // for the master password if the vault is locked (via SessionManager).
 // This differs from ssh-agent's passphrase-based locking.
 func (k *Keyring) Unlock(passphrase []byte) error {
-	ctx := context.Background()
+	ctx := requestContext()
 	// Just refresh keys - the client handles auto-unlock transparently
 	return k.refreshKeys(ctx)
 }
 
 // Signers returns signers for all available keys.
 func (k *Keyring) Signers() ([]cryptossh.Signer, error) {
-	ctx := context.Background()
+	ctx := requestContext()
 
 	// Refresh keys from Bitwarden (client handles auto-unlock)
 	if err := k.refreshKeys(ctx); err != nil {
@@ -328,10 +514,8 @@ func (k *Keyring) Signers() ([]cryptossh.Signer, error) {
 	defer k.mu.RUnlock()
 
 	var signers []cryptossh.Signer
-	for _, key := range k.keys {
-		if key.Signer != nil {
-			signers = append(signers, key.Signer)
-		}
+	for _, key := range k.activeKeys() {
+		signers = append(signers, key.Signer)
 	}
 
 	return signers, nil
@@ -345,3 +529,10 @@ func (k *Keyring) Extension(extensionType string, contents []byte) ([]byte, erro
 
 // Verify that Keyring implements agent.ExtendedAgent.
 var _ agent.ExtendedAgent = (*Keyring)(nil)
+
+// requestContext returns the context for a request to the vault. The agent
+// has no Prompt objects to hand out, so it asks for the master password when
+// the vault is locked even if D-Bus clients have to unlock it themselves.
+func requestContext() context.Context {
+	return bitwarden.WithUnlockPrompt(context.Background())
+}
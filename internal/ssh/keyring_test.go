@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"testing"
+	"time"
 
 	cryptossh "golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -21,6 +22,8 @@ type mockBitwardenClient struct {
 	createCalls   int
 	deleteCalls   int
 	deleteItemIDs []string
+	repromptCalls int
+	repromptErr   error
 }
 
 func (m *mockBitwardenClient) ListItems(ctx context.Context) ([]bitwarden.Item, error) {
@@ -42,6 +45,14 @@ func (m *mockBitwardenClient) Unlock(ctx context.Context, password string) (stri
 	return "session", nil
 }
 
+func (m *mockBitwardenClient) VerifyReprompt(ctx context.Context, item *bitwarden.Item) error {
+	if item.Reprompt != bitwarden.RepromptPassword {
+		return nil
+	}
+	m.repromptCalls++
+	return m.repromptErr
+}
+
 func (m *mockBitwardenClient) CreateItem(ctx context.Context, req bitwarden.CreateItemRequest) (*bitwarden.Item, error) {
 	if m.locked {
 		return nil, bitwarden.ErrVaultLocked
@@ -52,6 +63,7 @@ func (m *mockBitwardenClient) CreateItem(ctx context.Context, req bitwarden.Crea
 		Name:   req.Name,
 		Type:   req.Type,
 		SSHKey: req.SSHKey,
+		Fields: req.Fields,
 	}
 	m.items = append(m.items, *item)
 	return item, nil
@@ -387,3 +399,292 @@ func TestKeyring_Extension(t *testing.T) {
 		t.Errorf("Extension() error = %v, want %v", err, agent.ErrExtensionUnsupported)
 	}
 }
+
+// mockConfirmer answers confirmation prompts with a fixed reply
+type mockConfirmer struct {
+	answer bool
+	err    error
+	calls  int
+}
+
+func (m *mockConfirmer) PromptForConfirmation(message string) (bool, error) {
+	m.calls++
+	return m.answer, m.err
+}
+
+func TestKeyring_Sign_ConfirmField(t *testing.T) {
+	items := []bitwarden.Item{
+		{
+			ID:   "key1",
+			Name: "Test Key",
+			Type: bitwarden.ItemTypeSSHKey,
+			SSHKey: &bitwarden.SSHKey{
+				PrivateKey:     testED25519PrivateKey,
+				PublicKey:      testED25519PublicKey,
+				KeyFingerprint: "SHA256:test1",
+			},
+			Fields: []bitwarden.Field{{Name: ConfirmFieldName, Value: "true", Type: 2}},
+		},
+	}
+	signer, err := cryptossh.ParsePrivateKey([]byte(testED25519PrivateKey))
+	if err != nil {
+		t.Fatalf("failed to parse test key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		confirmer Confirmer
+		wantErr   error
+	}{
+		{"confirmed", &mockConfirmer{answer: true}, nil},
+		{"declined", &mockConfirmer{answer: false}, ErrConfirmationDenied},
+		{"prompt failed", &mockConfirmer{err: errors.New("no display")}, ErrConfirmationDenied},
+		{"no confirmer", nil, ErrConfirmationDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tk := newTestableKeyring(items, false)
+			if tt.confirmer != nil {
+				tk.SetConfirmer(tt.confirmer)
+			}
+
+			_, err := tk.Sign(signer.PublicKey(), []byte("data"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Sign() error = %v, want %v", err, tt.wantErr)
+			}
+			if m, ok := tt.confirmer.(*mockConfirmer); ok && m.calls != 1 {
+				t.Errorf("Expected 1 confirmation prompt, got %d", m.calls)
+			}
+		})
+	}
+}
+
+func TestKeyring_Add_ConfirmBeforeUse(t *testing.T) {
+	tk := newTestableKeyring([]bitwarden.Item{}, false)
+	confirmer := &mockConfirmer{answer: true}
+	tk.SetConfirmer(confirmer)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := cryptossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	if err := tk.Add(agent.AddedKey{PrivateKey: priv, Comment: "test-key", ConfirmBeforeUse: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// The constraint is stored in the vault
+	if len(tk.mock.items) != 1 || !RequiresConfirmation(&tk.mock.items[0]) {
+		t.Fatalf("Expected stored item with %s field, got %+v", ConfirmFieldName, tk.mock.items)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := tk.Sign(signer.PublicKey(), []byte("data")); err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+	}
+	if confirmer.calls != 2 {
+		t.Errorf("Expected a prompt before every signature, got %d prompts", confirmer.calls)
+	}
+}
+
+func TestKeyring_Add_ExistingKeyConstraints(t *testing.T) {
+	items := []bitwarden.Item{
+		{
+			ID:   "key1",
+			Name: "Test Key",
+			Type: bitwarden.ItemTypeSSHKey,
+			SSHKey: &bitwarden.SSHKey{
+				PrivateKey:     testED25519PrivateKey,
+				PublicKey:      testED25519PublicKey,
+				KeyFingerprint: "SHA256:test1",
+			},
+		},
+	}
+	tk := newTestableKeyring(items, false)
+	confirmer := &mockConfirmer{answer: false}
+	tk.SetConfirmer(confirmer)
+
+	raw, err := cryptossh.ParseRawPrivateKey([]byte(testED25519PrivateKey))
+	if err != nil {
+		t.Fatalf("failed to parse test key: %v", err)
+	}
+	signer, err := cryptossh.NewSignerFromKey(raw)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	if err := tk.Add(agent.AddedKey{PrivateKey: raw, ConfirmBeforeUse: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if tk.mock.createCalls != 0 {
+		t.Errorf("Expected 0 CreateItem calls for existing key, got %d", tk.mock.createCalls)
+	}
+
+	if _, err := tk.Sign(signer.PublicKey(), []byte("data")); !errors.Is(err, ErrConfirmationDenied) {
+		t.Errorf("Sign() error = %v, want %v", err, ErrConfirmationDenied)
+	}
+
+	// Adding it again without constraints lifts them
+	if err := tk.Add(agent.AddedKey{PrivateKey: raw}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := tk.Sign(signer.PublicKey(), []byte("data")); err != nil {
+		t.Errorf("Sign() error = %v", err)
+	}
+	if confirmer.calls != 1 {
+		t.Errorf("Expected 1 confirmation prompt, got %d", confirmer.calls)
+	}
+}
+
+func TestKeyring_Add_Lifetime(t *testing.T) {
+	tk := newTestableKeyring([]bitwarden.Item{}, false)
+	now := time.Now()
+	tk.now = func() time.Time { return now }
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := cryptossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	if err := tk.Add(agent.AddedKey{PrivateKey: priv, Comment: "temp-key", LifetimeSecs: 60}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Temporary keys never reach the vault
+	if tk.mock.createCalls != 0 {
+		t.Errorf("Expected 0 CreateItem calls, got %d", tk.mock.createCalls)
+	}
+
+	keys, err := tk.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].Comment != "temp-key" {
+		t.Fatalf("List() = %+v, want temp-key", keys)
+	}
+	if _, err := tk.Sign(signer.PublicKey(), []byte("data")); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	now = now.Add(time.Minute)
+
+	keys, err = tk.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("List() after expiry = %d keys, want 0", len(keys))
+	}
+	if _, err := tk.Sign(signer.PublicKey(), []byte("data")); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Sign() after expiry error = %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestKeyring_Add_LifetimeExpiryDropsKey(t *testing.T) {
+	tk := newTestableKeyring([]bitwarden.Item{}, false)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := tk.Add(agent.AddedKey{PrivateKey: priv, LifetimeSecs: 1}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tk.mu.RLock()
+		remaining := len(tk.temporary) + len(tk.constraints)
+		tk.mu.RUnlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("temporary key was not dropped after its lifetime")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestKeyring_Remove_Temporary(t *testing.T) {
+	tk := newTestableKeyring([]bitwarden.Item{}, false)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := cryptossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	if err := tk.Add(agent.AddedKey{PrivateKey: priv, LifetimeSecs: 3600}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if err := tk.Remove(signer.PublicKey()); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if tk.mock.deleteCalls != 0 {
+		t.Errorf("Expected 0 DeleteItem calls, got %d", tk.mock.deleteCalls)
+	}
+	if len(tk.temporary) != 0 || len(tk.constraints) != 0 {
+		t.Errorf("temporary key still held after Remove")
+	}
+}
+
+func TestKeyring_Sign_Reprompt(t *testing.T) {
+	signer, err := cryptossh.ParsePrivateKey([]byte(testED25519PrivateKey))
+	if err != nil {
+		t.Fatalf("failed to parse test key: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		reprompt    int
+		repromptErr error
+		wantCalls   int
+		wantErr     error
+	}{
+		{"not protected", bitwarden.RepromptNone, nil, 0, nil},
+		{"password verified", bitwarden.RepromptPassword, nil, 1, nil},
+		{"prompt cancelled", bitwarden.RepromptPassword, bitwarden.ErrUserCancelled, 1, bitwarden.ErrUserCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := []bitwarden.Item{
+				{
+					ID:       "key1",
+					Name:     "Test Key",
+					Type:     bitwarden.ItemTypeSSHKey,
+					Reprompt: tt.reprompt,
+					SSHKey: &bitwarden.SSHKey{
+						PrivateKey:     testED25519PrivateKey,
+						PublicKey:      testED25519PublicKey,
+						KeyFingerprint: "SHA256:test1",
+					},
+				},
+			}
+			tk := newTestableKeyring(items, false)
+			tk.mock.repromptErr = tt.repromptErr
+
+			_, err := tk.Sign(signer.PublicKey(), []byte("data"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Sign() error = %v, want %v", err, tt.wantErr)
+			}
+			if tk.mock.repromptCalls != tt.wantCalls {
+				t.Errorf("Expected %d re-prompts, got %d", tt.wantCalls, tk.mock.repromptCalls)
+			}
+		})
+	}
+}
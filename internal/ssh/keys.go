@@ -13,6 +13,10 @@ import (
 	"github.com/joe/bitwarden-keyring/internal/bitwarden"
 )
 
+// ConfirmFieldName is the custom field that makes a vault-stored key require
+// confirmation before every signature, like a key added with ssh-add -c.
+const ConfirmFieldName = "ssh-confirm"
+
 // IsSSHKeyItem returns true if the given Bitwarden item is an SSH key.
 func IsSSHKeyItem(item *bitwarden.Item) bool {
 	return item != nil && item.Type == bitwarden.ItemTypeSSHKey && item.SSHKey != nil
@@ -81,14 +85,30 @@ func ListSSHKeys(ctx context.Context, client ItemLister) (*ListSSHKeysResult, er
 		}
 
 		result.Keys = append(result.Keys, &SSHKeyItem{
-			Item:   item,
-			Signer: signer,
+			Item:    item,
+			Signer:  signer,
+			Confirm: RequiresConfirmation(item),
 		})
 	}
 
 	return result, nil
 }
 
+// RequiresConfirmation reports whether an item opts into confirm-before-use
+// through a ConfirmFieldName custom field set to true, yes or 1.
+func RequiresConfirmation(item *bitwarden.Item) bool {
+	for _, f := range item.Fields {
+		if f.Name != ConfirmFieldName {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(f.Value)) {
+		case "true", "yes", "1":
+			return true
+		}
+	}
+	return false
+}
+
 // FindSSHKeyByPublicKey searches for an SSH key item that matches the given public key.
 func FindSSHKeyByPublicKey(keys []*SSHKeyItem, pubKey cryptossh.PublicKey) (*SSHKeyItem, bool) {
 	targetBlob := pubKey.Marshal()
@@ -71,6 +71,30 @@ func TestIsSSHKeyItem(t *testing.T) {
 	}
 }
 
+func TestRequiresConfirmation(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []bitwarden.Field
+		want   bool
+	}{
+		{"no fields", nil, false},
+		{"boolean true", []bitwarden.Field{{Name: ConfirmFieldName, Value: "true", Type: 2}}, true},
+		{"boolean false", []bitwarden.Field{{Name: ConfirmFieldName, Value: "false", Type: 2}}, false},
+		{"text yes", []bitwarden.Field{{Name: ConfirmFieldName, Value: " Yes ", Type: 0}}, true},
+		{"text one", []bitwarden.Field{{Name: ConfirmFieldName, Value: "1", Type: 0}}, true},
+		{"other field", []bitwarden.Field{{Name: "confirm", Value: "true", Type: 0}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &bitwarden.Item{Type: bitwarden.ItemTypeSSHKey, Fields: tt.fields}
+			if got := RequiresConfirmation(item); got != tt.want {
+				t.Errorf("RequiresConfirmation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseSSHKey(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	cryptossh "golang.org/x/crypto/ssh"
 
@@ -22,6 +23,13 @@ type BitwardenClient interface {
 	DeleteItem(ctx context.Context, id string) error
 	Lock(ctx context.Context) error
 	Unlock(ctx context.Context, password string) (string, error)
+	VerifyReprompt(ctx context.Context, item *bitwarden.Item) error
+}
+
+// Confirmer asks the user a yes/no question before a key is used.
+// *bitwarden.SessionManager implements it with the configured prompt backends.
+type Confirmer interface {
+	PromptForConfirmation(message string) (bool, error)
 }
 
 // DefaultSocketPath returns the default path for the SSH agent socket.
@@ -36,8 +44,17 @@ func DefaultSocketPath() string {
 
 // SSHKeyItem wraps a Bitwarden item with its parsed SSH key signer.
 type SSHKeyItem struct {
-	Item   *bitwarden.Item
-	Signer cryptossh.Signer
+	Item    *bitwarden.Item
+	Signer  cryptossh.Signer
+	Confirm bool // item has ConfirmFieldName set; ask before every signature
+}
+
+// keyConstraints are the ssh-add constraints the agent applies to a key
+// for as long as it runs, on top of anything stored in the vault.
+type keyConstraints struct {
+	confirm bool        // ssh-add -c
+	expires time.Time   // ssh-add -t; zero if the key has no lifetime
+	timer   *time.Timer // drops a temporary key when it expires
 }
 
 // ParseError represents a key that failed to parse.
@@ -68,6 +85,10 @@ var (
 	ErrVaultLocked = errors.New("bitwarden vault is locked")
 	ErrReadOnly    = errors.New("ssh agent is read-only")
 
+	// ErrConfirmationDenied is returned by Sign when a key requires
+	// confirmation and the user declined or could not be asked.
+	ErrConfirmationDenied = errors.New("ssh key use was not confirmed")
+
 	// ErrRemoveAllNotSupported is returned by RemoveAll to prevent bulk deletion
 	// of SSH keys. This is a deliberate safety measure because SSH keys stored
 	// in Bitwarden should not be mass-deleted through the agent interface.
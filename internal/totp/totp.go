@@ -0,0 +1,180 @@
+// Package totp computes time-based one-time passwords (RFC 6238) from the
+// authenticator keys Bitwarden stores on login items.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of RFC 6238 and Google Authenticator key URIs
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+)
+
+// steamDigits is the length of Steam Guard codes
+const steamDigits = 5
+
+// steamAlphabet is the character set of Steam Guard codes
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+// ErrInvalidKey indicates an authenticator key that can't be parsed
+var ErrInvalidKey = errors.New("invalid TOTP key")
+
+// Key is a parsed authenticator key
+type Key struct {
+	Secret    []byte
+	Digits    int
+	Period    time.Duration
+	Algorithm string // SHA1, SHA256 or SHA512
+	Steam     bool   // Steam Guard codes instead of decimal digits
+}
+
+// Parse parses an authenticator key as Bitwarden stores it: a base32
+// secret, an otpauth://totp/ URI, or a steam:// key
+func Parse(s string) (*Key, error) {
+	s = strings.TrimSpace(s)
+	key := &Key{Digits: DefaultDigits, Period: DefaultPeriod, Algorithm: "SHA1"}
+
+	switch lower := strings.ToLower(s); {
+	case strings.HasPrefix(lower, "otpauth://"):
+		if err := key.parseURI(s); err != nil {
+			return nil, err
+		}
+		return key, nil
+
+	case strings.HasPrefix(lower, "steam://"):
+		key.Steam = true
+		key.Digits = steamDigits
+		s = s[len("steam://"):]
+	}
+
+	secret, err := decodeSecret(s)
+	if err != nil {
+		return nil, err
+	}
+	key.Secret = secret
+	return key, nil
+}
+
+// parseURI fills key from an otpauth:// URI
+func (k *Key) parseURI(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	switch strings.ToLower(u.Host) {
+	case "totp":
+	case "steam":
+		k.Steam = true
+		k.Digits = steamDigits
+	default:
+		return fmt.Errorf("%w: unsupported type %q", ErrInvalidKey, u.Host)
+	}
+
+	q := u.Query()
+	if k.Secret, err = decodeSecret(q.Get("secret")); err != nil {
+		return err
+	}
+	if strings.EqualFold(q.Get("encoder"), "steam") {
+		k.Steam = true
+		k.Digits = steamDigits
+	}
+	if v := q.Get("digits"); v != "" && !k.Steam {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 10 {
+			return fmt.Errorf("%w: digits %q", ErrInvalidKey, v)
+		}
+		k.Digits = n
+	}
+	if v := q.Get("period"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("%w: period %q", ErrInvalidKey, v)
+		}
+		k.Period = time.Duration(n) * time.Second
+	}
+	if v := q.Get("algorithm"); v != "" {
+		k.Algorithm = strings.ToUpper(v)
+		if newHash(k.Algorithm) == nil {
+			return fmt.Errorf("%w: algorithm %q", ErrInvalidKey, v)
+		}
+	}
+	return nil
+}
+
+// decodeSecret decodes a base32 secret, ignoring case, spaces and padding
+func decodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty secret", ErrInvalidKey)
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret is not base32", ErrInvalidKey)
+	}
+	return secret, nil
+}
+
+// newHash returns the HMAC hash constructor for an algorithm name, or nil
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// Code returns the code valid at t and how long it stays valid
+func (k *Key) Code(t time.Time) (string, time.Duration) {
+	period := int64(k.Period / time.Second)
+	unix := t.Unix()
+	counter := uint64(unix / period)
+	remaining := time.Duration(period-unix%period) * time.Second
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	newFn := newHash(k.Algorithm)
+	if newFn == nil {
+		newFn = sha1.New
+	}
+	mac := hmac.New(newFn, k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	if k.Steam {
+		code := make([]byte, steamDigits)
+		for i := range code {
+			code[i] = steamAlphabet[value%uint32(len(steamAlphabet))]
+			value /= uint32(len(steamAlphabet))
+		}
+		return string(code), remaining
+	}
+
+	mod := uint64(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, uint64(value)%mod), remaining
+}
@@ -0,0 +1,120 @@
+package totp
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test secrets, base32 encoded
+var (
+	rfcSHA1   = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	rfcSHA256 = base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	rfcSHA512 = base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+)
+
+func TestCode_RFC6238(t *testing.T) {
+	tests := []struct {
+		unix      int64
+		algorithm string
+		secret    string
+		want      string
+	}{
+		{59, "SHA1", rfcSHA1, "94287082"},
+		{59, "SHA256", rfcSHA256, "46119246"},
+		{59, "SHA512", rfcSHA512, "90693936"},
+		{1111111109, "SHA1", rfcSHA1, "07081804"},
+		{1111111109, "SHA256", rfcSHA256, "68084774"},
+		{1234567890, "SHA512", rfcSHA512, "93441116"},
+		{20000000000, "SHA1", rfcSHA1, "65353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm+"/"+tt.want, func(t *testing.T) {
+			key, err := Parse("otpauth://totp/test?digits=8&algorithm=" + tt.algorithm + "&secret=" + tt.secret)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got, _ := key.Code(time.Unix(tt.unix, 0)); got != tt.want {
+				t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		wantDigits int
+		wantPeriod time.Duration
+		wantAlg    string
+		wantSteam  bool
+	}{
+		{"bare secret", "JBSWY3DPEHPK3PXP", 6, 30 * time.Second, "SHA1", false},
+		{"bare secret with spaces", "jbsw y3dp ehpk 3pxp", 6, 30 * time.Second, "SHA1", false},
+		{"uri defaults", "otpauth://totp/Example:joe?secret=JBSWY3DPEHPK3PXP&issuer=Example", 6, 30 * time.Second, "SHA1", false},
+		{"uri options", "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=8&period=60&algorithm=sha256", 8, time.Minute, "SHA256", false},
+		{"steam key", "steam://JBSWY3DPEHPK3PXP", 5, 30 * time.Second, "SHA1", true},
+		{"steam uri", "otpauth://totp/Steam:joe?secret=JBSWY3DPEHPK3PXP&encoder=steam", 5, 30 * time.Second, "SHA1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if key.Digits != tt.wantDigits || key.Period != tt.wantPeriod || key.Algorithm != tt.wantAlg || key.Steam != tt.wantSteam {
+				t.Errorf("Parse(%q) = %+v", tt.in, key)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"not base32!",
+		"otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=0",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&period=-5",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+	} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidKey", in, err)
+		}
+	}
+}
+
+func TestCode_Steam(t *testing.T) {
+	key, err := Parse("steam://" + rfcSHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := key.Code(time.Unix(59, 0))
+	if len(code) != steamDigits {
+		t.Fatalf("Steam code %q has %d characters", code, len(code))
+	}
+	for _, c := range code {
+		found := false
+		for _, a := range steamAlphabet {
+			found = found || a == c
+		}
+		if !found {
+			t.Errorf("Steam code %q has character %q outside the alphabet", code, c)
+		}
+	}
+}
+
+func TestCode_Remaining(t *testing.T) {
+	key, _ := Parse("JBSWY3DPEHPK3PXP")
+	if _, remaining := key.Code(time.Unix(61, 0)); remaining != 29*time.Second {
+		t.Errorf("remaining = %v, want 29s", remaining)
+	}
+	if _, remaining := key.Code(time.Unix(90, 0)); remaining != 30*time.Second {
+		t.Errorf("remaining at period start = %v, want 30s", remaining)
+	}
+}